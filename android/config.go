@@ -151,6 +151,9 @@ type config struct {
 	// regenerate build.ninja.
 	ninjaFileDepsSet sync.Map
 
+	// If true the dependency tags between modules are recorded for NewModuleGraph.
+	collectModuleGraph bool
+
 	OncePer
 }
 
@@ -506,6 +509,12 @@ func (c *config) SetAllowMissingDependencies() {
 	c.productVariables.Allow_missing_dependencies = proptools.BoolPtr(true)
 }
 
+// SetCollectModuleGraph configures soong_build to record the dependency tags between modules so
+// that they are available to NewModuleGraph.  It must be called before the mutators are registered.
+func (c *config) SetCollectModuleGraph() {
+	c.collectModuleGraph = true
+}
+
 var _ bootstrap.ConfigStopBefore = (*config)(nil)
 
 // BlueprintToolLocation returns the directory containing build system tools
@@ -613,6 +622,30 @@ func (c *config) EnvDeps() map[string]string {
 	return c.envDeps
 }
 
+// SboxHermetic returns true if every RuleBuilder rule that uses sbox should run its commands in a
+// hermetic sandbox, see RuleBuilder.Hermetic.  Rules that don't use sbox are not affected.
+func (c *config) SboxHermetic() bool {
+	return c.IsEnvTrue("SOONG_SBOX_HERMETIC")
+}
+
+// SboxCacheDir returns the directory of the local action cache used by RuleBuilder rules that use
+// sbox with sandboxed inputs or hermetically, or an empty string if the cache is disabled.
+func (c *config) SboxCacheDir() string {
+	return c.Getenv("SOONG_SBOX_CACHE_DIR")
+}
+
+// SboxCacheMaxSize returns the maximum size of the local action cache in bytes as set by
+// SOONG_SBOX_CACHE_MAX_SIZE, or an empty string to use the default size.
+func (c *config) SboxCacheMaxSize() string {
+	return c.Getenv("SOONG_SBOX_CACHE_MAX_SIZE")
+}
+
+// WriteModuleOutputs returns true if soong_build should write the map from the outputs of build
+// statements to the modules that generated them, see module_outputs.go.
+func (c *config) WriteModuleOutputs() bool {
+	return c.IsEnvTrue("SOONG_WRITE_MODULE_OUTPUTS")
+}
+
 func (c *config) KatiEnabled() bool {
 	return c.katiEnabled
 }
@@ -1514,6 +1547,41 @@ func (c *config) SelinuxIgnoreNeverallows() bool {
 	return c.productVariables.SelinuxIgnoreNeverallows
 }
 
+// NeverallowRuleFiles returns the list of files containing product specific neverallow rules.
+func (c *config) NeverallowRuleFiles() []string {
+	return c.productVariables.NeverallowRuleFiles
+}
+
+// ReportPolicyViolations returns true if neverallow and visibility violations should be written to
+// a report instead of failing the build.
+func (c *config) ReportPolicyViolations() bool {
+	return Bool(c.productVariables.ReportPolicyViolations) || c.IsEnvTrue("SOONG_REPORT_POLICY_VIOLATIONS")
+}
+
+// PolicyViolationsBaseline returns the path to a file listing known neverallow and visibility
+// violations that should not fail the build.
+func (c *config) PolicyViolationsBaseline() string {
+	return String(c.productVariables.PolicyViolationsBaseline)
+}
+
+// CheckUnusedLibs returns true if the linked outputs of cc modules should be checked for
+// shared_libs and static_libs that none of their symbols come from.
+func (c *config) CheckUnusedLibs() bool {
+	return Bool(c.productVariables.CheckUnusedLibs) || c.IsEnvTrue("SOONG_CHECK_UNUSED_LIBS")
+}
+
+// SplitDwarf returns true if native modules should be compiled with their debug info split into
+// .dwo files by default, and packed into a .dwp file next to their unstripped output.
+func (c *config) SplitDwarf() bool {
+	return Bool(c.productVariables.SplitDwarf) || c.IsEnvTrue("SOONG_SPLIT_DWARF")
+}
+
+// StaticAnalyzer returns true if the clang static analyzer should be run over the sources of every
+// native module that doesn't disable it.
+func (c *config) StaticAnalyzer() bool {
+	return c.IsEnvTrue("WITH_STATIC_ANALYZER")
+}
+
 func (c *deviceConfig) SepolicySplit() bool {
 	return c.config.productVariables.SepolicySplit
 }
@@ -2221,6 +2221,9 @@ func (m *moduleContext) Build(pctx PackageContext, params BuildParams) {
 			m.ModuleName(),
 			err.Error())
 	}
+	if m.config.WriteModuleOutputs() {
+		recordModuleOutputs(m, bparams)
+	}
 	m.bp.Build(pctx.PackageContext, bparams)
 }
 
@@ -0,0 +1,212 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/google/blueprint"
+)
+
+// The module graph is the variant aware graph of modules and their dependencies after all the
+// mutators have run.  It is only collected when requested with Config.SetCollectModuleGraph, and
+// is used by soong_build --query to answer questions about dependencies without generating a
+// Bazel workspace like queryview does.
+
+func registerModuleGraphMutator(ctx RegisterMutatorsContext) {
+	ctx.BottomUpBlueprint("module_graph", moduleGraphMutator).Parallel()
+}
+
+// A dependency recorded by the moduleGraphMutator.
+type moduleGraphRecordedDep struct {
+	module blueprint.Module
+	tag    string
+}
+
+var moduleGraphDepsKey = NewOnceKey("moduleGraphDeps")
+
+// moduleGraphDeps returns the map from blueprint.Module to the []moduleGraphRecordedDep of its direct
+// dependencies.
+func moduleGraphDeps(config Config) *sync.Map {
+	return config.Once(moduleGraphDepsKey, func() interface{} {
+		return &sync.Map{}
+	}).(*sync.Map)
+}
+
+// moduleGraphMutator records the dependency tags of the direct dependencies of every module, which
+// are not available once the mutators have finished.  It is only registered when
+// Config.SetCollectModuleGraph was called before registering the mutators.
+func moduleGraphMutator(ctx blueprint.BottomUpMutatorContext) {
+	config := ctx.Config().(Config)
+
+	var deps []moduleGraphRecordedDep
+	ctx.VisitDirectDeps(func(dep blueprint.Module) {
+		deps = append(deps, moduleGraphRecordedDep{
+			module: dep,
+			tag:    dependencyTagLabel(ctx.OtherModuleDependencyTag(dep)),
+		})
+	})
+	moduleGraphDeps(config).Store(ctx.Module(), deps)
+}
+
+// dependencyTagLabel returns a short human readable description of a dependency tag, e.g.
+// "cc.libraryDependencyTag{Kind=sharedLibraryDependency}".
+func dependencyTagLabel(tag blueprint.DependencyTag) string {
+	if tag == nil {
+		return ""
+	}
+	if s, ok := tag.(fmt.Stringer); ok {
+		return s.String()
+	}
+
+	v := reflect.ValueOf(tag)
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+
+	label := v.Type().String()
+	if v.Kind() == reflect.Struct {
+		var fields []string
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			if field.Anonymous {
+				continue
+			}
+			value := v.Field(i)
+			switch value.Kind() {
+			case reflect.String:
+				if value.String() != "" {
+					fields = append(fields, field.Name+"="+value.String())
+				}
+			case reflect.Bool:
+				if value.Bool() {
+					fields = append(fields, field.Name+"=true")
+				}
+			case reflect.Int:
+				fields = append(fields, fmt.Sprintf("%s=%d", field.Name, value.Int()))
+			}
+		}
+		if len(fields) > 0 {
+			label += "{" + strings.Join(fields, ",") + "}"
+		}
+	}
+	return label
+}
+
+// ModuleGraphContext is the subset of the blueprint.Context used to build a ModuleGraph.
+type ModuleGraphContext interface {
+	ModuleName(module blueprint.Module) string
+	ModuleDir(module blueprint.Module) string
+	ModuleSubDir(module blueprint.Module) string
+	ModuleType(module blueprint.Module) string
+
+	VisitAllModules(visit func(blueprint.Module))
+	VisitDirectDeps(module blueprint.Module, visit func(blueprint.Module))
+}
+
+// ModuleGraphNode is a single variant of a module in the ModuleGraph.
+type ModuleGraphNode struct {
+	Name    string
+	Variant string
+	Type    string
+	Dir     string
+
+	deps  []ModuleGraphEdge
+	rdeps []ModuleGraphEdge
+}
+
+// Label returns the name of the module, followed by @ and the variant if the module has variants.
+func (n *ModuleGraphNode) Label() string {
+	if n.Variant == "" {
+		return n.Name
+	}
+	return n.Name + "@" + n.Variant
+}
+
+// ModuleGraphEdge is a dependency between two nodes in the ModuleGraph.
+type ModuleGraphEdge struct {
+	From *ModuleGraphNode
+	To   *ModuleGraphNode
+
+	// A description of the dependency tag used to add the dependency.
+	Tag string
+}
+
+// ModuleGraph is the graph of all module variants and the dependencies between them.
+type ModuleGraph struct {
+	nodes       []*ModuleGraphNode
+	nodesByName map[string][]*ModuleGraphNode
+}
+
+// NewModuleGraph creates a ModuleGraph from a context on which the mutators have run.  The
+// dependency tags are only available if config.SetCollectModuleGraph was called before running
+// the mutators.
+func NewModuleGraph(ctx ModuleGraphContext, config Config) *ModuleGraph {
+	g := &ModuleGraph{
+		nodesByName: make(map[string][]*ModuleGraphNode),
+	}
+
+	nodes := make(map[blueprint.Module]*ModuleGraphNode)
+	var modules []blueprint.Module
+	ctx.VisitAllModules(func(module blueprint.Module) {
+		node := &ModuleGraphNode{
+			Name:    ctx.ModuleName(module),
+			Variant: ctx.ModuleSubDir(module),
+			Type:    ctx.ModuleType(module),
+			Dir:     ctx.ModuleDir(module),
+		}
+		nodes[module] = node
+		modules = append(modules, module)
+		g.nodes = append(g.nodes, node)
+		g.nodesByName[node.Name] = append(g.nodesByName[node.Name], node)
+	})
+
+	recordedDeps := moduleGraphDeps(config)
+	for _, module := range modules {
+		from := nodes[module]
+		addEdge := func(dep blueprint.Module, tag string) {
+			to := nodes[dep]
+			if to == nil {
+				return
+			}
+			edge := ModuleGraphEdge{From: from, To: to, Tag: tag}
+			from.deps = append(from.deps, edge)
+			to.rdeps = append(to.rdeps, edge)
+		}
+
+		if deps, ok := recordedDeps.Load(module); ok {
+			for _, dep := range deps.([]moduleGraphRecordedDep) {
+				addEdge(dep.module, dep.tag)
+			}
+		} else {
+			ctx.VisitDirectDeps(module, func(dep blueprint.Module) {
+				addEdge(dep, "")
+			})
+		}
+	}
+
+	sort.SliceStable(g.nodes, func(i, j int) bool {
+		return g.nodes[i].Label() < g.nodes[j].Label()
+	})
+
+	return g
+}
@@ -0,0 +1,445 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/scanner"
+)
+
+// Queries over the ModuleGraph.
+//
+// The query language is a small subset of the Bazel query language:
+//   foo                 all variants of the module foo, the name may contain * wildcards
+//   foo@variant         a single variant of the module foo
+//   deps(x)             x and everything x transitively depends on
+//   deps(x, depth)      x and everything x depends on up to depth edges away
+//   rdeps(x)            x and everything that transitively depends on x
+//   rdeps(x, depth)     x and everything that depends on x up to depth edges away
+//   somepath(x, y)      the modules on one path from any module in x to any module in y
+//   allpaths(x, y)      the modules on all paths from any module in x to any module in y
+//   kind(pattern, x)    the modules in x with a module type matching the regular expression
+//
+// The result of a query is a set of module variants, which can be printed as text, JSON or as a
+// Graphviz dot graph of the dependencies between them labelled with the dependency tags.
+
+// ModuleGraphQueryResult is the set of nodes selected by a query.
+type ModuleGraphQueryResult struct {
+	Nodes []*ModuleGraphNode
+
+	// The edges to output, for somepath this is restricted to the edges on the path.
+	edges []ModuleGraphEdge
+}
+
+type moduleGraphNodeSet map[*ModuleGraphNode]bool
+
+func (s moduleGraphNodeSet) sorted() []*ModuleGraphNode {
+	nodes := make([]*ModuleGraphNode, 0, len(s))
+	for n := range s {
+		nodes = append(nodes, n)
+	}
+	sortModuleGraphNodes(nodes)
+	return nodes
+}
+
+func sortModuleGraphNodes(nodes []*ModuleGraphNode) {
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].Label() < nodes[j].Label()
+	})
+}
+
+// Query evaluates the query expression against the graph.
+func (g *ModuleGraph) Query(query string) (*ModuleGraphQueryResult, error) {
+	p := &moduleGraphQueryParser{graph: g}
+	p.scanner.Init(strings.NewReader(query))
+	p.scanner.Mode = scanner.ScanIdents | scanner.ScanStrings
+	p.scanner.IsIdentRune = isModuleGraphQueryIdentRune
+	p.scanner.Error = func(s *scanner.Scanner, msg string) {
+		p.errorf("%s", msg)
+	}
+	p.next()
+
+	nodes, edges := p.parseExpression()
+	if p.err == nil && p.tok != scanner.EOF {
+		p.errorf("unexpected %q", p.scanner.TokenText())
+	}
+	if p.err != nil {
+		return nil, fmt.Errorf("invalid query %q: %s", query, p.err)
+	}
+
+	result := &ModuleGraphQueryResult{
+		Nodes: nodes.sorted(),
+		edges: edges,
+	}
+	if result.edges == nil {
+		for _, n := range result.Nodes {
+			for _, edge := range n.deps {
+				if nodes[edge.To] {
+					result.edges = append(result.edges, edge)
+				}
+			}
+		}
+	}
+	return result, nil
+}
+
+func isModuleGraphQueryIdentRune(ch rune, i int) bool {
+	switch {
+	case ch >= 'a' && ch <= 'z', ch >= 'A' && ch <= 'Z', ch >= '0' && ch <= '9':
+		return true
+	case strings.ContainsRune("_-.+*@/:", ch):
+		return true
+	}
+	return false
+}
+
+type moduleGraphQueryParser struct {
+	graph   *ModuleGraph
+	scanner scanner.Scanner
+	tok     rune
+	err     error
+}
+
+func (p *moduleGraphQueryParser) errorf(format string, args ...interface{}) {
+	if p.err == nil {
+		p.err = fmt.Errorf("%s: %s", p.scanner.Position, fmt.Sprintf(format, args...))
+	}
+}
+
+func (p *moduleGraphQueryParser) next() {
+	if p.err == nil {
+		p.tok = p.scanner.Scan()
+	}
+}
+
+func (p *moduleGraphQueryParser) accept(tok rune) bool {
+	if p.tok != tok {
+		p.errorf("expected %s, found %q", scanner.TokenString(tok), p.scanner.TokenText())
+		return false
+	}
+	p.next()
+	return true
+}
+
+// parseExpression parses and evaluates an expression.  It returns the resulting set of nodes, and
+// for expressions that select specific edges the list of edges.
+func (p *moduleGraphQueryParser) parseExpression() (moduleGraphNodeSet, []ModuleGraphEdge) {
+	if p.tok != scanner.Ident {
+		p.errorf("expected module or function, found %q", p.scanner.TokenText())
+		return nil, nil
+	}
+
+	word := p.scanner.TokenText()
+	p.next()
+	if p.tok != '(' {
+		return p.targets(word), nil
+	}
+	p.next()
+
+	var nodes moduleGraphNodeSet
+	var edges []ModuleGraphEdge
+	switch word {
+	case "deps", "rdeps":
+		from, _ := p.parseExpression()
+		depth := -1
+		if p.tok == ',' {
+			p.next()
+			depth = p.parseInt()
+		}
+		nodes = p.graph.transitive(from, depth, word == "rdeps")
+	case "somepath", "allpaths":
+		from, _ := p.parseExpression()
+		p.accept(',')
+		to, _ := p.parseExpression()
+		if p.err != nil {
+			break
+		}
+		if word == "somepath" {
+			nodes, edges = p.graph.somepath(from, to)
+		} else {
+			nodes = p.graph.allpaths(from, to)
+		}
+	case "kind":
+		pattern := p.parseString()
+		p.accept(',')
+		from, _ := p.parseExpression()
+		if p.err != nil {
+			break
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			p.errorf("invalid kind pattern %q: %s", pattern, err)
+			break
+		}
+		nodes = make(moduleGraphNodeSet)
+		for n := range from {
+			if re.MatchString(n.Type) {
+				nodes[n] = true
+			}
+		}
+	default:
+		p.errorf("unknown function %q", word)
+	}
+
+	p.accept(')')
+	return nodes, edges
+}
+
+func (p *moduleGraphQueryParser) parseInt() int {
+	// Digits are valid in module names, so integers are scanned as identifiers.
+	text := p.scanner.TokenText()
+	if !p.accept(scanner.Ident) {
+		return 0
+	}
+	i, err := strconv.Atoi(text)
+	if err != nil {
+		p.errorf("invalid depth %q: %s", text, err)
+	}
+	return i
+}
+
+func (p *moduleGraphQueryParser) parseString() string {
+	text := p.scanner.TokenText()
+	switch p.tok {
+	case scanner.Ident:
+		p.next()
+		return text
+	case scanner.String:
+		p.next()
+		s, err := strconv.Unquote(text)
+		if err != nil {
+			p.errorf("invalid string %s: %s", text, err)
+		}
+		return s
+	}
+	p.errorf("expected string, found %q", text)
+	return ""
+}
+
+// targets returns the nodes matching a target pattern.
+func (p *moduleGraphQueryParser) targets(pattern string) moduleGraphNodeSet {
+	name, variant := pattern, ""
+	hasVariant := false
+	if i := strings.IndexRune(pattern, '@'); i >= 0 {
+		name, variant, hasVariant = pattern[:i], pattern[i+1:], true
+	}
+
+	var candidates []*ModuleGraphNode
+	if strings.Contains(name, "*") {
+		for _, n := range p.graph.nodes {
+			if match, _ := filepath.Match(name, n.Name); match {
+				candidates = append(candidates, n)
+			}
+		}
+	} else {
+		candidates = p.graph.nodesByName[name]
+	}
+
+	nodes := make(moduleGraphNodeSet)
+	for _, n := range candidates {
+		if !hasVariant || n.Variant == variant {
+			nodes[n] = true
+		}
+	}
+
+	if len(nodes) == 0 {
+		p.errorf("no modules match %q", pattern)
+	}
+	return nodes
+}
+
+// transitive returns the nodes reachable from the given nodes in at most depth steps, following
+// dependencies or reverse dependencies.  A negative depth is unlimited.
+func (g *ModuleGraph) transitive(from moduleGraphNodeSet, depth int, reverse bool) moduleGraphNodeSet {
+	visited := make(moduleGraphNodeSet)
+	queue := from.sorted()
+	for _, n := range queue {
+		visited[n] = true
+	}
+
+	for level := 0; len(queue) > 0 && (depth < 0 || level < depth); level++ {
+		var nextQueue []*ModuleGraphNode
+		for _, n := range queue {
+			edges := n.deps
+			if reverse {
+				edges = n.rdeps
+			}
+			for _, edge := range edges {
+				other := edge.To
+				if reverse {
+					other = edge.From
+				}
+				if !visited[other] {
+					visited[other] = true
+					nextQueue = append(nextQueue, other)
+				}
+			}
+		}
+		queue = nextQueue
+	}
+
+	return visited
+}
+
+// allpaths returns the nodes on any path from a node in from to a node in to.
+func (g *ModuleGraph) allpaths(from, to moduleGraphNodeSet) moduleGraphNodeSet {
+	forward := g.transitive(from, -1, false)
+	backward := g.transitive(to, -1, true)
+	nodes := make(moduleGraphNodeSet)
+	for n := range forward {
+		if backward[n] {
+			nodes[n] = true
+		}
+	}
+	return nodes
+}
+
+// somepath returns the nodes and edges on a shortest path from a node in from to a node in to, or
+// an empty set if there is no path.
+func (g *ModuleGraph) somepath(from, to moduleGraphNodeSet) (moduleGraphNodeSet, []ModuleGraphEdge) {
+	parents := make(map[*ModuleGraphNode]*ModuleGraphEdge)
+	visited := make(moduleGraphNodeSet)
+	queue := from.sorted()
+	for _, n := range queue {
+		visited[n] = true
+	}
+
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+		if to[n] {
+			nodes := moduleGraphNodeSet{n: true}
+			var edges []ModuleGraphEdge
+			for edge := parents[n]; edge != nil; edge = parents[edge.From] {
+				nodes[edge.From] = true
+				edges = append([]ModuleGraphEdge{*edge}, edges...)
+			}
+			return nodes, edges
+		}
+		for i := range n.deps {
+			edge := &n.deps[i]
+			if !visited[edge.To] {
+				visited[edge.To] = true
+				parents[edge.To] = edge
+				queue = append(queue, edge.To)
+			}
+		}
+	}
+
+	return moduleGraphNodeSet{}, []ModuleGraphEdge{}
+}
+
+// WriteText writes one line per node with the label, type and directory of the module.
+func (r *ModuleGraphQueryResult) WriteText(w io.Writer) error {
+	for _, n := range r.Nodes {
+		dir := n.Dir
+		if dir == "." {
+			dir = ""
+		}
+		if _, err := fmt.Fprintf(w, "%s (%s in //%s)\n", n.Label(), n.Type, dir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type moduleGraphNodeJson struct {
+	Name    string `json:"name"`
+	Variant string `json:"variant,omitempty"`
+	Type    string `json:"type"`
+	Dir     string `json:"dir"`
+}
+
+type moduleGraphEdgeJson struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	Tag  string `json:"tag,omitempty"`
+}
+
+type moduleGraphJson struct {
+	Nodes []moduleGraphNodeJson `json:"nodes"`
+	Edges []moduleGraphEdgeJson `json:"edges"`
+}
+
+// WriteJson writes the nodes and the edges between them as JSON.  Edges refer to nodes by label.
+func (r *ModuleGraphQueryResult) WriteJson(w io.Writer) error {
+	out := moduleGraphJson{
+		Nodes: make([]moduleGraphNodeJson, 0, len(r.Nodes)),
+		Edges: make([]moduleGraphEdgeJson, 0, len(r.edges)),
+	}
+	for _, n := range r.Nodes {
+		out.Nodes = append(out.Nodes, moduleGraphNodeJson{
+			Name:    n.Name,
+			Variant: n.Variant,
+			Type:    n.Type,
+			Dir:     n.Dir,
+		})
+	}
+	for _, e := range r.edges {
+		out.Edges = append(out.Edges, moduleGraphEdgeJson{
+			From: e.From.Label(),
+			To:   e.To.Label(),
+			Tag:  e.Tag,
+		})
+	}
+
+	buf, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(buf, '\n'))
+	return err
+}
+
+// WriteDot writes the nodes and the edges between them as a Graphviz dot graph, with the edges
+// labelled with the dependency tags.
+func (r *ModuleGraphQueryResult) WriteDot(w io.Writer) error {
+	var sb strings.Builder
+	sb.WriteString("digraph soong {\n")
+	for _, n := range r.Nodes {
+		fmt.Fprintf(&sb, "  %q [label=%q];\n", n.Label(), n.Label()+"\n"+n.Type)
+	}
+	for _, e := range r.edges {
+		if e.Tag != "" {
+			fmt.Fprintf(&sb, "  %q -> %q [label=%q];\n", e.From.Label(), e.To.Label(), e.Tag)
+		} else {
+			fmt.Fprintf(&sb, "  %q -> %q;\n", e.From.Label(), e.To.Label())
+		}
+	}
+	sb.WriteString("}\n")
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// Write writes the result in the given format, one of "text", "json" or "dot".
+func (r *ModuleGraphQueryResult) Write(w io.Writer, format string) error {
+	switch format {
+	case "", "text":
+		return r.WriteText(w)
+	case "json":
+		return r.WriteJson(w)
+	case "dot":
+		return r.WriteDot(w)
+	default:
+		return fmt.Errorf("unknown query output format %q, expected text, json or dot", format)
+	}
+}
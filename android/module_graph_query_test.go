@@ -0,0 +1,182 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"strings"
+	"testing"
+)
+
+func testModuleGraph(t *testing.T) *ModuleGraph {
+	t.Helper()
+	result := GroupFixturePreparers(
+		prepareForNeverAllowTest,
+		FixtureModifyConfig(func(config Config) {
+			config.SetCollectModuleGraph()
+		}),
+		FixtureWithRootAndroidBp(`
+			cc_library {
+				name: "liba",
+				static_libs: ["libb", "javad"],
+			}
+
+			cc_library {
+				name: "libb",
+				static_libs: ["libc"],
+			}
+
+			cc_library {
+				name: "libc",
+			}
+
+			java_library {
+				name: "javad",
+			}
+
+			cc_library {
+				name: "libe",
+			}
+		`),
+	).RunTest(t)
+
+	return NewModuleGraph(result.TestContext, result.Config)
+}
+
+func moduleGraphQueryLabels(t *testing.T, g *ModuleGraph, query string) []string {
+	t.Helper()
+	result, err := g.Query(query)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	var labels []string
+	for _, n := range result.Nodes {
+		labels = append(labels, n.Label())
+	}
+	return labels
+}
+
+func TestModuleGraphQuery(t *testing.T) {
+	g := testModuleGraph(t)
+
+	tests := []struct {
+		query    string
+		expected []string
+	}{
+		{"liba", []string{"liba"}},
+		{"lib*", []string{"liba", "libb", "libc", "libe"}},
+		{"deps(liba)", []string{"javad", "liba", "libb", "libc"}},
+		{"deps(liba, 1)", []string{"javad", "liba", "libb"}},
+		{"rdeps(libc)", []string{"liba", "libb", "libc"}},
+		{"rdeps(libc, 1)", []string{"libb", "libc"}},
+		{"somepath(liba, libc)", []string{"liba", "libb", "libc"}},
+		{"somepath(libe, libc)", nil},
+		{"allpaths(liba, libc)", []string{"liba", "libb", "libc"}},
+		{"kind(java_library, deps(liba))", []string{"javad"}},
+		{`kind("^cc_", rdeps(libc))`, []string{"liba", "libb", "libc"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.query, func(t *testing.T) {
+			AssertDeepEquals(t, "result", test.expected, moduleGraphQueryLabels(t, g, test.query))
+		})
+	}
+}
+
+func TestModuleGraphQueryErrors(t *testing.T) {
+	g := testModuleGraph(t)
+
+	tests := []struct {
+		query string
+		err   string
+	}{
+		{"libz", `no modules match "libz"`},
+		{"foo(liba)", `unknown function "foo"`},
+		{"deps(liba", `expected ")"`},
+		{"deps(liba, x)", `invalid depth "x"`},
+		{"somepath(liba)", `expected ","`},
+		{`kind("(", liba)`, `invalid kind pattern "("`},
+		{"liba libb", `unexpected "libb"`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.query, func(t *testing.T) {
+			_, err := g.Query(test.query)
+			if err == nil {
+				t.Fatalf("expected error containing %q", test.err)
+			}
+			AssertStringDoesContain(t, "error", err.Error(), test.err)
+		})
+	}
+}
+
+func TestModuleGraphQueryOutput(t *testing.T) {
+	g := testModuleGraph(t)
+
+	result, err := g.Query("somepath(liba, libc)")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	text := &strings.Builder{}
+	if err := result.Write(text, "text"); err != nil {
+		t.Fatal(err)
+	}
+	AssertStringEquals(t, "text", `liba (cc_library in //)
+libb (cc_library in //)
+libc (cc_library in //)
+`, text.String())
+
+	dot := &strings.Builder{}
+	if err := result.Write(dot, "dot"); err != nil {
+		t.Fatal(err)
+	}
+	AssertStringEquals(t, "dot", `digraph soong {
+  "liba" [label="liba\ncc_library"];
+  "libb" [label="libb\ncc_library"];
+  "libc" [label="libc\ncc_library"];
+  "liba" -> "libb" [label="android.neverallowTestDependencyTag{name=static}"];
+  "libb" -> "libc" [label="android.neverallowTestDependencyTag{name=static}"];
+}
+`, dot.String())
+
+	json := &strings.Builder{}
+	if err := result.Write(json, "json"); err != nil {
+		t.Fatal(err)
+	}
+	AssertStringDoesContain(t, "json", json.String(), `"from": "liba",
+      "to": "libb",
+      "tag": "android.neverallowTestDependencyTag{name=static}"`)
+
+	if err := result.Write(json, "xml"); err == nil {
+		t.Errorf("expected error for unknown format")
+	}
+}
+
+func TestModuleGraphMutatorOnlyRegisteredForQuery(t *testing.T) {
+	result := prepareForNeverAllowTest.RunTest(t)
+	if InList("module_graph", result.TestContext.mutatorOrder) {
+		t.Errorf("expected module_graph mutator to not be registered, got %q", result.TestContext.mutatorOrder)
+	}
+
+	result = GroupFixturePreparers(
+		prepareForNeverAllowTest,
+		FixtureModifyConfig(func(config Config) {
+			config.SetCollectModuleGraph()
+		}),
+	).RunTest(t)
+	if !InList("module_graph", result.TestContext.mutatorOrder) {
+		t.Errorf("expected module_graph mutator to be registered, got %q", result.TestContext.mutatorOrder)
+	}
+}
@@ -0,0 +1,129 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"encoding/json"
+	"sync"
+
+	"github.com/google/blueprint"
+)
+
+// Setting SOONG_WRITE_MODULE_OUTPUTS=true makes soong_build write out/soong/module_outputs.json,
+// which maps every output of a build statement generated by a module back to the module, variant
+// and rule that produced it.  It is used by the why_rebuilt tool to explain which module is
+// responsible for an action that ninja reran.
+
+const moduleOutputsFileName = "module_outputs.json"
+
+func init() {
+	RegisterModuleOutputsBuildComponents(InitRegistrationContext)
+}
+
+func RegisterModuleOutputsBuildComponents(ctx RegistrationContext) {
+	ctx.RegisterSingletonType("module_outputs", moduleOutputsSingletonFactory)
+}
+
+var PrepareForTestWithModuleOutputs = GroupFixturePreparers(
+	FixtureRegisterWithContext(RegisterModuleOutputsBuildComponents),
+	FixtureMergeEnv(map[string]string{
+		"SOONG_WRITE_MODULE_OUTPUTS": "true",
+	}),
+)
+
+// ModuleOutput describes the build statement that produces an output.
+type ModuleOutput struct {
+	// The name of the module that generated the build statement.
+	Module string `json:"module"`
+
+	// The variant of the module, empty if the module has no variants.
+	Variant string `json:"variant,omitempty"`
+
+	// The type and directory of the module.
+	Type string `json:"type"`
+	Dir  string `json:"dir"`
+
+	// The name of the ninja rule used by the build statement.
+	Rule string `json:"rule"`
+
+	// The explicit and implicit inputs of the build statement.  Inputs discovered through depfiles
+	// are only known to ninja.
+	Inputs []string `json:"inputs,omitempty"`
+}
+
+var moduleOutputsKey = NewOnceKey("moduleOutputs")
+
+// moduleOutputs returns the map from output path to the ModuleOutput that produces it.
+func moduleOutputs(config Config) *sync.Map {
+	return config.Once(moduleOutputsKey, func() interface{} {
+		return &sync.Map{}
+	}).(*sync.Map)
+}
+
+// recordModuleOutputs records the outputs of a build statement generated by a module.
+func recordModuleOutputs(ctx *moduleContext, params blueprint.BuildParams) {
+	output := ModuleOutput{
+		Module:  ctx.ModuleName(),
+		Variant: ctx.ModuleSubDir(),
+		Type:    ctx.ModuleType(),
+		Dir:     ctx.ModuleDir(),
+		Rule:    params.Rule.String(),
+		Inputs:  append(append([]string(nil), params.Inputs...), params.Implicits...),
+	}
+
+	outputs := moduleOutputs(ctx.Config())
+	for _, out := range params.Outputs {
+		outputs.Store(out, output)
+	}
+	for _, out := range params.ImplicitOutputs {
+		outputs.Store(out, output)
+	}
+}
+
+func moduleOutputsSingletonFactory() Singleton {
+	return &moduleOutputsSingleton{}
+}
+
+type moduleOutputsSingleton struct{}
+
+func (moduleOutputsSingleton) GenerateBuildActions(ctx SingletonContext) {
+	if !ctx.Config().WriteModuleOutputs() {
+		return
+	}
+
+	outputs := make(map[string]ModuleOutput)
+	moduleOutputs(ctx.Config()).Range(func(key, value interface{}) bool {
+		outputs[key.(string)] = value.(ModuleOutput)
+		return true
+	})
+
+	path := PathForOutput(ctx, moduleOutputsFileName)
+	data, err := json.Marshal(outputs)
+	if err != nil {
+		ctx.Errorf("JSON marshal of %s failed: %s", path, err)
+		return
+	}
+	if err := WriteFileToOutputDir(path, data, 0666); err != nil {
+		ctx.Errorf("Writing %s failed: %s", path, err)
+		return
+	}
+
+	// This is necessary to satisfy the dangling rules check as this file is written by Soong
+	// rather than a rule.
+	ctx.Build(pctx, BuildParams{
+		Rule:   Touch,
+		Output: path,
+	})
+}
@@ -0,0 +1,82 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+type moduleOutputsTestModule struct {
+	ModuleBase
+	properties struct {
+		Srcs []string
+	}
+}
+
+func moduleOutputsTestModuleFactory() Module {
+	m := &moduleOutputsTestModule{}
+	m.AddProperties(&m.properties)
+	InitAndroidModule(m)
+	return m
+}
+
+func (m *moduleOutputsTestModule) GenerateAndroidBuildActions(ctx ModuleContext) {
+	ctx.Build(pctx, BuildParams{
+		Rule:           Cp,
+		Input:          PathForModuleSrc(ctx, m.properties.Srcs[0]),
+		Output:         PathForModuleOut(ctx, "out"),
+		ImplicitOutput: PathForModuleOut(ctx, "out.d"),
+	})
+}
+
+func TestModuleOutputs(t *testing.T) {
+	result := GroupFixturePreparers(
+		PrepareForTestWithModuleOutputs,
+		FixtureRegisterWithContext(func(ctx RegistrationContext) {
+			ctx.RegisterModuleType("test", moduleOutputsTestModuleFactory)
+		}),
+		FixtureAddFile("foo/src.txt", nil),
+		FixtureAddTextFile("foo/Android.bp", `
+			test {
+				name: "foo",
+				srcs: ["src.txt"],
+			}
+		`),
+	).RunTest(t)
+
+	out := result.ModuleForTests("foo", "").Output("out")
+
+	data, err := ioutil.ReadFile(filepath.Join(result.Config.BuildDir(), moduleOutputsFileName))
+	if err != nil {
+		t.Fatalf("failed to read module outputs: %s", err)
+	}
+	var outputs map[string]ModuleOutput
+	if err := json.Unmarshal(data, &outputs); err != nil {
+		t.Fatalf("failed to parse module outputs: %s", err)
+	}
+
+	expected := ModuleOutput{
+		Module: "foo",
+		Type:   "test",
+		Dir:    "foo",
+		Rule:   Cp.String(),
+		Inputs: []string{"foo/src.txt"},
+	}
+	AssertDeepEquals(t, "output", expected, outputs[out.Output.String()])
+	AssertDeepEquals(t, "implicit output", expected, outputs[out.ImplicitOutput.String()])
+}
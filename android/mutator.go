@@ -71,13 +71,15 @@ func RegisterMutatorsForBazelConversion(ctx *Context, preArchMutators, depsMutat
 }
 
 // collateGloballyRegisteredMutators constructs the list of mutators that have been registered
-// with the InitRegistrationContext and will be used at runtime.
-func collateGloballyRegisteredMutators() sortableComponents {
-	return collateRegisteredMutators(preArch, preDeps, postDeps, finalDeps)
+// with the InitRegistrationContext and will be used at runtime.  The mutator that records the
+// module graph is only included if collectModuleGraph is true.
+func collateGloballyRegisteredMutators(collectModuleGraph bool) sortableComponents {
+	return collateRegisteredMutators(preArch, preDeps, postDeps, finalDeps, collectModuleGraph)
 }
 
 // collateRegisteredMutators constructs a single list of mutators from the separate lists.
-func collateRegisteredMutators(preArch, preDeps, postDeps, finalDeps []RegisterMutatorFunc) sortableComponents {
+func collateRegisteredMutators(preArch, preDeps, postDeps, finalDeps []RegisterMutatorFunc,
+	collectModuleGraph bool) sortableComponents {
 	mctx := &registerMutatorsContext{}
 
 	register := func(funcs []RegisterMutatorFunc) {
@@ -97,6 +99,12 @@ func collateRegisteredMutators(preArch, preDeps, postDeps, finalDeps []RegisterM
 	mctx.finalPhase = true
 	register(finalDeps)
 
+	// Must run after all other mutators so that it records the final module graph.  It visits every
+	// module, so it is only registered when the graph is needed by soong_build --query.
+	if collectModuleGraph {
+		register([]RegisterMutatorFunc{registerModuleGraphMutator})
+	}
+
 	return mctx.mutators
 }
 
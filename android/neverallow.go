@@ -41,6 +41,9 @@ import (
 // - - if the property is a list, any of the values in the list being matches
 //     counts as a match
 // - it has none of the "Without" properties matched (same rules as above)
+//
+// Additional rules can be loaded from files listed in the NeverallowRuleFiles product variable,
+// see neverallow_files.go.
 
 func registerNeverallowMutator(ctx RegisterMutatorsContext) {
 	ctx.BottomUp("neverallow", neverallowMutator).Parallel()
@@ -228,7 +231,12 @@ func neverallowMutator(ctx BottomUpMutatorContext) {
 
 	osClass := ctx.Module().Target().Os.Class
 
-	for _, r := range neverallowRules(ctx.Config()) {
+	rules := neverallowRules(ctx.Config())
+	if productRules := productNeverallowRules(ctx); len(productRules) > 0 {
+		rules = append(append([]Rule(nil), rules...), productRules...)
+	}
+
+	for _, r := range rules {
 		n := r.(*rule)
 		if !n.appliesToPath(dir) {
 			continue
@@ -254,7 +262,18 @@ func neverallowMutator(ctx BottomUpMutatorContext) {
 			continue
 		}
 
-		ctx.ModuleErrorf("violates " + n.String())
+		violation := PolicyViolation{
+			Kind:     "neverallow",
+			Rule:     n.String(),
+			RuleId:   n.id(),
+			Module:   ctx.ModuleName(),
+			Package:  ctx.ModuleDir(),
+			Property: n.propertyNames(),
+			Reason:   n.reason,
+		}
+		if handlePolicyViolation(ctx, violation) {
+			ctx.ModuleErrorf("violates " + n.String())
+		}
 	}
 }
 
@@ -337,6 +356,7 @@ func (m *isSetMatcher) String() string {
 var isSetMatcherInstance = &isSetMatcher{}
 
 type ruleProperty struct {
+	name    string   // e.x.: vndk.enabled
 	fields  []string // e.x.: Vndk.Enabled
 	matcher ValueMatcher
 }
@@ -366,12 +386,18 @@ type Rule interface {
 	WithoutMatcher(properties string, matcher ValueMatcher) Rule
 
 	Because(reason string) Rule
+
+	// Named sets a name that identifies the rule in policy violation baselines.
+	Named(name string) Rule
 }
 
 type rule struct {
 	// User string for why this is a thing.
 	reason string
 
+	// Name that identifies the rule in policy violation baselines, if set.
+	name string
+
 	paths       []string
 	unlessPaths []string
 
@@ -386,6 +412,9 @@ type rule struct {
 	unlessProps []ruleProperty
 
 	onlyBootclasspathJar bool
+
+	// The location of the definition for rules loaded from a rule file.
+	definedIn string
 }
 
 // Create a new NeverAllow rule.
@@ -431,6 +460,7 @@ func (r *rule) With(properties, value string) Rule {
 
 func (r *rule) WithMatcher(properties string, matcher ValueMatcher) Rule {
 	r.props = append(r.props, ruleProperty{
+		name:    properties,
 		fields:  fieldNamesForProperties(properties),
 		matcher: matcher,
 	})
@@ -443,6 +473,7 @@ func (r *rule) Without(properties, value string) Rule {
 
 func (r *rule) WithoutMatcher(properties string, matcher ValueMatcher) Rule {
 	r.unlessProps = append(r.unlessProps, ruleProperty{
+		name:    properties,
 		fields:  fieldNamesForProperties(properties),
 		matcher: matcher,
 	})
@@ -461,12 +492,39 @@ func (r *rule) Because(reason string) Rule {
 	return r
 }
 
+func (r *rule) Named(name string) Rule {
+	r.name = name
+	return r
+}
+
 func (r *rule) BootclasspathJar() Rule {
 	r.onlyBootclasspathJar = true
 	return r
 }
 
 func (r *rule) String() string {
+	s := r.conditions()
+	if len(r.reason) != 0 {
+		s += " which is restricted because " + r.reason
+	}
+	if r.definedIn != "" {
+		s += " (defined at " + r.definedIn + ")"
+	}
+	return s
+}
+
+// id returns the name of the rule, or its conditions if it has no name.  Unlike String it doesn't
+// change when the reason or the location of the definition of the rule changes, so it is used to
+// match violations against a baseline.
+func (r *rule) id() string {
+	if r.name != "" {
+		return r.name
+	}
+	return r.conditions()
+}
+
+// conditions returns a description of the modules the rule applies to.
+func (r *rule) conditions() string {
 	s := "neverallow"
 	for _, v := range r.paths {
 		s += " dir:" + v + "*"
@@ -486,7 +544,7 @@ func (r *rule) String() string {
 	for _, v := range r.unlessProps {
 		s += " -" + strings.Join(v.fields, ".") + v.matcher.String()
 	}
-	for k := range r.directDeps {
+	for _, k := range SortedStringKeys(r.directDeps) {
 		s += " deps:" + k
 	}
 	for _, v := range r.osClasses {
@@ -495,12 +553,21 @@ func (r *rule) String() string {
 	if r.onlyBootclasspathJar {
 		s += " inBcp"
 	}
-	if len(r.reason) != 0 {
-		s += " which is restricted because " + r.reason
-	}
 	return s
 }
 
+// propertyNames returns a comma separated list of the properties checked by the rule.
+func (r *rule) propertyNames() string {
+	var names []string
+	for _, v := range r.props {
+		names = append(names, v.name)
+	}
+	for _, v := range r.unlessProps {
+		names = append(names, v.name)
+	}
+	return strings.Join(FirstUniqueStrings(names), ",")
+}
+
 func (r *rule) appliesToPath(dir string) bool {
 	includePath := len(r.paths) == 0 || HasAnyPrefix(dir, r.paths)
 	excludePath := HasAnyPrefix(dir, r.unlessPaths)
@@ -0,0 +1,296 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/google/blueprint/parser"
+	"github.com/google/blueprint/proptools"
+	"github.com/google/blueprint/scanner"
+)
+
+// Neverallow rules loaded from checked-in files.
+//
+// Products can add neverallow rules without modifying soong by listing Blueprint syntax files in
+// the NeverallowRuleFiles product variable.  Each file contains one or more neverallow
+// definitions that map directly onto the methods of the Rule interface:
+//
+//   neverallow {
+//       name: "acme-no-external-includes",
+//       in: ["vendor/acme"],
+//       not_in: ["vendor/acme/legacy"],
+//       module_type: ["cc_library", "cc_library_shared"],
+//       with: ["include_dirs.starts-with(external/)"],
+//       without: ["vendor=true"],
+//       because: "acme libraries must not reach into external/ directly",
+//   }
+//
+// Entries in with and without use the same syntax that is used to print a rule:
+// - "prop=value" matches the value exactly, "prop=*" matches any value
+// - "prop.is-set" matches any non-empty value
+// - "prop.starts-with(prefix)" matches values starting with prefix
+// - "prop.regexp(re)" matches values matching the regular expression re
+// - "prop.not-in-list(a,b)" matches values that are not in the list
+//
+// Rules loaded from files are applied by neverallowMutator alongside the built in rules, and the
+// location of the definition is included in the error reported for a violation.
+
+// neverallowFileRuleProperties are the properties of a neverallow definition in a rule file.
+type neverallowFileRuleProperties struct {
+	// a name that identifies the rule in policy violation baselines.  If not set the rule is
+	// identified by the modules it applies to.
+	Name *string
+
+	// the directories the rule applies to.  If empty the rule applies to all directories.
+	In []string
+
+	// the directories the rule does not apply to.
+	Not_in []string
+
+	// the names of modules that a module must directly depend upon for the rule to apply.
+	In_direct_deps []string
+
+	// the os classes the rule applies to, one of "device" or "host".
+	Os_class []string
+
+	// the module types the rule applies to.  If empty the rule applies to all module types.
+	Module_type []string
+
+	// the module types the rule does not apply to.
+	Not_module_type []string
+
+	// property matchers which must all match for the rule to apply.
+	With []string
+
+	// property matchers which must all not match for the rule to apply.
+	Without []string
+
+	// if true the rule only applies to modules on the bootclasspath.
+	Bootclasspath_jar *bool
+
+	// the reason why the rule exists, required.
+	Because *string
+}
+
+// parseNeverallowRules parses the neverallow definitions in a Blueprint syntax rule file.
+func parseNeverallowRules(r io.Reader, from string) ([]Rule, []error) {
+	scope := parser.NewScope(nil)
+	file, errs := parser.ParseAndEval(from, r, scope)
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	var rules []Rule
+	for _, def := range file.Defs {
+		switch def := def.(type) {
+		case *parser.Module:
+			if def.Type != "neverallow" {
+				errs = append(errs, &parser.ParseError{
+					Err: fmt.Errorf("unknown definition type %q, expected neverallow", def.Type),
+					Pos: def.TypePos,
+				})
+				continue
+			}
+			rule, ruleErrs := neverallowRuleFromDef(def)
+			if len(ruleErrs) > 0 {
+				errs = append(errs, ruleErrs...)
+				continue
+			}
+			rules = append(rules, rule)
+		case *parser.Assignment:
+			// Already handled via Scope object
+		default:
+			panic("unknown definition type")
+		}
+	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	return rules, nil
+}
+
+func neverallowRuleFromDef(def *parser.Module) (Rule, []error) {
+	props := &neverallowFileRuleProperties{}
+	if _, errs := proptools.UnpackProperties(def.Properties, props); len(errs) > 0 {
+		return nil, errs
+	}
+
+	var errs []error
+	propertyErrorf := func(format string, args ...interface{}) {
+		errs = append(errs, &parser.ParseError{
+			Err: fmt.Errorf(format, args...),
+			Pos: def.TypePos,
+		})
+	}
+
+	if String(props.Because) == "" {
+		propertyErrorf("because property must be set")
+	}
+
+	r := NeverAllow().(*rule)
+	r.definedIn = def.TypePos.String()
+
+	if len(props.In) > 0 {
+		r.In(props.In...)
+	}
+	if len(props.Not_in) > 0 {
+		r.NotIn(props.Not_in...)
+	}
+	r.InDirectDeps(props.In_direct_deps...)
+	r.ModuleType(props.Module_type...)
+	r.NotModuleType(props.Not_module_type...)
+
+	for _, class := range props.Os_class {
+		switch class {
+		case "device":
+			r.WithOsClass(Device)
+		case "host":
+			r.WithOsClass(Host)
+		default:
+			propertyErrorf("os_class: unknown os class %q, expected device or host", class)
+		}
+	}
+
+	for _, with := range props.With {
+		properties, matcher, err := parseNeverallowPropertyMatcher(with)
+		if err != nil {
+			propertyErrorf("with: %s", err)
+			continue
+		}
+		r.WithMatcher(properties, matcher)
+	}
+
+	for _, without := range props.Without {
+		properties, matcher, err := parseNeverallowPropertyMatcher(without)
+		if err != nil {
+			propertyErrorf("without: %s", err)
+			continue
+		}
+		r.WithoutMatcher(properties, matcher)
+	}
+
+	if Bool(props.Bootclasspath_jar) {
+		r.BootclasspathJar()
+	}
+
+	r.Because(String(props.Because))
+	if name := String(props.Name); name != "" {
+		r.Named(name)
+	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	return r, nil
+}
+
+// parseNeverallowPropertyMatcher parses a property matcher in the format printed by rule.String()
+// and returns the property names and the ValueMatcher.
+func parseNeverallowPropertyMatcher(s string) (string, ValueMatcher, error) {
+	if strings.HasSuffix(s, ")") {
+		functions := []struct {
+			name   string
+			create func(arg string) (ValueMatcher, error)
+		}{
+			{"starts-with", func(arg string) (ValueMatcher, error) { return StartsWith(arg), nil }},
+			{"regexp", func(arg string) (ValueMatcher, error) {
+				re, err := regexp.Compile(arg)
+				if err != nil {
+					return nil, err
+				}
+				return &regexMatcher{re}, nil
+			}},
+			{"not-in-list", func(arg string) (ValueMatcher, error) {
+				return NotInList(strings.Split(arg, ",")), nil
+			}},
+		}
+		for _, f := range functions {
+			if i := strings.Index(s, "."+f.name+"("); i > 0 {
+				arg := s[i+len(f.name)+2 : len(s)-1]
+				matcher, err := f.create(arg)
+				if err != nil {
+					return "", nil, fmt.Errorf("invalid matcher %q: %s", s, err)
+				}
+				return s[:i], matcher, nil
+			}
+		}
+	}
+
+	if strings.HasSuffix(s, ".is-set") {
+		properties := strings.TrimSuffix(s, ".is-set")
+		if properties == "" {
+			return "", nil, fmt.Errorf("invalid matcher %q: missing property name", s)
+		}
+		return properties, isSetMatcherInstance, nil
+	}
+
+	i := strings.Index(s, "=")
+	if i <= 0 {
+		return "", nil, fmt.Errorf("invalid matcher %q: expected property=value", s)
+	}
+	return s[:i], selectMatcher(s[i+1:]), nil
+}
+
+var productNeverallowRulesKey = NewOnceKey("productNeverallowRules")
+
+type productNeverallowRulesResult struct {
+	rules []Rule
+	errs  []error
+
+	reportOnce sync.Once
+}
+
+// productNeverallowRules returns the neverallow rules loaded from the files listed in the
+// NeverallowRuleFiles product variable.  Errors loading the files are reported once on the first
+// module that requests the rules.
+func productNeverallowRules(ctx BottomUpMutatorContext) []Rule {
+	config := ctx.Config()
+	result := config.Once(productNeverallowRulesKey, func() interface{} {
+		result := &productNeverallowRulesResult{}
+		for _, file := range config.NeverallowRuleFiles() {
+			config.addNinjaFileDeps(file)
+			r, err := config.fs.Open(file)
+			if err != nil {
+				result.errs = append(result.errs, fmt.Errorf("failed to open neverallow rule file %q: %s", file, err))
+				continue
+			}
+			rules, errs := parseNeverallowRules(r, file)
+			r.Close()
+			result.rules = append(result.rules, rules...)
+			result.errs = append(result.errs, errs...)
+		}
+		return result
+	}).(*productNeverallowRulesResult)
+
+	result.reportOnce.Do(func() {
+		for _, err := range result.errs {
+			if parseErr, ok := err.(*parser.ParseError); ok {
+				ctx.Errorf(parseErr.Pos, "%s", parseErr.Err)
+			} else {
+				ctx.Errorf(scanner.Position{}, "%s", err)
+			}
+		}
+	})
+
+	return result.rules
+}
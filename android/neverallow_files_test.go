@@ -0,0 +1,195 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"strings"
+	"testing"
+)
+
+var neverallowRuleFileTests = []struct {
+	// The name of the test.
+	name string
+
+	// The contents of the rule file.
+	ruleFile string
+
+	// Additional contents to add to the virtual filesystem used by the tests.
+	fs MockFS
+
+	// The expected error patterns, see neverallowTests.
+	expectedErrors []string
+}{
+	{
+		name: "rule from file",
+		ruleFile: `
+			neverallow {
+				in: ["vendor/acme"],
+				module_type: ["cc_library"],
+				with: ["include_dirs.starts-with(external/)"],
+				because: "acme must not reach into external",
+			}`,
+		fs: map[string][]byte{
+			"vendor/acme/Android.bp": []byte(`
+				cc_library {
+					name: "libacme",
+					include_dirs: ["external/foo"],
+				}`),
+			"vendor/other/Android.bp": []byte(`
+				cc_library {
+					name: "libother",
+					include_dirs: ["external/foo"],
+				}`),
+		},
+		expectedErrors: []string{
+			`module "libacme": violates neverallow dir:vendor/acme/\* type:cc_library include_dirs.starts-with\(external/\) which is restricted because acme must not reach into external \(defined at neverallow.bp:2:4\)`,
+		},
+	},
+	{
+		name: "without matcher from file",
+		ruleFile: `
+			neverallow {
+				with: ["vndk.enabled=true"],
+				without: ["vendor_available.is-set"],
+				because: "vndk libraries must be vendor available",
+			}`,
+		fs: map[string][]byte{
+			"Android.bp": []byte(`
+				cc_library {
+					name: "libvendor_available",
+					vendor_available: true,
+					vndk: {
+						enabled: true,
+					},
+				}
+
+				cc_library {
+					name: "libnot_vendor_available",
+					vndk: {
+						enabled: true,
+					},
+				}`),
+		},
+		expectedErrors: []string{
+			`module "libnot_vendor_available": violates neverallow`,
+		},
+	},
+	{
+		name: "missing because",
+		ruleFile: `
+			neverallow {
+				module_type: ["cc_library"],
+			}`,
+		expectedErrors: []string{
+			`neverallow.bp:2:4: because property must be set`,
+		},
+	},
+	{
+		name: "invalid matcher",
+		ruleFile: `
+			neverallow {
+				with: ["include_dirs"],
+				because: "invalid",
+			}`,
+		expectedErrors: []string{
+			`with: invalid matcher "include_dirs": expected property=value`,
+		},
+	},
+	{
+		name: "unknown definition",
+		ruleFile: `
+			cc_library {
+				name: "libfoo",
+			}`,
+		expectedErrors: []string{
+			`unknown definition type "cc_library", expected neverallow`,
+		},
+	},
+}
+
+func TestNeverallowRuleFiles(t *testing.T) {
+	for _, test := range neverallowRuleFileTests {
+		t.Run(test.name, func(t *testing.T) {
+			GroupFixturePreparers(
+				prepareForNeverAllowTest,
+				PrepareForTestWithNeverallowRules([]Rule{}),
+				FixtureModifyProductVariables(func(variables FixtureProductVariables) {
+					variables.NeverallowRuleFiles = []string{"neverallow.bp"}
+				}),
+				FixtureAddTextFile("neverallow.bp", test.ruleFile),
+				test.fs.AddToFixture(),
+			).
+				ExtendWithErrorHandler(FixtureExpectsAllErrorsToMatchAPattern(test.expectedErrors)).
+				RunTest(t)
+		})
+	}
+}
+
+func TestParseNeverallowPropertyMatcher(t *testing.T) {
+	tests := []struct {
+		in         string
+		properties string
+		matcher    string
+		err        string
+	}{
+		{in: "vndk.enabled=true", properties: "vndk.enabled", matcher: "=true"},
+		{in: "owner=*", properties: "owner", matcher: "=*"},
+		{in: "owner=", properties: "owner", matcher: "="},
+		{in: "sdk_variant_only.is-set", properties: "sdk_variant_only", matcher: ".is-set"},
+		{in: "include_dirs.starts-with(art/)", properties: "include_dirs", matcher: ".starts-with(art/)"},
+		{in: "product_out_path.regexp(^boot.*$)", properties: "product_out_path", matcher: ".regexp(^boot.*$)"},
+		{in: "libs.not-in-list(a,b)", properties: "libs", matcher: ".not-in-list(a,b)"},
+		{in: "cflags=-DFOO(x)", properties: "cflags", matcher: "=-DFOO(x)"},
+		{in: "owner", err: `invalid matcher "owner": expected property=value`},
+		{in: ".is-set", err: `invalid matcher ".is-set": missing property name`},
+		{in: "srcs.regexp(()", err: "invalid matcher \"srcs.regexp(()\": error parsing regexp: missing closing ): `(`"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.in, func(t *testing.T) {
+			properties, matcher, err := parseNeverallowPropertyMatcher(test.in)
+			if test.err != "" {
+				AssertErrorMessageEquals(t, "error", test.err, err)
+				return
+			}
+			AssertStringEquals(t, "properties", test.properties, properties)
+			AssertStringEquals(t, "matcher", test.matcher, matcher.String())
+		})
+	}
+}
+
+func TestNeverallowRuleFileIds(t *testing.T) {
+	rules, errs := parseNeverallowRules(strings.NewReader(`
+		neverallow {
+			name: "acme-no-external-includes",
+			in: ["vendor/acme"],
+			with: ["include_dirs.starts-with(external/)"],
+			because: "acme must not reach into external",
+		}
+
+		neverallow {
+			in: ["vendor/acme"],
+			in_direct_deps: ["libb", "liba"],
+			because: "acme must not depend on liba and libb",
+		}`), "neverallow.bp")
+	if len(errs) > 0 {
+		t.Fatalf("unexpected errors: %q", errs)
+	}
+
+	AssertStringEquals(t, "named rule", "acme-no-external-includes", rules[0].(*rule).id())
+	// The id of a rule without a name doesn't include the reason or where it is defined.
+	AssertStringEquals(t, "unnamed rule", "neverallow dir:vendor/acme/* deps:liba deps:libb",
+		rules[1].(*rule).id())
+}
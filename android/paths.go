@@ -1632,6 +1632,29 @@ func (p InstallPath) withRel(rel string) InstallPath {
 	return p
 }
 
+// ToSymbolsPath returns the path in the symbols directory of the device that corresponds to this
+// device install path, e.g. out/target/product/<device>/symbols/system/bin for
+// out/target/product/<device>/system/bin.  Make copies the unstripped version of every installed
+// binary and shared library there.
+func (p InstallPath) ToSymbolsPath(ctx PathContext) InstallPath {
+	deviceDir := filepath.Join("target", "product", ctx.Config().DeviceName())
+	rel, err := filepath.Rel(deviceDir, p.path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, "../") {
+		ReportPathErrorf(ctx, "%q is not installed to the device", p.path)
+		return p
+	}
+	partitionRel, err := filepath.Rel(deviceDir, p.partitionDir)
+	if err != nil {
+		ReportPathErrorf(ctx, "%q is not installed to the device", p.path)
+		return p
+	}
+	symbolsDir := filepath.Join(deviceDir, "symbols")
+	p.basePath = basePath{filepath.Join(symbolsDir, rel), ""}
+	p.partitionDir = filepath.Join(symbolsDir, partitionRel)
+	p.makePath = true
+	return p
+}
+
 // ToMakePath returns a new InstallPath that points to Make's install directory instead of Soong's,
 // i.e. out/ instead of out/soong/.
 func (p InstallPath) ToMakePath() InstallPath {
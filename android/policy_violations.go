@@ -0,0 +1,240 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"sync"
+
+	"github.com/google/blueprint/scanner"
+)
+
+// Reporting of neverallow and visibility violations.
+//
+// By default every violation of a neverallow or visibility rule is a build error.  When migrating
+// a large tree that makes it hard to see how much work is left, so violations can instead be
+// collected into a report:
+// - Setting SOONG_REPORT_POLICY_VIOLATIONS=true or the ReportPolicyViolations product variable
+//   enables report mode.  Every violation is written to out/soong/policy_violations.json and the
+//   build keeps going.
+// - Setting the PolicyViolationsBaseline product variable to a file in the format of the report
+//   suppresses the violations listed in it.  Violations that are not in the baseline are still
+//   errors, even in report mode.  Violations are matched by their rule_id rather than the rule
+//   text, so moving a rule or editing its reason doesn't invalidate the baseline.  Rules in rule
+//   files can set a name to keep their id stable when their conditions change.
+
+func init() {
+	RegisterPolicyViolationsBuildComponents(InitRegistrationContext)
+}
+
+func RegisterPolicyViolationsBuildComponents(ctx RegistrationContext) {
+	ctx.RegisterSingletonType("policy_violations", policyViolationsSingletonFactory)
+}
+
+var PrepareForTestWithPolicyViolations = FixtureRegisterWithContext(RegisterPolicyViolationsBuildComponents)
+
+const policyViolationsFileName = "policy_violations.json"
+
+// PolicyViolation describes a single violation of a neverallow or visibility rule.
+type PolicyViolation struct {
+	// The kind of rule that was violated, either "neverallow" or "visibility".
+	Kind string `json:"kind"`
+
+	// The rule that was violated.
+	Rule string `json:"rule"`
+
+	// The name of the rule that was violated, or a description of the modules it applies to if it
+	// has no name.  Unlike Rule it doesn't include the reason for the rule or where it is defined,
+	// so that editing them doesn't invalidate a baseline.  For visibility violations it is the
+	// dependency, so that editing the visibility of the dependency doesn't invalidate a baseline.
+	RuleId string `json:"rule_id"`
+
+	// The name of the module that violated the rule.
+	Module string `json:"module"`
+
+	// The package (i.e. directory) of the module that violated the rule.
+	Package string `json:"package"`
+
+	// The properties that were checked by the rule, if any.
+	Property string `json:"property,omitempty"`
+
+	// The dependency that was not visible, for visibility violations.
+	Dependency string `json:"dependency,omitempty"`
+
+	// The reason the rule exists.
+	Reason string `json:"reason,omitempty"`
+}
+
+// baselineKey returns the violation without the parts that are not used to match a violation
+// against a baseline.  Violations are matched on the module, the property and the id of the rule,
+// the description of the rule is ignored as it includes the location of its definition.
+func (v PolicyViolation) baselineKey() PolicyViolation {
+	v.Rule = ""
+	v.Reason = ""
+	return v
+}
+
+type policyViolations struct {
+	lock       sync.Mutex
+	violations map[PolicyViolation]bool
+}
+
+var policyViolationsKey = NewOnceKey("policyViolations")
+
+func getPolicyViolations(config Config) *policyViolations {
+	return config.Once(policyViolationsKey, func() interface{} {
+		return &policyViolations{violations: make(map[PolicyViolation]bool)}
+	}).(*policyViolations)
+}
+
+func (p *policyViolations) add(v PolicyViolation) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.violations[v] = true
+}
+
+// sorted returns the recorded violations in a stable order.
+func (p *policyViolations) sorted() []PolicyViolation {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	list := make([]PolicyViolation, 0, len(p.violations))
+	for v := range p.violations {
+		list = append(list, v)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		a, b := list[i], list[j]
+		if a.Package != b.Package {
+			return a.Package < b.Package
+		}
+		if a.Module != b.Module {
+			return a.Module < b.Module
+		}
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		if a.Dependency != b.Dependency {
+			return a.Dependency < b.Dependency
+		}
+		if a.RuleId != b.RuleId {
+			return a.RuleId < b.RuleId
+		}
+		return a.Rule < b.Rule
+	})
+	return list
+}
+
+type policyViolationsBaseline struct {
+	violations map[PolicyViolation]bool
+	err        error
+
+	reportOnce sync.Once
+}
+
+var policyViolationsBaselineKey = NewOnceKey("policyViolationsBaseline")
+
+// loadPolicyViolationsBaseline returns the set of violations listed in the baseline file, or nil
+// if there is no baseline.  Errors loading the baseline are reported once on the first module
+// that requests it.
+func loadPolicyViolationsBaseline(ctx BaseModuleContext) map[PolicyViolation]bool {
+	config := ctx.Config()
+	baseline := config.Once(policyViolationsBaselineKey, func() interface{} {
+		baseline := &policyViolationsBaseline{}
+		file := config.PolicyViolationsBaseline()
+		if file == "" {
+			return baseline
+		}
+		config.addNinjaFileDeps(file)
+		r, err := config.fs.Open(file)
+		if err != nil {
+			baseline.err = fmt.Errorf("failed to open policy violations baseline %q: %s", file, err)
+			return baseline
+		}
+		defer r.Close()
+		data, err := ioutil.ReadAll(r)
+		if err != nil {
+			baseline.err = fmt.Errorf("failed to read policy violations baseline %q: %s", file, err)
+			return baseline
+		}
+		var list []PolicyViolation
+		if err := json.Unmarshal(data, &list); err != nil {
+			baseline.err = fmt.Errorf("failed to parse policy violations baseline %q: %s", file, err)
+			return baseline
+		}
+		baseline.violations = make(map[PolicyViolation]bool, len(list))
+		for _, v := range list {
+			baseline.violations[v.baselineKey()] = true
+		}
+		return baseline
+	}).(*policyViolationsBaseline)
+
+	baseline.reportOnce.Do(func() {
+		if baseline.err != nil {
+			ctx.Errorf(scanner.Position{}, "%s", baseline.err)
+		}
+	})
+
+	return baseline.violations
+}
+
+// handlePolicyViolation records a violation of a neverallow or visibility rule and returns true if
+// the caller should report it as an error.
+func handlePolicyViolation(ctx BaseModuleContext, v PolicyViolation) bool {
+	reportMode := ctx.Config().ReportPolicyViolations()
+	if reportMode {
+		getPolicyViolations(ctx.Config()).add(v)
+	}
+
+	baseline := loadPolicyViolationsBaseline(ctx)
+	if baseline != nil {
+		return !baseline[v.baselineKey()]
+	}
+
+	return !reportMode
+}
+
+func policyViolationsSingletonFactory() Singleton {
+	return &policyViolationsSingleton{}
+}
+
+type policyViolationsSingleton struct{}
+
+func (policyViolationsSingleton) GenerateBuildActions(ctx SingletonContext) {
+	if !ctx.Config().ReportPolicyViolations() {
+		return
+	}
+
+	violations := getPolicyViolations(ctx.Config()).sorted()
+	buf, err := json.MarshalIndent(violations, "", "  ")
+	if err != nil {
+		ctx.Errorf("JSON marshal of policy violations failed: %s", err)
+		return
+	}
+
+	reportPath := PathForOutput(ctx, policyViolationsFileName)
+	if err := WriteFileToOutputDir(reportPath, buf, 0666); err != nil {
+		ctx.Errorf("Writing policy violations to %s failed: %s", reportPath.String(), err)
+		return
+	}
+
+	// This is necessary to satisfy the dangling rules check as this file is written by Soong rather
+	// than a rule.
+	ctx.Build(pctx, BuildParams{
+		Rule:   Touch,
+		Output: reportPath,
+	})
+}
@@ -0,0 +1,220 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+var prepareForPolicyViolationsTest = GroupFixturePreparers(
+	prepareForNeverAllowTest,
+	PrepareForTestWithVisibility,
+	PrepareForTestWithPolicyViolations,
+	PrepareForTestWithNeverallowRules([]Rule{
+		NeverAllow().In("vendor").WithMatcher("include_dirs", isSetMatcherInstance).Because("no include_dirs in vendor"),
+	}),
+	FixtureWithRootAndroidBp(`
+		cc_library {
+			name: "libprivate",
+			visibility: ["//visibility:private"],
+		}
+	`),
+	FixtureAddTextFile("vendor/Android.bp", `
+		cc_library {
+			name: "libvendor",
+			include_dirs: ["foo"],
+			static_libs: ["libprivate"],
+		}
+	`),
+)
+
+func readPolicyViolationsReport(t *testing.T, config Config) []PolicyViolation {
+	t.Helper()
+	data, err := ioutil.ReadFile(filepath.Join(config.BuildDir(), policyViolationsFileName))
+	if err != nil {
+		t.Fatalf("failed to read report: %s", err)
+	}
+	var violations []PolicyViolation
+	if err := json.Unmarshal(data, &violations); err != nil {
+		t.Fatalf("failed to parse report: %s", err)
+	}
+	return violations
+}
+
+func TestPolicyViolationsFailByDefault(t *testing.T) {
+	prepareForPolicyViolationsTest.
+		ExtendWithErrorHandler(FixtureExpectsAllErrorsToMatchAPattern([]string{
+			`module "libvendor": violates neverallow dir:vendor/\* include_dirs.is-set`,
+			`module "libvendor": depends on //:libprivate which is not visible to this module`,
+		})).
+		RunTest(t)
+}
+
+func TestPolicyViolationsReportMode(t *testing.T) {
+	result := GroupFixturePreparers(
+		prepareForPolicyViolationsTest,
+		FixtureModifyProductVariables(func(variables FixtureProductVariables) {
+			variables.ReportPolicyViolations = boolPtr(true)
+		}),
+	).RunTest(t)
+
+	expected := []PolicyViolation{
+		{
+			Kind:     "neverallow",
+			Rule:     "neverallow dir:vendor/* include_dirs.is-set which is restricted because no include_dirs in vendor",
+			RuleId:   "neverallow dir:vendor/* include_dirs.is-set",
+			Module:   "libvendor",
+			Package:  "vendor",
+			Property: "include_dirs",
+			Reason:   "no include_dirs in vendor",
+		},
+		{
+			Kind:       "visibility",
+			Rule:       "[//visibility:private]",
+			RuleId:     "//:libprivate",
+			Module:     "libvendor",
+			Package:    "vendor",
+			Property:   "visibility",
+			Dependency: "//:libprivate",
+			Reason:     "//:libprivate is not visible to this module",
+		},
+	}
+
+	AssertDeepEquals(t, "violations", expected, readPolicyViolationsReport(t, result.Config))
+}
+
+func TestPolicyViolationsBaseline(t *testing.T) {
+	baseline := `[
+		{
+			"kind": "visibility",
+			"rule": "[//visibility:private]",
+			"rule_id": "//:libprivate",
+			"module": "libvendor",
+			"package": "vendor",
+			"property": "visibility",
+			"dependency": "//:libprivate"
+		}
+	]`
+
+	t.Run("new violations fail", func(t *testing.T) {
+		GroupFixturePreparers(
+			prepareForPolicyViolationsTest,
+			FixtureAddTextFile("baseline.json", baseline),
+			FixtureModifyProductVariables(func(variables FixtureProductVariables) {
+				variables.PolicyViolationsBaseline = stringPtr("baseline.json")
+			}),
+		).
+			ExtendWithErrorHandler(FixtureExpectsAllErrorsToMatchAPattern([]string{
+				`module "libvendor": violates neverallow dir:vendor/\* include_dirs.is-set`,
+			})).
+			RunTest(t)
+	})
+
+	t.Run("new violations fail in report mode", func(t *testing.T) {
+		GroupFixturePreparers(
+			prepareForPolicyViolationsTest,
+			FixtureAddTextFile("baseline.json", baseline),
+			FixtureModifyProductVariables(func(variables FixtureProductVariables) {
+				variables.ReportPolicyViolations = boolPtr(true)
+				variables.PolicyViolationsBaseline = stringPtr("baseline.json")
+			}),
+		).
+			ExtendWithErrorHandler(FixtureExpectsAllErrorsToMatchAPattern([]string{
+				`module "libvendor": violates neverallow dir:vendor/\* include_dirs.is-set`,
+			})).
+			RunTest(t)
+	})
+
+	t.Run("matches rules by id", func(t *testing.T) {
+		// The rule and reason of the neverallow violation are out of date, as if the rule had been
+		// moved or its reason edited since the baseline was written.
+		GroupFixturePreparers(
+			prepareForPolicyViolationsTest,
+			FixtureAddTextFile("baseline.json", `[
+				{
+					"kind": "neverallow",
+					"rule": "neverallow dir:vendor/* include_dirs.is-set (defined at old/rules.bp:1:1)",
+					"rule_id": "neverallow dir:vendor/* include_dirs.is-set",
+					"module": "libvendor",
+					"package": "vendor",
+					"property": "include_dirs",
+					"reason": "old reason"
+				},
+				{
+					"kind": "visibility",
+					"rule_id": "//:libprivate",
+					"module": "libvendor",
+					"package": "vendor",
+					"property": "visibility",
+					"dependency": "//:libprivate"
+				}
+			]`),
+			FixtureModifyProductVariables(func(variables FixtureProductVariables) {
+				variables.PolicyViolationsBaseline = stringPtr("baseline.json")
+			}),
+		).RunTest(t)
+	})
+
+	t.Run("matches visibility violations by dependency", func(t *testing.T) {
+		// The visibility of the dependency was edited since the baseline was written, but it is
+		// still not visible to the module.
+		GroupFixturePreparers(
+			prepareForPolicyViolationsTest,
+			FixtureOverrideTextFile("Android.bp", `
+				cc_library {
+					name: "libprivate",
+					visibility: ["//other"],
+				}
+			`),
+			FixtureAddTextFile("baseline.json", `[
+				{
+					"kind": "neverallow",
+					"rule_id": "neverallow dir:vendor/* include_dirs.is-set",
+					"module": "libvendor",
+					"package": "vendor",
+					"property": "include_dirs"
+				},
+				{
+					"kind": "visibility",
+					"rule": "[//visibility:private]",
+					"rule_id": "//:libprivate",
+					"module": "libvendor",
+					"package": "vendor",
+					"property": "visibility",
+					"dependency": "//:libprivate"
+				}
+			]`),
+			FixtureModifyProductVariables(func(variables FixtureProductVariables) {
+				variables.PolicyViolationsBaseline = stringPtr("baseline.json")
+			}),
+		).RunTest(t)
+	})
+
+	t.Run("invalid baseline", func(t *testing.T) {
+		GroupFixturePreparers(
+			prepareForPolicyViolationsTest,
+			FixtureAddTextFile("baseline.json", "{"),
+			FixtureModifyProductVariables(func(variables FixtureProductVariables) {
+				variables.PolicyViolationsBaseline = stringPtr("baseline.json")
+			}),
+		).
+			ExtendWithErrorHandler(FixtureExpectsAtLeastOneErrorMatchingPattern(
+				`failed to parse policy violations baseline "baseline.json"`)).
+			RunTest(t)
+	})
+}
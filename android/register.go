@@ -201,7 +201,7 @@ func (ctx *Context) Register() {
 		}
 	}
 
-	mutators := collateGloballyRegisteredMutators()
+	mutators := collateGloballyRegisteredMutators(ctx.config.collectModuleGraph)
 	mutators.registerAll(ctx)
 
 	singletons := collateGloballyRegisteredSingletons()
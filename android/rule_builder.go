@@ -54,6 +54,7 @@ type RuleBuilder struct {
 	outDir           WritablePath
 	sboxTools        bool
 	sboxInputs       bool
+	sboxHermetic     bool
 	sboxManifestPath WritablePath
 	missingDeps      []string
 }
@@ -190,6 +191,45 @@ func (r *RuleBuilder) SandboxInputs() *RuleBuilder {
 	return r
 }
 
+// Hermetic runs the commands of the rule in a mount and user namespace in which only the sandbox
+// directory, the directories of the inputs and tools of the rule and the host system directories
+// are visible.  The commands are traced, and any file they access or look for in the source or
+// output directory that is not an input or tool is reported as a missing dependency, even if the
+// command succeeds.  Hermetic sandboxing is only supported when building on Linux.
+//
+// SOONG_SBOX_HERMETIC=true enables hermetic sandboxing for every RuleBuilder rule that uses Sbox().
+// It has no effect on rules that don't use sbox, which includes every rule written with
+// ctx.Build directly; those have to be converted to RuleBuilder with Sbox() to be checked.
+func (r *RuleBuilder) Hermetic() *RuleBuilder {
+	if !r.sbox {
+		panic("Hermetic() must be called after Sbox()")
+	}
+	if len(r.commands) > 0 {
+		panic("Hermetic() may not be called after Command()")
+	}
+	r.sboxHermetic = true
+	return r
+}
+
+// hermetic returns true if the sbox rule should be run in a hermetic sandbox.
+func (r *RuleBuilder) hermetic() bool {
+	if r.rbeParams != nil || BuildOs != Linux {
+		return false
+	}
+	return r.sboxHermetic || r.ctx.Config().SboxHermetic()
+}
+
+// actionCache returns true if the outputs of the sbox rule should be stored in the local action
+// cache.  Only rules that sandbox their inputs or run hermetically are cached, as the commands of
+// other rules can read files that are not hashed into the cache key.  Rules that run remotely are
+// cached by the remote execution service instead.
+func (r *RuleBuilder) actionCache() bool {
+	if r.rbeParams != nil || r.ctx.Config().SboxCacheDir() == "" {
+		return false
+	}
+	return r.sboxInputs || r.hermetic()
+}
+
 // Install associates an output of the rule with an install location, which can be retrieved later using
 // RuleBuilder.Installs.
 func (r *RuleBuilder) Install(from Path, to string) {
@@ -536,6 +576,18 @@ func (r *RuleBuilder) Build(name string, desc string) {
 			manifest.OutputDepfile = proto.String(depFile.String())
 		}
 
+		if m, ok := r.ctx.(interface {
+			ModuleName() string
+			ModuleDir() string
+		}); ok {
+			dir := m.ModuleDir()
+			if dir == "." {
+				// Modules in the root directory have labels like //:foo.
+				dir = ""
+			}
+			manifest.Module = proto.String("//" + dir + ":" + m.ModuleName())
+		}
+
 		// If sandboxing tools is enabled, add copy rules to the manifest to copy each tool
 		// into the sbox directory.
 		if r.sboxTools {
@@ -589,6 +641,25 @@ func (r *RuleBuilder) Build(name string, desc string) {
 			command.Chdir = proto.Bool(true)
 		}
 
+		// If running hermetically or using the action cache, list every file the commands may
+		// read so that sbox can make them visible in the sandbox and hash them into the cache key.
+		hermetic := r.hermetic()
+		actionCache := r.actionCache()
+		if hermetic || actionCache {
+			var commandInputs Paths
+			commandInputs = append(commandInputs, inputs...)
+			commandInputs = append(commandInputs, tools...)
+			commandInputs = append(commandInputs, r.OrderOnlys()...)
+			for _, rspFile := range rspFiles {
+				commandInputs = append(commandInputs, rspFile.file)
+				commandInputs = append(commandInputs, rspFile.paths...)
+			}
+			if hermetic {
+				command.Hermetic = proto.Bool(true)
+			}
+			command.Inputs = FirstUniqueStrings(commandInputs.Strings())
+		}
+
 		// Add copy rules to the manifest to copy each output file from the sbox directory.
 		// to the output directory after running the commands.
 		sboxOutputs := make([]string, len(outputs))
@@ -636,6 +707,15 @@ func (r *RuleBuilder) Build(name string, desc string) {
 		sboxCmd.BuiltTool("sbox").
 			Flag("--sandbox-path").Text(shared.TempDirForOutDir(PathForOutput(r.ctx).String())).
 			Flag("--manifest").Input(r.sboxManifestPath)
+		if hermetic {
+			sboxCmd.Flag("--nsjail").PrebuiltBuildTool(r.ctx, "nsjail")
+		}
+		if actionCache {
+			sboxCmd.FlagWithArg("--cache-dir ", r.ctx.Config().SboxCacheDir())
+			if maxSize := r.ctx.Config().SboxCacheMaxSize(); maxSize != "" {
+				sboxCmd.FlagWithArg("--cache-max-size ", maxSize)
+			}
+		}
 
 		// Replace the command string, and add the sbox tool and manifest textproto to the
 		// dependencies of the final sbox rule.
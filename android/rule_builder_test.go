@@ -792,3 +792,86 @@ func TestRuleBuilderHashInputs(t *testing.T) {
 		})
 	}
 }
+
+func TestRuleBuilderHermetic(t *testing.T) {
+	if BuildOs != Linux {
+		t.Skip("hermetic sandboxing is only supported on Linux")
+	}
+
+	bp := `
+		rule_builder_test {
+			name: "foo_sbox",
+			srcs: ["in"],
+			sbox: true,
+		}
+	`
+
+	result := GroupFixturePreparers(
+		prepareForRuleBuilderTest,
+		FixtureWithRootAndroidBp(bp),
+		FixtureMergeEnv(map[string]string{
+			"SOONG_SBOX_HERMETIC": "true",
+		}),
+	).RunTest(t)
+
+	gen := result.ModuleForTests("foo_sbox", "")
+	manifest := RuleBuilderSboxProtoForTests(t, gen.Output("sbox.textproto"))
+	command := manifest.Commands[0]
+
+	AssertStringEquals(t, "module", "//:foo_sbox", manifest.GetModule())
+	AssertBoolEquals(t, "hermetic", true, command.GetHermetic())
+	AssertDeepEquals(t, "inputs",
+		[]string{"implicit", "in", "cp", "orderonly", "out/soong/.intermediates/foo_sbox/rsp",
+			"rsp_in", "out/soong/.intermediates/foo_sbox/rsp2", "rsp_in2"},
+		StringsRelativeToTop(result.Config, command.GetInputs()))
+
+	nsjail := "prebuilts/build-tools/" + result.Config.PrebuiltOS() + "/bin/nsjail"
+	rule := gen.Output("gen/foo_sbox")
+	AssertStringDoesContain(t, "command", StringRelativeToTop(result.Config, rule.RuleParams.Command),
+		"--nsjail "+nsjail)
+	AssertStringListContains(t, "command deps", rule.RuleParams.CommandDeps, nsjail)
+}
+
+func TestRuleBuilderActionCache(t *testing.T) {
+	bp := `
+		rule_builder_test {
+			name: "foo_sbox",
+			srcs: ["in"],
+			sbox: true,
+			sbox_inputs: true,
+		}
+
+		rule_builder_test {
+			name: "bar_sbox",
+			srcs: ["in"],
+			sbox: true,
+		}
+	`
+
+	result := GroupFixturePreparers(
+		prepareForRuleBuilderTest,
+		FixtureWithRootAndroidBp(bp),
+		FixtureMergeEnv(map[string]string{
+			"SOONG_SBOX_CACHE_DIR":      "/tmp/sbox_cache",
+			"SOONG_SBOX_CACHE_MAX_SIZE": "1000000",
+		}),
+	).RunTest(t)
+
+	gen := result.ModuleForTests("foo_sbox", "")
+	manifest := RuleBuilderSboxProtoForTests(t, gen.Output("sbox.textproto"))
+	command := manifest.Commands[0]
+
+	AssertBoolEquals(t, "hermetic", false, command.GetHermetic())
+	AssertStringListContains(t, "inputs", StringsRelativeToTop(result.Config, command.GetInputs()), "in")
+
+	rule := gen.Output("gen/foo_sbox")
+	AssertStringDoesContain(t, "command", rule.RuleParams.Command,
+		"--cache-dir /tmp/sbox_cache --cache-max-size 1000000")
+
+	// The commands of rules that don't sandbox their inputs can read files that are not in the
+	// cache key.
+	gen = result.ModuleForTests("bar_sbox", "")
+	manifest = RuleBuilderSboxProtoForTests(t, gen.Output("sbox.textproto"))
+	AssertIntEquals(t, "inputs", 0, len(manifest.Commands[0].GetInputs()))
+	AssertStringDoesNotContain(t, "command", gen.Output("gen/bar_sbox").RuleParams.Command, "--cache-dir")
+}
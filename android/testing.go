@@ -411,8 +411,8 @@ func (s *registrationSorter) populate() {
 		// Create an ordering from the globally registered pre-singletons.
 		s.preSingletonOrder = registeredComponentOrderFromExistingOrder("pre-singleton", preSingletons)
 
-		// Created an ordering from the globally registered mutators.
-		globallyRegisteredMutators := collateGloballyRegisteredMutators()
+		// Created an ordering from the globally registered mutators, including the optional ones.
+		globallyRegisteredMutators := collateGloballyRegisteredMutators(true)
 		s.mutatorOrder = registeredComponentOrderFromExistingOrder("mutator", globallyRegisteredMutators)
 
 		// Create an ordering from the globally registered singletons.
@@ -442,7 +442,8 @@ func (ctx *TestContext) Register() {
 	globalOrder.preSingletonOrder.enforceOrdering(ctx.preSingletons)
 	ctx.preSingletons.registerAll(ctx.Context)
 
-	mutators := collateRegisteredMutators(ctx.preArch, ctx.preDeps, ctx.postDeps, ctx.finalDeps)
+	mutators := collateRegisteredMutators(ctx.preArch, ctx.preDeps, ctx.postDeps, ctx.finalDeps,
+		ctx.config.collectModuleGraph)
 	// Ensure that the mutators used in the test are in the same order as they are used at runtime.
 	globalOrder.mutatorOrder.enforceOrdering(mutators)
 	mutators.registerAll(ctx.Context)
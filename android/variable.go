@@ -393,6 +393,15 @@ type productVariables struct {
 
 	SelinuxIgnoreNeverallows bool `json:",omitempty"`
 
+	NeverallowRuleFiles []string `json:",omitempty"`
+
+	ReportPolicyViolations   *bool   `json:",omitempty"`
+	PolicyViolationsBaseline *string `json:",omitempty"`
+
+	CheckUnusedLibs *bool `json:",omitempty"`
+
+	SplitDwarf *bool `json:",omitempty"`
+
 	SepolicySplit bool `json:",omitempty"`
 }
 
@@ -456,7 +456,19 @@ func visibilityRuleEnforcer(ctx TopDownMutatorContext) {
 
 		rule := effectiveVisibilityRules(ctx.Config(), depQualified)
 		if !rule.matches(qualified) {
-			ctx.ModuleErrorf("depends on %s which is not visible to this module\nYou may need to add %q to its visibility", depQualified, "//"+ctx.ModuleDir())
+			violation := PolicyViolation{
+				Kind:       "visibility",
+				Rule:       rule.String(),
+				RuleId:     depQualified.String(),
+				Module:     ctx.ModuleName(),
+				Package:    ctx.ModuleDir(),
+				Property:   "visibility",
+				Dependency: depQualified.String(),
+				Reason:     fmt.Sprintf("%s is not visible to this module", depQualified),
+			}
+			if handlePolicyViolation(ctx, violation) {
+				ctx.ModuleErrorf("depends on %s which is not visible to this module\nYou may need to add %q to its visibility", depQualified, "//"+ctx.ModuleDir())
+			}
 		}
 	})
 }
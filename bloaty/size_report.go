@@ -0,0 +1,182 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bloaty
+
+import (
+	"strconv"
+	"strings"
+
+	"android/soong/android"
+
+	"github.com/google/blueprint"
+)
+
+// Size reports break down the size of every installed native binary and library by section,
+// symbol and compile unit.  `m size-reports` merges the reports of all modules into
+// out/soong/size_reports.json.  When SIZE_REPORT_BASELINE points to the size_reports.json of an
+// earlier build, `m size-report-diff` compares the reports with it and fails if a module grew by
+// more than SIZE_REPORT_THRESHOLD_BYTES bytes and SIZE_REPORT_THRESHOLD_PERCENT percent.
+
+const sizeReportExt = ".size_report.json"
+const sizeReportsFilename = "size_reports.json"
+const sizeReportDiffFilename = "size_report_diff.txt"
+
+var (
+	sizeReportKey blueprint.ProviderKey
+
+	// bloatySizeReport measures the sections of a file, and the symbols and compile units its
+	// sizes are attributed to, using the unstripped file for the debug information.
+	bloatySizeReport = pctx.AndroidStaticRule("bloatySizeReport",
+		blueprint.RuleParams{
+			Command: "${bloaty} -n 0 -d sections --csv ${in} > ${out}.sections.csv && " +
+				"${bloaty} -n 50 -d symbols --csv --debug-file=${debugFile} ${in} > ${out}.symbols.csv && " +
+				"${bloaty} -n 50 -d compileunits --csv --debug-file=${debugFile} ${in} > ${out}.compileunits.csv && " +
+				"${bloatyReport} --module ${module} --variant ${variant} --file ${in} " +
+				"--sections ${out}.sections.csv --symbols ${out}.symbols.csv " +
+				"--compileunits ${out}.compileunits.csv -o ${out}",
+			CommandDeps: []string{"${bloaty}", "${bloatyReport}"},
+		}, "debugFile", "module", "variant")
+
+	// bloatySizeReportMerge combines the size reports of all modules.
+	bloatySizeReportMerge = pctx.AndroidStaticRule("bloatySizeReportMerge",
+		blueprint.RuleParams{
+			Command:        "${bloatyReport} --merge -l ${out}.lst -o ${out}",
+			CommandDeps:    []string{"${bloatyReport}"},
+			Rspfile:        "${out}.lst",
+			RspfileContent: "${in}",
+		})
+
+	// bloatySizeReportDiff compares the merged size report with a baseline.
+	bloatySizeReportDiff = pctx.AndroidStaticRule("bloatySizeReportDiff",
+		blueprint.RuleParams{
+			Command:     "${bloatyReport} --diff ${thresholdFlags} -o ${out} ${baseline} ${in}",
+			CommandDeps: []string{"${bloatyReport}"},
+		}, "baseline", "thresholdFlags")
+)
+
+func init() {
+	pctx.HostBinToolVariable("bloatyReport", "bloaty_report")
+	android.RegisterSingletonType("size_reports", sizeReportsSingleton)
+	sizeReportKey = blueprint.NewProvider(sizeReport{})
+}
+
+// sizeReport contains the path of the size report of a module.
+type sizeReport struct {
+	path android.Path
+}
+
+// ReportSizeForModule should be called by modules that install a native binary or library to
+// generate the size report of the installed file.  debugFile is the unstripped file, whose debug
+// information attributes the sizes to symbols and compile units, or nil if the file is not
+// stripped.  It must only be called once per module; it will panic otherwise.
+func ReportSizeForModule(ctx android.ModuleContext, file, debugFile android.Path) {
+	if debugFile == nil {
+		debugFile = file
+	}
+	report := android.PathForModuleOut(ctx, file.Base()+sizeReportExt)
+	ctx.Build(pctx, android.BuildParams{
+		Rule:        bloatySizeReport,
+		Description: "bloaty size report " + file.Base(),
+		Input:       file,
+		Implicit:    debugFile,
+		Output:      report,
+		Args: map[string]string{
+			"debugFile": debugFile.String(),
+			"module":    ctx.ModuleName(),
+			"variant":   ctx.ModuleSubDir(),
+		},
+	})
+	ctx.SetProvider(sizeReportKey, sizeReport{report})
+}
+
+type sizeReportsSingletonType struct {
+	reports android.Path
+	diff    android.Path
+}
+
+func sizeReportsSingleton() android.Singleton {
+	return &sizeReportsSingletonType{}
+}
+
+func (s *sizeReportsSingletonType) GenerateBuildActions(ctx android.SingletonContext) {
+	var reports android.Paths
+	ctx.VisitAllModules(func(m android.Module) {
+		if !ctx.ModuleHasProvider(m, sizeReportKey) {
+			return
+		}
+		reports = append(reports, ctx.ModuleProvider(m, sizeReportKey).(sizeReport).path)
+	})
+	if len(reports) == 0 {
+		return
+	}
+
+	s.reports = android.PathForOutput(ctx, sizeReportsFilename)
+	ctx.Build(pctx, android.BuildParams{
+		Rule:        bloatySizeReportMerge,
+		Description: "merge size reports",
+		Inputs:      android.SortedUniquePaths(reports),
+		Output:      s.reports,
+	})
+	ctx.Phony("size-reports", s.reports)
+
+	baselineEnv := ctx.Config().Getenv("SIZE_REPORT_BASELINE")
+	if baselineEnv == "" {
+		return
+	}
+	baseline := android.ExistentPathForSource(ctx, baselineEnv)
+	if !baseline.Valid() {
+		ctx.Errorf("SIZE_REPORT_BASELINE %q does not exist", baselineEnv)
+		return
+	}
+
+	var thresholdFlags []string
+	if bytes := ctx.Config().Getenv("SIZE_REPORT_THRESHOLD_BYTES"); bytes != "" {
+		if _, err := strconv.ParseUint(bytes, 10, 64); err != nil {
+			ctx.Errorf("SIZE_REPORT_THRESHOLD_BYTES must be a number of bytes, not %q", bytes)
+			return
+		}
+		thresholdFlags = append(thresholdFlags, "--threshold_bytes "+bytes)
+	}
+	if percent := ctx.Config().Getenv("SIZE_REPORT_THRESHOLD_PERCENT"); percent != "" {
+		if _, err := strconv.ParseFloat(percent, 64); err != nil {
+			ctx.Errorf("SIZE_REPORT_THRESHOLD_PERCENT must be a percentage, not %q", percent)
+			return
+		}
+		thresholdFlags = append(thresholdFlags, "--threshold_percent "+percent)
+	}
+
+	s.diff = android.PathForOutput(ctx, sizeReportDiffFilename)
+	ctx.Build(pctx, android.BuildParams{
+		Rule:        bloatySizeReportDiff,
+		Description: "size report diff",
+		Input:       s.reports,
+		Implicit:    baseline.Path(),
+		Output:      s.diff,
+		Args: map[string]string{
+			"baseline":       baseline.Path().String(),
+			"thresholdFlags": strings.Join(thresholdFlags, " "),
+		},
+	})
+	ctx.Phony("size-report-diff", s.diff)
+}
+
+func (s *sizeReportsSingletonType) MakeVars(ctx android.MakeVarsContext) {
+	if s.reports != nil {
+		ctx.DistForGoal("size-reports", s.reports)
+	}
+	if s.diff != nil {
+		ctx.DistForGoal("size-report-diff", s.diff)
+	}
+}
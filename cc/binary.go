@@ -94,6 +94,11 @@ type binaryDecorator struct {
 	// Location of the linked, unstripped binary
 	unstrippedOutputFile android.Path
 
+	// Location of the split DWARF package of the unstripped binary, and of its copy in the
+	// symbols directory for device modules
+	splitDwarfPackage        android.OptionalPath
+	symbolsSplitDwarfPackage android.OptionalPath
+
 	// Names of symlinks to be installed for use in LOCAL_MODULE_SYMLINKS
 	symlinks []string
 
@@ -370,6 +375,12 @@ func (binary *binaryDecorator) link(ctx ModuleContext,
 
 	binary.unstrippedOutputFile = outputFile
 
+	if flags.SplitDwarf {
+		dwpFile := android.PathForModuleOut(ctx, "unstripped", fileName+".dwp")
+		binary.stripper.PackSplitDwarf(ctx, outputFile, dwpFile)
+		binary.splitDwarfPackage = android.OptionalPathForPath(dwpFile)
+	}
+
 	if String(binary.Properties.Prefix_symbols) != "" {
 		afterPrefixSymbols := outputFile
 		outputFile = android.PathForModuleOut(ctx, "unprefixed", fileName)
@@ -425,6 +436,8 @@ func (binary *binaryDecorator) link(ctx ModuleContext,
 	}
 
 	linkerDeps = append(linkerDeps, objs.tidyFiles...)
+	linkerDeps = append(linkerDeps, objs.layeringCheckFiles...)
+	linkerDeps = append(linkerDeps, objs.staticAnalyzerFiles...)
 	linkerDeps = append(linkerDeps, flags.LdFlagsDeps...)
 
 	// Register link action.
@@ -504,6 +517,8 @@ func (binary *binaryDecorator) install(ctx ModuleContext, file android.Path) {
 		binary.baseInstaller.subDir = "bootstrap"
 	}
 	binary.baseInstaller.install(ctx, file)
+	binary.symbolsSplitDwarfPackage = InstallSplitDwarfPackage(ctx, binary.baseInstaller.installDir(ctx),
+		file.Base(), binary.splitDwarfPackage)
 
 	var preferredArchSymlinkPath android.OptionalPath
 	for _, symlink := range binary.symlinks {
@@ -49,6 +49,16 @@ var (
 		},
 		"ccCmd", "cFlags")
 
+	// Rule to precompile a C++ header with the flags of the module. Outputs a .d depfile.
+	ccPch = pctx.AndroidStaticRule("ccPch",
+		blueprint.RuleParams{
+			Depfile:     "${out}.d",
+			Deps:        blueprint.DepsGCC,
+			Command:     "$relPwd $ccCmd -x c++-header $cFlags -MD -MF ${out}.d -o $out $in",
+			CommandDeps: []string{"$ccCmd"},
+		},
+		"ccCmd", "cFlags")
+
 	// Rule to invoke gcc with given command and flags, but no dependencies.
 	ccNoDeps = pctx.AndroidStaticRule("ccNoDeps",
 		blueprint.RuleParams{
@@ -152,6 +162,14 @@ var (
 			CommandDeps: []string{"${config.MacStripPath}"},
 		})
 
+	// Rule to pack the split DWARF .dwo files referenced by a linked binary or shared library into a
+	// .dwp file.
+	dwp = pctx.AndroidStaticRule("dwp",
+		blueprint.RuleParams{
+			Command:     "${config.ClangBin}/llvm-dwp -e $in -o $out",
+			CommandDeps: []string{"${config.ClangBin}/llvm-dwp"},
+		})
+
 	// b/132822437: objcopy uses a file descriptor per .o file when called on .a files, which runs the system out of
 	// file descriptors on darwin.  Limit concurrent calls to 5 on darwin.
 	darwinStripPool = func() blueprint.Pool {
@@ -212,6 +230,21 @@ var (
 			Platform:    map[string]string{remoteexec.PoolKey: "${config.REClangTidyPool}"},
 		}, []string{"cFlags", "tidyFlags"}, []string{})
 
+	// Rule for invoking clang-tidy remotely with -export-fixes, which also downloads the file
+	// written by -export-fixes.  The local rule is the same as clangTidy.
+	_, clangTidyFixesRE = pctx.RemoteStaticRules("clangTidyFixes",
+		blueprint.RuleParams{
+			Command:     "rm -f $out && $reTemplate${config.ClangBin}/clang-tidy $tidyFlags $in -- $cFlags && touch $out",
+			CommandDeps: []string{"${config.ClangBin}/clang-tidy"},
+		},
+		&remoteexec.REParams{
+			Labels:       map[string]string{"type": "lint", "tool": "clang-tidy", "lang": "cpp"},
+			ExecStrategy: "${config.REClangTidyExecStrategy}",
+			Inputs:       []string{"$in"},
+			OutputFiles:  []string{"$in", "$tidyFixFile"},
+			Platform:     map[string]string{remoteexec.PoolKey: "${config.REClangTidyPool}"},
+		}, []string{"cFlags", "tidyFlags"}, []string{"tidyFixFile"})
+
 	_ = pctx.SourcePathVariable("yasmCmd", "prebuilts/misc/${config.HostPrebuiltTag}/yasm/yasm")
 
 	// Rule for invoking yasm to compile .asm assembly files.
@@ -366,24 +399,36 @@ type builderFlags struct {
 	localCppFlags        string
 	localLdFlags         string
 
-	libFlags      string // Flags to add to the linker directly after specifying libraries to link.
-	extraLibFlags string // Flags to add to the linker last.
-	tidyFlags     string // Flags that apply to clang-tidy
-	sAbiFlags     string // Flags that apply to header-abi-dumps
-	aidlFlags     string // Flags that apply to aidl source files
-	rsFlags       string // Flags that apply to renderscript source files
-	toolchain     config.Toolchain
+	libFlags             string // Flags to add to the linker directly after specifying libraries to link.
+	extraLibFlags        string // Flags to add to the linker last.
+	tidyFlags            string // Flags that apply to clang-tidy
+	layeringCheckFlags   string // Flags that apply to layering_check
+	staticAnalyzerFlags  string // Flags that apply to the clang static analyzer
+	staticAnalyzerOutput string // Format of the static analyzer reports
+	sAbiFlags            string // Flags that apply to header-abi-dumps
+	aidlFlags            string // Flags that apply to aidl source files
+	rsFlags              string // Flags that apply to renderscript source files
+	toolchain            config.Toolchain
 
 	// True if these extra features are enabled.
-	tidy         bool
-	gcovCoverage bool
-	sAbiDump     bool
-	emitXrefs    bool
+	tidy            bool
+	tidyExportFixes bool
+	layeringCheck   bool
+	staticAnalyzer  bool
+	gcovCoverage    bool
+	sAbiDump        bool
+	emitXrefs       bool
+	splitDwarf      bool
 
 	assemblerWithCpp bool // True if .s files should be processed with the c preprocessor.
 
 	systemIncludeFlags string
 
+	tidyBaseline android.Path // Baseline of the clang-tidy warnings, or nil.
+
+	pchHeader android.Path         // Header that pch is compiled from.
+	pch       android.WritablePath // Precompiled header included in C++ sources, or nil.
+
 	// True if static libraries should be grouped (using `-Wl,--start-group` and `-Wl,--end-group`).
 	groupStaticLibs bool
 
@@ -409,43 +454,92 @@ type StripFlags struct {
 
 // Objects is a collection of file paths corresponding to outputs for C++ related build statements.
 type Objects struct {
-	objFiles      android.Paths
-	tidyFiles     android.Paths
-	coverageFiles android.Paths
-	sAbiDumpFiles android.Paths
-	kytheFiles    android.Paths
+	objFiles            android.Paths
+	tidyFiles           android.Paths
+	layeringCheckFiles  android.Paths
+	staticAnalyzerFiles android.Paths
+	coverageFiles       android.Paths
+	sAbiDumpFiles       android.Paths
+	kytheFiles          android.Paths
+	tidyFixFiles        android.Paths
+	tidyBaselineFiles   android.Paths
 }
 
 func (a Objects) Copy() Objects {
 	return Objects{
-		objFiles:      append(android.Paths{}, a.objFiles...),
-		tidyFiles:     append(android.Paths{}, a.tidyFiles...),
-		coverageFiles: append(android.Paths{}, a.coverageFiles...),
-		sAbiDumpFiles: append(android.Paths{}, a.sAbiDumpFiles...),
-		kytheFiles:    append(android.Paths{}, a.kytheFiles...),
+		objFiles:            append(android.Paths{}, a.objFiles...),
+		tidyFiles:           append(android.Paths{}, a.tidyFiles...),
+		layeringCheckFiles:  append(android.Paths{}, a.layeringCheckFiles...),
+		staticAnalyzerFiles: append(android.Paths{}, a.staticAnalyzerFiles...),
+		coverageFiles:       append(android.Paths{}, a.coverageFiles...),
+		sAbiDumpFiles:       append(android.Paths{}, a.sAbiDumpFiles...),
+		kytheFiles:          append(android.Paths{}, a.kytheFiles...),
+		tidyFixFiles:        append(android.Paths{}, a.tidyFixFiles...),
+		tidyBaselineFiles:   append(android.Paths{}, a.tidyBaselineFiles...),
 	}
 }
 
 func (a Objects) Append(b Objects) Objects {
 	return Objects{
-		objFiles:      append(a.objFiles, b.objFiles...),
-		tidyFiles:     append(a.tidyFiles, b.tidyFiles...),
-		coverageFiles: append(a.coverageFiles, b.coverageFiles...),
-		sAbiDumpFiles: append(a.sAbiDumpFiles, b.sAbiDumpFiles...),
-		kytheFiles:    append(a.kytheFiles, b.kytheFiles...),
+		objFiles:            append(a.objFiles, b.objFiles...),
+		tidyFiles:           append(a.tidyFiles, b.tidyFiles...),
+		layeringCheckFiles:  append(a.layeringCheckFiles, b.layeringCheckFiles...),
+		staticAnalyzerFiles: append(a.staticAnalyzerFiles, b.staticAnalyzerFiles...),
+		coverageFiles:       append(a.coverageFiles, b.coverageFiles...),
+		sAbiDumpFiles:       append(a.sAbiDumpFiles, b.sAbiDumpFiles...),
+		kytheFiles:          append(a.kytheFiles, b.kytheFiles...),
+		tidyFixFiles:        append(a.tidyFixFiles, b.tidyFixFiles...),
+		tidyBaselineFiles:   append(a.tidyBaselineFiles, b.tidyBaselineFiles...),
 	}
 }
 
+// cppflags returns the fully expanded flags for C++ compiles.
+func (flags builderFlags) cppflags() string {
+	return flags.globalCommonFlags + " " +
+		flags.globalCFlags + " " +
+		flags.globalCppFlags + " " +
+		flags.localCommonFlags + " " +
+		flags.localCFlags + " " +
+		flags.localCppFlags + " " +
+		flags.systemIncludeFlags + " " +
+		"${config.NoOverrideClangGlobalCflags}"
+}
+
+// Generate a rule for precompiling a C++ header with the flags used for the C++ sources of the
+// module. The depfile of the rule lists the headers it includes, so any change to them rebuilds the
+// precompiled header, and the objects that depend on it.
+func transformHeaderToPch(ctx android.ModuleContext, flags builderFlags, pathDeps android.Paths,
+	cFlagsDeps android.Paths) {
+
+	ctx.Build(pctx, android.BuildParams{
+		Rule:        ccPch,
+		Description: "clang++ pch " + flags.pchHeader.Rel(),
+		Output:      flags.pch,
+		Input:       flags.pchHeader,
+		Implicits:   cFlagsDeps,
+		OrderOnly:   pathDeps,
+		Args: map[string]string{
+			"cFlags": flags.cppflags(),
+			"ccCmd":  "${config.ClangBin}/clang++",
+		},
+	})
+}
+
 // Generate rules for compiling multiple .c, .cpp, or .S files to individual .o files
 func transformSourceToObj(ctx android.ModuleContext, subdir string, srcFiles android.Paths,
 	flags builderFlags, pathDeps android.Paths, cFlagsDeps android.Paths) Objects {
 
-	// Source files are one-to-one with tidy, coverage, or kythe files, if enabled.
+	// Source files are one-to-one with tidy, layering check, coverage, or kythe files, if enabled.
 	objFiles := make(android.Paths, len(srcFiles))
-	var tidyFiles android.Paths
+	var tidyFiles, tidyFixFiles android.Paths
 	if flags.tidy {
 		tidyFiles = make(android.Paths, 0, len(srcFiles))
 	}
+	var layeringCheckFiles android.Paths
+	if flags.layeringCheck {
+		layeringCheckFiles = make(android.Paths, 0, len(srcFiles))
+	}
+	var staticAnalyzerSrcs []staticAnalyzerSource
 	var coverageFiles android.Paths
 	if flags.gcovCoverage {
 		coverageFiles = make(android.Paths, 0, len(srcFiles))
@@ -481,13 +575,7 @@ func transformSourceToObj(ctx android.ModuleContext, subdir string, srcFiles and
 		flags.localToolingCppFlags + " " +
 		flags.systemIncludeFlags
 
-	cppflags := flags.globalCommonFlags + " " +
-		flags.globalCFlags + " " +
-		flags.globalCppFlags + " " +
-		flags.localCommonFlags + " " +
-		flags.localCFlags + " " +
-		flags.localCppFlags + " " +
-		flags.systemIncludeFlags
+	cppflags := flags.cppflags()
 
 	asflags := flags.globalCommonFlags + " " +
 		flags.globalAsFlags + " " +
@@ -502,7 +590,6 @@ func transformSourceToObj(ctx android.ModuleContext, subdir string, srcFiles and
 
 	cflags += " ${config.NoOverrideClangGlobalCflags}"
 	toolingCflags += " ${config.NoOverrideClangGlobalCflags}"
-	cppflags += " ${config.NoOverrideClangGlobalCflags}"
 	toolingCppflags += " ${config.NoOverrideClangGlobalCflags}"
 
 	for i, srcFile := range srcFiles {
@@ -546,10 +633,13 @@ func transformSourceToObj(ctx android.ModuleContext, subdir string, srcFiles and
 
 		var moduleFlags string
 		var moduleToolingFlags string
+		implicits := cFlagsDeps
 
 		var ccCmd string
 		tidy := flags.tidy
+		staticAnalyzer := flags.staticAnalyzer
 		coverage := flags.gcovCoverage
+		splitDwarf := flags.splitDwarf
 		dump := flags.sAbiDump
 		rule := cc
 		emitXref := flags.emitXrefs
@@ -564,7 +654,9 @@ func transformSourceToObj(ctx android.ModuleContext, subdir string, srcFiles and
 			ccCmd = "clang"
 			moduleFlags = asflags
 			tidy = false
+			staticAnalyzer = false
 			coverage = false
+			splitDwarf = false
 			dump = false
 			emitXref = false
 		case ".c":
@@ -575,6 +667,13 @@ func transformSourceToObj(ctx android.ModuleContext, subdir string, srcFiles and
 			ccCmd = "clang++"
 			moduleFlags = cppflags
 			moduleToolingFlags = toolingCppflags
+			// The precompiled header is C++, so it can't be used by Objective-C++ sources. Tools
+			// that don't read it include the header instead.
+			if flags.pch != nil && srcFile.Ext() != ".mm" {
+				moduleFlags += " -include-pch " + flags.pch.String()
+				moduleToolingFlags += " -include " + flags.pchHeader.String()
+				implicits = append(android.Paths{flags.pch}, cFlagsDeps...)
+			}
 		case ".h", ".hpp":
 			ctx.PropertyErrorf("srcs", "Header file %s is not supported, instead use export_include_dirs or local_include_dirs.", srcFile)
 			continue
@@ -593,6 +692,16 @@ func transformSourceToObj(ctx android.ModuleContext, subdir string, srcFiles and
 			implicitOutputs = append(implicitOutputs, gcnoFile)
 			coverageFiles = append(coverageFiles, gcnoFile)
 		}
+		if splitDwarf {
+			implicitOutputs = append(implicitOutputs, objFile.ReplaceExtension(ctx, "dwo"))
+		}
+		// The layering check reads the depfile of the object, which is kept by ninja after it is
+		// read.
+		var depFile android.WritablePath
+		if flags.layeringCheck && rule == cc {
+			depFile = objFile.ReplaceExtension(ctx, "o.d")
+			implicitOutputs = append(implicitOutputs, depFile)
+		}
 
 		ctx.Build(pctx, android.BuildParams{
 			Rule:            rule,
@@ -600,7 +709,7 @@ func transformSourceToObj(ctx android.ModuleContext, subdir string, srcFiles and
 			Output:          objFile,
 			ImplicitOutputs: implicitOutputs,
 			Input:           srcFile,
-			Implicits:       cFlagsDeps,
+			Implicits:       implicits,
 			OrderOnly:       pathDeps,
 			Args: map[string]string{
 				"cFlags": moduleFlags,
@@ -616,7 +725,7 @@ func transformSourceToObj(ctx android.ModuleContext, subdir string, srcFiles and
 				Description: "Xref C++ extractor " + srcFile.Rel(),
 				Output:      kytheFile,
 				Input:       srcFile,
-				Implicits:   cFlagsDeps,
+				Implicits:   implicits,
 				OrderOnly:   pathDeps,
 				Args: map[string]string{
 					"cFlags": moduleFlags,
@@ -629,24 +738,69 @@ func transformSourceToObj(ctx android.ModuleContext, subdir string, srcFiles and
 			tidyFile := android.ObjPathWithExt(ctx, subdir, srcFile, "tidy")
 			tidyFiles = append(tidyFiles, tidyFile)
 
+			tidyFlags := flags.tidyFlags
+			var tidyFixFile android.WritablePath
+			if flags.tidyExportFixes {
+				tidyFixFile = android.ObjPathWithExt(ctx, subdir, srcFile, "tidy.yaml")
+				tidyFixFiles = append(tidyFixFiles, tidyFixFile)
+				tidyFlags += " -export-fixes=" + tidyFixFile.String()
+			}
+
 			rule := clangTidy
-			if ctx.Config().UseRBE() && ctx.Config().IsEnvTrue("RBE_CLANG_TIDY") {
+			args := map[string]string{
+				"cFlags":    moduleToolingFlags,
+				"tidyFlags": tidyFlags,
+			}
+			if flags.tidyBaseline != nil {
+				rule = clangTidyLog
+			} else if ctx.Config().UseRBE() && ctx.Config().IsEnvTrue("RBE_CLANG_TIDY") {
 				rule = clangTidyRE
+				if tidyFixFile != nil {
+					// The fixes are written remotely, download them with the stamp file.
+					rule = clangTidyFixesRE
+					args["tidyFixFile"] = tidyFixFile.String()
+				}
 			}
 
 			ctx.Build(pctx, android.BuildParams{
-				Rule:        rule,
-				Description: "clang-tidy " + srcFile.Rel(),
-				Output:      tidyFile,
-				Input:       srcFile,
+				Rule:           rule,
+				Description:    "clang-tidy " + srcFile.Rel(),
+				Output:         tidyFile,
+				ImplicitOutput: tidyFixFile,
+				Input:          srcFile,
 				// We must depend on objFile, since clang-tidy doesn't
 				// support exporting dependencies.
 				Implicit:  objFile,
 				Implicits: cFlagsDeps,
 				OrderOnly: pathDeps,
+				Args:      args,
+			})
+		}
+
+		if staticAnalyzer {
+			staticAnalyzerSrcs = append(staticAnalyzerSrcs, staticAnalyzerSource{
+				src:    srcFile,
+				obj:    objFile,
+				ccCmd:  ccCmd,
+				cFlags: moduleToolingFlags,
+			})
+		}
+
+		// The layering check reads the depfile of the object, so it only applies to sources that
+		// are compiled with a rule that writes one.
+		if flags.layeringCheck && rule == cc {
+			layeringCheckFile := android.ObjPathWithExt(ctx, subdir, srcFile, "layering")
+			layeringCheckFiles = append(layeringCheckFiles, layeringCheckFile)
+
+			ctx.Build(pctx, android.BuildParams{
+				Rule:        layeringCheck,
+				Description: "layering check " + srcFile.Rel(),
+				Output:      layeringCheckFile,
+				Input:       objFile,
+				Implicits:   android.Paths{depFile, layeringCheckExportsPath(ctx)},
 				Args: map[string]string{
-					"cFlags":    moduleToolingFlags,
-					"tidyFlags": flags.tidyFlags,
+					"exports":            layeringCheckExportsPath(ctx).String(),
+					"layeringCheckFlags": flags.layeringCheckFlags,
 				},
 			})
 		}
@@ -676,12 +830,37 @@ func transformSourceToObj(ctx android.ModuleContext, subdir string, srcFiles and
 
 	}
 
+	// The warnings of all the sources are compared with the baseline at once, so that a warning
+	// in a header that is included by several sources is only reported once.
+	var tidyBaselineFiles android.Paths
+	if flags.tidyBaseline != nil && len(tidyFiles) > 0 {
+		stamp, newBaseline := transformTidyLogsToBaselineCheck(ctx, subdir, tidyFiles, flags)
+		if stamp != nil {
+			tidyFiles = append(tidyFiles, stamp)
+		}
+		if newBaseline != nil {
+			tidyBaselineFiles = append(tidyBaselineFiles, newBaseline)
+		}
+	}
+
+	// The analysis of each source reads the ASTs of all the sources, so its rules can only be
+	// generated once all the sources are known.
+	var staticAnalyzerFiles android.Paths
+	if len(staticAnalyzerSrcs) > 0 {
+		staticAnalyzerFiles = transformSourcesToStaticAnalyzerReports(ctx, subdir, staticAnalyzerSrcs,
+			flags, pathDeps, cFlagsDeps)
+	}
+
 	return Objects{
-		objFiles:      objFiles,
-		tidyFiles:     tidyFiles,
-		coverageFiles: coverageFiles,
-		sAbiDumpFiles: sAbiDumpFiles,
-		kytheFiles:    kytheFiles,
+		objFiles:            objFiles,
+		tidyFiles:           tidyFiles,
+		layeringCheckFiles:  layeringCheckFiles,
+		staticAnalyzerFiles: staticAnalyzerFiles,
+		coverageFiles:       coverageFiles,
+		sAbiDumpFiles:       sAbiDumpFiles,
+		kytheFiles:          kytheFiles,
+		tidyFixFiles:        tidyFixFiles,
+		tidyBaselineFiles:   tidyBaselineFiles,
 	}
 }
 
@@ -1022,6 +1201,19 @@ func transformStrip(ctx android.ModuleContext, inputFile android.Path,
 	})
 }
 
+// Registers a build statement to pack the .dwo files referenced by the skeleton compile units of a
+// binary or shared library into a .dwp file.
+func transformBinaryToDwp(ctx android.ModuleContext, inputFile android.Path,
+	outputFile android.WritablePath) {
+
+	ctx.Build(pctx, android.BuildParams{
+		Rule:        dwp,
+		Description: "llvm-dwp " + outputFile.Base(),
+		Output:      outputFile,
+		Input:       inputFile,
+	})
+}
+
 // Registers build statement to invoke `strip` on darwin architecture.
 func transformDarwinStrip(ctx android.ModuleContext, inputFile android.Path,
 	outputFile android.WritablePath) {
@@ -28,6 +28,7 @@ import (
 	"github.com/google/blueprint/proptools"
 
 	"android/soong/android"
+	"android/soong/bloaty"
 	"android/soong/cc/config"
 	"android/soong/genrule"
 )
@@ -73,6 +74,9 @@ func RegisterCCBuildComponents(ctx android.RegistrationContext) {
 		ctx.TopDown("tsan_deps", sanitizerDepsMutator(tsan))
 		ctx.BottomUp("tsan", sanitizerMutator(tsan)).Parallel()
 
+		ctx.TopDown("msan_deps", sanitizerDepsMutator(msan))
+		ctx.BottomUp("msan", sanitizerMutator(msan)).Parallel()
+
 		ctx.TopDown("sanitize_runtime_deps", sanitizerRuntimeDepsMutator).Parallel()
 		ctx.BottomUp("sanitize_runtime", sanitizerRuntimeMutator).Parallel()
 
@@ -91,6 +95,9 @@ func RegisterCCBuildComponents(ctx android.RegistrationContext) {
 	})
 
 	ctx.RegisterSingletonType("kythe_extract_all", kytheExtractAllFactory)
+	ctx.RegisterSingletonType("static_analyzer", staticAnalyzerSingleton)
+	ctx.RegisterSingletonType("tidy", tidySingleton)
+	ctx.RegisterSingletonType("unused_libs", unusedLibsSingleton)
 }
 
 // Deps is a struct containing module names of dependencies, separated by the kind of dependency.
@@ -209,31 +216,43 @@ type Flags struct {
 	// Global flags (which build system or toolchain is responsible for).
 	Global LocalOrGlobalFlags
 
-	aidlFlags     []string // Flags that apply to aidl source files
-	rsFlags       []string // Flags that apply to renderscript source files
-	libFlags      []string // Flags to add libraries early to the link order
-	extraLibFlags []string // Flags to add libraries late in the link order after LdFlags
-	TidyFlags     []string // Flags that apply to clang-tidy
-	SAbiFlags     []string // Flags that apply to header-abi-dumper
+	aidlFlags            []string // Flags that apply to aidl source files
+	rsFlags              []string // Flags that apply to renderscript source files
+	libFlags             []string // Flags to add libraries early to the link order
+	extraLibFlags        []string // Flags to add libraries late in the link order after LdFlags
+	TidyFlags            []string // Flags that apply to clang-tidy
+	LayeringCheckFlags   []string // Flags that apply to layering_check
+	StaticAnalyzerFlags  []string // Flags that apply to the clang static analyzer
+	StaticAnalyzerOutput string   // Format of the static analyzer reports, "sarif" or "plist"
+	SAbiFlags            []string // Flags that apply to header-abi-dumper
 
 	// Global include flags that apply to C, C++, and assembly source files
 	// These must be after any module include flags, which will be in CommonFlags.
 	SystemIncludeFlags []string
 
-	Toolchain    config.Toolchain
-	Tidy         bool // True if clang-tidy is enabled.
-	GcovCoverage bool // True if coverage files should be generated.
-	SAbiDump     bool // True if header abi dumps should be generated.
-	EmitXrefs    bool // If true, generate Ninja rules to generate emitXrefs input files for Kythe
+	Toolchain       config.Toolchain
+	Tidy            bool // True if clang-tidy is enabled.
+	TidyExportFixes bool // True if the fixes suggested by clang-tidy should be exported.
+	LayeringCheck   bool // True if the layering check is enabled.
+	StaticAnalyzer  bool // True if the clang static analyzer is enabled.
+	GcovCoverage    bool // True if coverage files should be generated.
+	SAbiDump        bool // True if header abi dumps should be generated.
+	EmitXrefs       bool // If true, generate Ninja rules to generate emitXrefs input files for Kythe
+	SplitDwarf      bool // True if debug info should be split into .dwo files and packed into a .dwp file.
 
 	// The instruction set required for clang ("arm" or "thumb").
 	RequiredInstructionSet string
 	// The target-device system path to the dynamic linker.
 	DynamicLinker string
 
+	TidyBaseline android.Path // Baseline of the clang-tidy warnings, or nil
+
 	CFlagsDeps  android.Paths // Files depended on by compiler flags
 	LdFlagsDeps android.Paths // Files depended on by linker flags
 
+	PchHeader android.Path         // Header to precompile, or nil if precompiled headers are not used
+	Pch       android.WritablePath // Precompiled header to include in every C++ source
+
 	// True if .s files should be processed with the c preprocessor.
 	AssemblerWithCpp bool
 	// True if static libraries should be grouped (using `-Wl,--start-group` and `-Wl,--end-group`).
@@ -564,6 +583,7 @@ type linker interface {
 // specifiedDeps is a tuple struct representing dependencies of a linked binary owned by the linker.
 type specifiedDeps struct {
 	sharedLibs []string
+	staticLibs []string
 	// Note nil and [] are semantically distinct. [] prevents linking against the defaults (usually
 	// libc, libm, etc.)
 	systemSharedLibs []string
@@ -820,6 +840,15 @@ type Module struct {
 	makeLinkType string
 	// Kythe (source file indexer) paths for this compilation module
 	kytheFiles android.Paths
+	// Clang static analyzer reports for this compilation module
+	staticAnalyzerFiles android.Paths
+	// Fixes exported by clang-tidy, and new clang-tidy baselines, for this compilation module
+	tidyFixFiles      android.Paths
+	tidyBaselineFiles android.Paths
+
+	// Report of the shared_libs and static_libs that the linked output doesn't use, only set when
+	// CheckUnusedLibs is enabled
+	unusedLibsReport android.OptionalPath
 
 	// For apex variants, this is set as apex.min_sdk_version
 	apexSdkVersion android.ApiLevel
@@ -1526,6 +1555,9 @@ func newModule(hod android.HostOrDeviceSupported, multilib android.Multilib) *Mo
 	module := newBaseModule(hod, multilib)
 	module.features = []feature{
 		&tidyFeature{},
+		&layeringCheckFeature{},
+		&staticAnalyzerFeature{},
+		&splitDwarfFeature{},
 	}
 	module.stl = &stl{}
 	module.sanitize = &sanitize{}
@@ -1764,6 +1796,9 @@ func (c *Module) GenerateAndroidBuildActions(actx android.ModuleContext) {
 			return
 		}
 		c.kytheFiles = objs.kytheFiles
+		c.staticAnalyzerFiles = objs.staticAnalyzerFiles
+		c.tidyFixFiles = objs.tidyFixFiles
+		c.tidyBaselineFiles = objs.tidyBaselineFiles
 	}
 
 	if c.linker != nil {
@@ -1773,6 +1808,10 @@ func (c *Module) GenerateAndroidBuildActions(actx android.ModuleContext) {
 		}
 		c.outputFile = android.OptionalPathForPath(outputFile)
 
+		if ctx.Config().CheckUnusedLibs() {
+			c.unusedLibsReport = checkUnusedLibs(ctx, c)
+		}
+
 		// If a lib is directly included in any of the APEXes or is not available to the
 		// platform (which is often the case when the stub is provided as a prebuilt),
 		// unhide the stubs variant having the latest version gets visible to make. In
@@ -1815,6 +1854,10 @@ func (c *Module) GenerateAndroidBuildActions(actx android.ModuleContext) {
 			return
 		}
 	}
+
+	if (c.Binary() || c.Shared()) && installable(c, apexInfo) {
+		bloaty.ReportSizeForModule(ctx, c.outputFile.Path(), c.UnstrippedOutputFile())
+	}
 }
 
 func (c *Module) toolchain(ctx android.BaseModuleContext) config.Toolchain {
@@ -3347,6 +3390,9 @@ func DefaultsFactory(props ...interface{}) android.Module {
 		&StripProperties{},
 		&InstallerProperties{},
 		&TidyProperties{},
+		&LayeringCheckProperties{},
+		&StaticAnalyzerProperties{},
+		&SplitDwarfProperties{},
 		&CoverageProperties{},
 		&SAbiProperties{},
 		&VndkProperties{},
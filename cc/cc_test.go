@@ -4291,3 +4291,30 @@ func TestIncludeDirsExporting(t *testing.T) {
 		)
 	})
 }
+
+func TestSizeReports(t *testing.T) {
+	ctx := testCc(t, `
+		cc_library {
+			name: "libfoo",
+			srcs: ["foo.c"],
+		}
+
+		cc_binary {
+			name: "foo",
+			srcs: ["foo.c"],
+		}
+	`)
+
+	const libDir = "out/soong/.intermediates/libfoo/android_arm64_armv8-a_shared/"
+	report := ctx.ModuleForTests("libfoo", "android_arm64_armv8-a_shared").Output("libfoo.so.size_report.json")
+	android.AssertPathRelativeToTopEquals(t, "size report input", libDir+"libfoo.so", report.Input)
+	android.AssertStringPathRelativeToTopEquals(t, "size report debug file", ctx.Config(),
+		libDir+"unstripped/libfoo.so", report.Args["debugFile"])
+
+	ctx.ModuleForTests("foo", "android_arm64_armv8-a").Output("foo.size_report.json")
+
+	// Static libraries are not installed.
+	if params := ctx.ModuleForTests("libfoo", "android_arm64_armv8-a_static").MaybeOutput("libfoo.a.size_report.json"); params.Rule != nil {
+		t.Errorf("unexpected size report for the static library")
+	}
+}
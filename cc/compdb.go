@@ -15,21 +15,47 @@
 package cc
 
 import (
+	"bytes"
 	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
 	"log"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"unicode"
+
+	"github.com/google/blueprint/pathtools"
 
 	"android/soong/android"
+	"android/soong/makedeps"
 )
 
-// This singleton generates a compile_commands.json file. It does so for each
+// This singleton generates compile_commands.json files. It does so for each
 // blueprint Android.bp resulting in a cc.Module when either make, mm, mma, mmm
-// or mmma is called. It will only create a single compile_commands.json file
-// at ${OUT_DIR}/soong/development/ide/compdb/compile_commands.json. It will also symlink it
-// to ${SOONG_LINK_COMPDB_TO} if set. In general this should be created by running
-// make SOONG_GEN_COMPDB=1 nothing to get all targets.
+// or mmma is called. It creates ${OUT_DIR}/soong/development/ide/compdb/compile_commands.json
+// with one entry per source file, and a compile_commands.json for every variant in
+// ${OUT_DIR}/soong/development/ide/compdb/<os>_<arch>[_<image>]/ with the flags used for that
+// variant, e.g. linux_glibc_x86_64 for the host or android_arm64_vendor for the vendor image.
+// It will also symlink the first one to ${SOONG_LINK_COMPDB_TO} if set. In general this should
+// be created by running make SOONG_GEN_COMPDB=1 nothing to get all targets.
+//
+// Instead of 1, SOONG_GEN_COMPDB can be set to a comma or space separated list of filters of the
+// form <kind>:<glob>, where kind is one of module, path, image or arch, to only include the
+// module variants that match, e.g. SOONG_GEN_COMPDB="path:frameworks/native arch:arm64". A path
+// filter also matches the directories below it. A module variant is included if it matches one of
+// the filters of every kind that is given.
+//
+// Headers get an entry with the flags of a source that includes them, taken from the depfile of
+// the source written by the previous build. soong_build reads the depfiles directly and does not
+// depend on them, as they are deleted and rewritten by every compile, so the header entries are
+// only as up to date as the depfiles were the last time soong_build ran. Sources that have not
+// been compiled yet have no header entries, and a warning with their number is printed; build the
+// modules and regenerate the database, e.g. with make SOONG_GEN_COMPDB=1 nothing after touching an
+// Android.bp file, to add them. The files are only rewritten when their contents change, so
+// regenerating them after a small change is cheap.
 
 func init() {
 	android.RegisterSingletonType("compdb_generator", compDBGeneratorSingleton)
@@ -59,41 +85,197 @@ type compDbEntry struct {
 	Output    string   `json:"output,omitempty"`
 }
 
+// compdbFilters selects the module variants that are written to the compdb. Each list holds
+// globs, and an empty list matches everything.
+type compdbFilters struct {
+	modules []string
+	paths   []string
+	images  []string
+	arches  []string
+}
+
+// parseCompdbFilters parses the value of SOONG_GEN_COMPDB, which is either a true value to include
+// all module variants or a list of <kind>:<glob> filters.
+func parseCompdbFilters(value string) (*compdbFilters, error) {
+	filters := &compdbFilters{}
+	switch value {
+	case "1", "y", "yes", "on", "true":
+		return filters, nil
+	}
+
+	isSeparator := func(r rune) bool { return r == ',' || unicode.IsSpace(r) }
+	for _, filter := range strings.FieldsFunc(value, isSeparator) {
+		i := strings.IndexByte(filter, ':')
+		if i < 0 || i == len(filter)-1 {
+			return nil, fmt.Errorf("invalid filter %q, expected <module|path|image|arch>:<glob>", filter)
+		}
+		glob := filter[i+1:]
+		switch filter[:i] {
+		case "module":
+			filters.modules = append(filters.modules, glob)
+		case "path":
+			glob = strings.TrimSuffix(strings.TrimSuffix(glob, "/"), "/**")
+			filters.paths = append(filters.paths, glob)
+		case "image":
+			filters.images = append(filters.images, glob)
+		case "arch":
+			filters.arches = append(filters.arches, glob)
+		default:
+			return nil, fmt.Errorf("invalid filter %q, unknown kind %q", filter, filter[:i])
+		}
+	}
+	return filters, nil
+}
+
+// match returns true if the module variant with the given name, directory, image and arch is
+// selected by the filters.
+func (f *compdbFilters) match(name, dir, image, arch string) (bool, error) {
+	for _, check := range []struct {
+		globs []string
+		value string
+	}{
+		{f.modules, name},
+		{f.paths, dir},
+		{f.images, image},
+		{f.arches, arch},
+	} {
+		if len(check.globs) == 0 {
+			continue
+		}
+		if ok, err := matchAnyGlob(check.globs, check.value); err != nil || !ok {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// matchPath returns true if the file is under one of the path filters, or if there are none.
+func (f *compdbFilters) matchPath(file string) (bool, error) {
+	if len(f.paths) == 0 {
+		return true, nil
+	}
+	return matchAnyGlob(f.paths, filepath.Dir(file))
+}
+
+// matchAnyGlob returns true if the value matches one of the globs. A path glob also matches
+// the paths below the directories it matches.
+func matchAnyGlob(globs []string, value string) (bool, error) {
+	for _, glob := range globs {
+		for _, pattern := range []string{glob, glob + "/**/*"} {
+			if ok, err := pathtools.Match(pattern, value); err != nil {
+				return false, fmt.Errorf("invalid glob %q: %s", glob, err)
+			} else if ok {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// compdbVariantName returns the name of the directory for the compile_commands.json of the
+// variant of a module.
+func compdbVariantName(ccModule *Module) string {
+	name := ccModule.Os().String() + "_" + ccModule.Arch().ArchType.String()
+	if !ccModule.Host() {
+		name += "_" + string(GetImageVariantType(ccModule))
+	}
+	return name
+}
+
+type compdbObjFilesInterface interface {
+	srcObjFiles() android.Paths
+}
+
 func (c *compdbGeneratorSingleton) GenerateBuildActions(ctx android.SingletonContext) {
-	if !ctx.Config().IsEnvTrue(envVariableGenerateCompdb) {
+	value := ctx.Config().Getenv(envVariableGenerateCompdb)
+	if value == "" || ctx.Config().IsEnvFalse(envVariableGenerateCompdb) {
+		return
+	}
+	filters, err := parseCompdbFilters(value)
+	if err != nil {
+		ctx.Errorf("%s: %s", envVariableGenerateCompdb, err)
 		return
 	}
 
 	// Instruct the generator to indent the json file for easier debugging.
 	outputCompdbDebugInfo := ctx.Config().IsEnvTrue(envVariableGenerateCompdbDebugInfo)
 
-	// We only want one entry per file. We don't care what module/isa it's from
+	// We only want one entry per file in the combined compdb. We don't care what module/isa it's
+	// from. The compdb of each variant also has one entry per file.
 	m := make(map[string]compDbEntry)
+	variants := make(map[string]map[string]compDbEntry)
+	uncompiled := 0
 	ctx.VisitAllModules(func(module android.Module) {
 		if ccModule, ok := module.(*Module); ok {
 			if compiledModule, ok := ccModule.compiler.(CompiledInterface); ok {
-				generateCompdbProject(compiledModule, ctx, ccModule, m)
+				match, err := filters.match(ctx.ModuleName(module), ctx.ModuleDir(module),
+					string(GetImageVariantType(ccModule)), ccModule.Arch().ArchType.String())
+				if err != nil {
+					ctx.Errorf("%s: %s", envVariableGenerateCompdb, err)
+					return
+				}
+				if !match {
+					return
+				}
+				entries, n := generateCompdbProject(compiledModule, ctx, ccModule, filters)
+				uncompiled += n
+				if len(entries) == 0 {
+					return
+				}
+				variant := compdbVariantName(ccModule)
+				if variants[variant] == nil {
+					variants[variant] = make(map[string]compDbEntry)
+				}
+				for _, entry := range entries {
+					if _, ok := m[entry.File]; !ok {
+						m[entry.File] = entry
+					}
+					if _, ok := variants[variant][entry.File]; !ok {
+						variants[variant][entry.File] = entry
+					}
+				}
 			}
 		}
 	})
+	if ctx.Failed() {
+		return
+	}
 
-	// Create the output file.
 	dir := android.PathForOutput(ctx, compdbOutputProjectsDirectory)
-	os.MkdirAll(filepath.Join(android.AbsSrcDirForExistingUseCases(), dir.String()), 0777)
 	compDBFile := dir.Join(ctx, compdbFilename)
-	f, err := os.Create(filepath.Join(android.AbsSrcDirForExistingUseCases(), compDBFile.String()))
-	if err != nil {
-		log.Fatalf("Could not create file %s: %s", compDBFile, err)
+	writeCompdbFile(compDBFile, m, outputCompdbDebugInfo)
+	for variant, entries := range variants {
+		writeCompdbFile(dir.Join(ctx, variant, compdbFilename), entries, outputCompdbDebugInfo)
 	}
-	defer f.Close()
+	removeStaleCompdbVariants(dir, variants)
 
-	v := make([]compDbEntry, 0, len(m))
+	if uncompiled > 0 {
+		fmt.Fprintf(os.Stderr, "warning: %d sources in %s have not been compiled yet, the headers "+
+			"they include are missing from it until they are built and it is regenerated\n",
+			uncompiled, compDBFile)
+	}
 
+	if finalLinkDir := ctx.Config().Getenv(envVariableCompdbLink); finalLinkDir != "" {
+		finalLinkPath := filepath.Join(finalLinkDir, compdbFilename)
+		os.Remove(finalLinkPath)
+		if err := os.Symlink(compDBFile.String(), finalLinkPath); err != nil {
+			log.Fatalf("Unable to symlink %s to %s: %s", compDBFile, finalLinkPath, err)
+		}
+	}
+}
+
+// writeCompdbFile writes the entries sorted by file, unless the file already has the same
+// contents.
+func writeCompdbFile(file android.WritablePath, m map[string]compDbEntry, indent bool) {
+	v := make([]compDbEntry, 0, len(m))
 	for _, value := range m {
 		v = append(v, value)
 	}
+	sort.Slice(v, func(i, j int) bool { return v[i].File < v[j].File })
+
 	var dat []byte
-	if outputCompdbDebugInfo {
+	var err error
+	if indent {
 		dat, err = json.MarshalIndent(v, "", " ")
 	} else {
 		dat, err = json.Marshal(v)
@@ -101,13 +283,29 @@ func (c *compdbGeneratorSingleton) GenerateBuildActions(ctx android.SingletonCon
 	if err != nil {
 		log.Fatalf("Failed to marshal: %s", err)
 	}
-	f.Write(dat)
 
-	if finalLinkDir := ctx.Config().Getenv(envVariableCompdbLink); finalLinkDir != "" {
-		finalLinkPath := filepath.Join(finalLinkDir, compdbFilename)
-		os.Remove(finalLinkPath)
-		if err := os.Symlink(compDBFile.String(), finalLinkPath); err != nil {
-			log.Fatalf("Unable to symlink %s to %s: %s", compDBFile, finalLinkPath, err)
+	absFile := filepath.Join(android.AbsSrcDirForExistingUseCases(), file.String())
+	if old, err := ioutil.ReadFile(absFile); err == nil && bytes.Equal(old, dat) {
+		return
+	}
+	os.MkdirAll(filepath.Dir(absFile), 0777)
+	if err := ioutil.WriteFile(absFile, dat, 0666); err != nil {
+		log.Fatalf("Could not write file %s: %s", file, err)
+	}
+}
+
+// removeStaleCompdbVariants removes the compile_commands.json files of variants that were written
+// by a previous run but are no longer selected.
+func removeStaleCompdbVariants(dir android.OutputPath, variants map[string]map[string]compDbEntry) {
+	absDir := filepath.Join(android.AbsSrcDirForExistingUseCases(), dir.String())
+	infos, err := ioutil.ReadDir(absDir)
+	if err != nil {
+		return
+	}
+	for _, info := range infos {
+		if _, ok := variants[info.Name()]; info.IsDir() && !ok {
+			os.Remove(filepath.Join(absDir, info.Name(), compdbFilename))
+			os.Remove(filepath.Join(absDir, info.Name()))
 		}
 	}
 }
@@ -168,10 +366,20 @@ func getArguments(src android.Path, ctx android.SingletonContext, ccModule *Modu
 	return args
 }
 
-func generateCompdbProject(compiledModule CompiledInterface, ctx android.SingletonContext, ccModule *Module, builds map[string]compDbEntry) {
+// generateCompdbProject returns the entries for the sources of a module variant, followed by the
+// entries for the headers they include, and the number of sources whose included headers are
+// unknown because they have not been compiled yet.
+func generateCompdbProject(compiledModule CompiledInterface, ctx android.SingletonContext, ccModule *Module,
+	filters *compdbFilters) ([]compDbEntry, int) {
+
 	srcs := compiledModule.Srcs()
 	if len(srcs) == 0 {
-		return
+		return nil, 0
+	}
+
+	var objFiles android.Paths
+	if c, ok := compiledModule.(compdbObjFilesInterface); ok {
+		objFiles = c.srcObjFiles()
 	}
 
 	pathToCC, err := ctx.Eval(pctx, "${config.ClangBin}")
@@ -181,15 +389,76 @@ func generateCompdbProject(compiledModule CompiledInterface, ctx android.Singlet
 		ccPath = filepath.Join(pathToCC, "clang")
 		cxxPath = filepath.Join(pathToCC, "clang++")
 	}
-	for _, src := range srcs {
-		if _, ok := builds[src.String()]; !ok {
-			builds[src.String()] = compDbEntry{
-				Directory: android.AbsSrcDirForExistingUseCases(),
-				Arguments: getArguments(src, ctx, ccModule, ccPath, cxxPath),
-				File:      src.String(),
+	var entries, headerEntries []compDbEntry
+	uncompiled := 0
+	for i, src := range srcs {
+		entry := compDbEntry{
+			Directory: android.AbsSrcDirForExistingUseCases(),
+			Arguments: getArguments(src, ctx, ccModule, ccPath, cxxPath),
+			File:      src.String(),
+		}
+		entries = append(entries, entry)
+
+		if len(objFiles) != len(srcs) || objFiles[i] == nil {
+			continue
+		}
+		headers, compiled := compdbHeaders(ctx, objFiles[i].String()+".d")
+		if !compiled {
+			uncompiled++
+		}
+		for _, header := range headers {
+			if ok, _ := filters.matchPath(header); ok {
+				headerEntries = append(headerEntries, compdbHeaderEntry(entry, header))
 			}
 		}
 	}
+	return append(entries, headerEntries...), uncompiled
+}
+
+// compdbHeaderEntry returns an entry for a header that compiles it with the arguments of a
+// source that includes it.
+func compdbHeaderEntry(src compDbEntry, header string) compDbEntry {
+	args := append([]string(nil), src.Arguments...)
+	args[len(args)-1] = header
+	return compDbEntry{
+		Directory: src.Directory,
+		Arguments: args,
+		File:      header,
+	}
+}
+
+// compdbHeaders returns the headers in the source tree listed in the depfile written when the
+// source was last compiled, and false if it has not been compiled yet.  The depfile is read
+// without adding a dependency on it, so the headers may be out of date, see the comment at the top
+// of this file.
+func compdbHeaders(ctx android.SingletonContext, depFile string) ([]string, bool) {
+	f, err := os.Open(filepath.Join(android.AbsSrcDirForExistingUseCases(), depFile))
+	if err != nil {
+		return nil, false
+	}
+	defer f.Close()
+	// Generated headers are skipped, they are in the parent of the soong output directory.
+	return parseCompdbHeaders(depFile, f, filepath.Dir(ctx.Config().BuildDir())), true
+}
+
+func parseCompdbHeaders(depFile string, r io.Reader, outDir string) []string {
+	deps, err := makedeps.Parse(depFile, r)
+	if err != nil || len(deps.Inputs) == 0 {
+		return nil
+	}
+	var headers []string
+	// The first input of a depfile written by clang is the source file itself.
+	for _, input := range deps.Inputs[1:] {
+		input = filepath.Clean(input)
+		if filepath.IsAbs(input) || strings.HasPrefix(input, "../") || strings.HasPrefix(input, outDir+"/") {
+			continue
+		}
+		switch filepath.Ext(input) {
+		case ".h", ".hh", ".hpp", ".hxx", ".inc", ".inl", "":
+			headers = append(headers, input)
+		}
+	}
+	return headers
 }
 
 func evalAndSplitVariable(ctx android.SingletonContext, str string) ([]string, error) {
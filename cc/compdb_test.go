@@ -0,0 +1,121 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cc
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCompdbFilters(t *testing.T) {
+	testCases := []struct {
+		name  string
+		value string
+		err   string
+		match map[[4]string]bool
+	}{
+		{
+			name:  "all",
+			value: "1",
+			match: map[[4]string]bool{
+				{"libfoo", "foo", "core", "arm64"}: true,
+			},
+		},
+		{
+			name:  "module and path",
+			value: "module:libfoo*,path:frameworks/native/**",
+			match: map[[4]string]bool{
+				{"libfoo", "frameworks/native/libs/foo", "core", "arm64"}:  true,
+				{"libfoo_test", "frameworks/native", "host", "x86_64"}:     true,
+				{"libbar", "frameworks/native/libs/bar", "core", "arm64"}:  false,
+				{"libfoo", "frameworks/native2/libs/foo", "core", "arm64"}: false,
+			},
+		},
+		{
+			name:  "image and arch",
+			value: "image:vendor image:product arch:arm*",
+			match: map[[4]string]bool{
+				{"libfoo", "foo", "vendor", "arm64"}:  true,
+				{"libfoo", "foo", "product", "arm"}:   true,
+				{"libfoo", "foo", "core", "arm64"}:    false,
+				{"libfoo", "foo", "vendor", "x86_64"}: false,
+			},
+		},
+		{
+			name:  "missing kind",
+			value: "libfoo",
+			err:   `invalid filter "libfoo"`,
+		},
+		{
+			name:  "unknown kind",
+			value: "variant:shared",
+			err:   `unknown kind "variant"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			filters, err := parseCompdbFilters(tc.value)
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("expected error containing %q, got %v", tc.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			for variant, want := range tc.match {
+				got, err := filters.match(variant[0], variant[1], variant[2], variant[3])
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got != want {
+					t.Errorf("match(%q): expected %v, got %v", variant, want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestCompdbHeaders(t *testing.T) {
+	depFile := `out/soong/.intermediates/foo/libfoo/android_arm64_armv8-a_shared/obj/foo/foo.o: \
+  foo/foo.cpp \
+  foo/include/foo.h \
+  foo/./internal.inc \
+  out/soong/.intermediates/foo/libfoo_gen/gen/foo.pb.h \
+  external/libcxx/include/vector \
+  /usr/include/stdio.h
+`
+	got := parseCompdbHeaders("foo.o.d", strings.NewReader(depFile), "out")
+	want := []string{"foo/include/foo.h", "foo/internal.inc", "external/libcxx/include/vector"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+
+	src := compDbEntry{
+		Directory: "/src",
+		Arguments: []string{"clang++", "-Ifoo/include", "foo/foo.cpp"},
+		File:      "foo/foo.cpp",
+	}
+	header := compdbHeaderEntry(src, "foo/include/foo.h")
+	if w := []string{"clang++", "-Ifoo/include", "foo/include/foo.h"}; !reflect.DeepEqual(header.Arguments, w) {
+		t.Errorf("expected arguments %q, got %q", w, header.Arguments)
+	}
+	if src.Arguments[2] != "foo/foo.cpp" {
+		t.Errorf("source arguments were modified: %q", src.Arguments)
+	}
+}
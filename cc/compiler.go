@@ -82,6 +82,10 @@ type BaseCompilerProperties struct {
 	// directories. Defaults to true.
 	Include_build_directory *bool
 
+	// header file to precompile once for each variant of the module with its cflags and
+	// cppflags. The precompiled header is included in every C++ source with -include-pch.
+	Pch *string `android:"path,arch_variant"`
+
 	// list of generated sources to compile. These are the names of gensrcs or
 	// genrule modules.
 	Generated_sources []string `android:"arch_variant"`
@@ -226,6 +230,9 @@ type baseCompiler struct {
 	// Sources that were passed to the C/C++ compiler
 	srcs android.Paths
 
+	// Objects compiled from srcs, in the same order
+	objFiles android.Paths
+
 	// Sources that were passed in the Android.bp file, including generated sources generated by
 	// other modules and filegroups. May include source files that have not yet been translated to
 	// C/C++ (.aidl, .proto, etc.)
@@ -244,6 +251,10 @@ func (compiler *baseCompiler) Srcs() android.Paths {
 	return append(android.Paths{}, compiler.srcs...)
 }
 
+func (compiler *baseCompiler) srcObjFiles() android.Paths {
+	return append(android.Paths{}, compiler.objFiles...)
+}
+
 func (compiler *baseCompiler) appendCflags(flags []string) {
 	compiler.Properties.Cflags = append(compiler.Properties.Cflags, flags...)
 }
@@ -332,6 +343,16 @@ func (compiler *baseCompiler) compilerFlags(ctx ModuleContext, flags Flags, deps
 	flags.Yacc = compiler.Properties.Yacc
 	flags.Lex = compiler.Properties.Lex
 
+	if pch := android.OptionalPathForModuleSrc(ctx, compiler.Properties.Pch); pch.Valid() {
+		switch pch.Path().Ext() {
+		case ".h", ".hh", ".hpp", ".hxx":
+			flags.PchHeader = pch.Path()
+			flags.Pch = android.ObjPathWithExt(ctx, "pch", pch.Path(), "pch")
+		default:
+			ctx.PropertyErrorf("pch", "%s is not a C++ header", pch.Path())
+		}
+	}
+
 	// Include dir cflags
 	localIncludeDirs := android.PathsForModuleSrc(ctx, compiler.Properties.Local_include_dirs)
 	if len(localIncludeDirs) > 0 {
@@ -656,6 +677,10 @@ func (compiler *baseCompiler) compile(ctx ModuleContext, flags Flags, deps PathD
 	compiler.generatedSourceInfo = info
 	compiler.cFlagsDeps = flags.CFlagsDeps
 
+	if buildFlags.pch != nil {
+		transformHeaderToPch(ctx, buildFlags, pathDeps, compiler.cFlagsDeps)
+	}
+
 	// Save src, buildFlags and context
 	compiler.srcs = srcs
 
@@ -666,6 +691,8 @@ func (compiler *baseCompiler) compile(ctx ModuleContext, flags Flags, deps PathD
 		return Objects{}
 	}
 
+	compiler.objFiles = objs.objFiles
+
 	return objs
 }
 
@@ -245,6 +245,10 @@ func ThreadSanitizerRuntimeLibrary(t Toolchain) string {
 	return LibclangRuntimeLibrary(t, "tsan")
 }
 
+func MemorySanitizerRuntimeLibrary(t Toolchain) string {
+	return LibclangRuntimeLibrary(t, "msan")
+}
+
 func ScudoRuntimeLibrary(t Toolchain) string {
 	return LibclangRuntimeLibrary(t, "scudo")
 }
@@ -73,6 +73,71 @@ type FuzzProperties struct {
 	Fuzz_config *FuzzConfig
 }
 
+const (
+	fuzzEngineLibFuzzer = "libfuzzer"
+	fuzzEngineAfl       = "afl"
+	fuzzEngineHonggfuzz = "honggfuzz"
+)
+
+// Instrumentation flags for the fuzzing engines other than libFuzzer, which use
+// -fsanitize=fuzzer-no-link.
+var fuzzEngineCoverageFlags = map[string][]string{
+	fuzzEngineAfl:       {"-fsanitize-coverage=trace-pc-guard"},
+	fuzzEngineHonggfuzz: {"-fsanitize-coverage=trace-pc-guard,indirect-calls,trace-cmp"},
+}
+
+// Static libraries that provide the main function and the coverage callbacks for the fuzzing
+// engines other than libFuzzer.
+var fuzzEngineRuntimeLibraries = map[string][]string{
+	fuzzEngineAfl:       {"libafl_driver", "afl-compiler-rt"},
+	fuzzEngineHonggfuzz: {"libhfuzz", "libhfcommon"},
+}
+
+// FuzzEngine returns the fuzzing engine that cc_fuzz targets are built for, selected with
+// FUZZ_ENGINE.  Defaults to libfuzzer.
+func FuzzEngine(config android.Config) string {
+	return config.GetenvWithDefault("FUZZ_ENGINE", fuzzEngineLibFuzzer)
+}
+
+// fuzzPackageName returns the name of the package of the fuzz targets of an engine for an
+// architecture.  libFuzzer packages keep their historical names.
+func fuzzPackageName(prefix, engine, hostOrTarget, arch string) string {
+	if engine != fuzzEngineLibFuzzer {
+		prefix += engine + "-"
+	}
+	return prefix + hostOrTarget + "-" + arch
+}
+
+// FuzzManifestEntry describes a fuzz target in the JSON manifest written next to every fuzz
+// package, so that the targets can be scheduled without unpacking the package.
+type FuzzManifestEntry struct {
+	// Name of the fuzz target.
+	Name string `json:"name"`
+	// Name of the zip file of the target in the package.
+	Zip string `json:"zip"`
+	// Fuzzing engine the target is built for.
+	Engine string `json:"engine"`
+	// Sanitizer the target is built with: "address", "hwaddress" or "none".
+	Sanitizer string `json:"sanitizer"`
+	Arch      string `json:"arch"`
+	Host      bool   `json:"host"`
+	// Number of files in the seed corpus of the target.
+	CorpusSize int `json:"corpus_size"`
+	// Name of the dictionary of the target, if any.
+	Dictionary string      `json:"dictionary,omitempty"`
+	FuzzConfig *FuzzConfig `json:"fuzz_config,omitempty"`
+}
+
+// WriteFuzzManifest writes the manifest of the fuzz targets of a package, sorted by name.
+func WriteFuzzManifest(ctx android.SingletonContext, path android.WritablePath, entries []FuzzManifestEntry) {
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+	b, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		panic(err)
+	}
+	android.WriteFileRule(ctx, path, string(b))
+}
+
 func init() {
 	android.RegisterModuleType("cc_fuzz", FuzzFactory)
 	android.RegisterSingletonType("cc_fuzz_packaging", fuzzPackagingFactory)
@@ -115,8 +180,15 @@ func (fuzz *fuzzBinary) linkerInit(ctx BaseModuleContext) {
 }
 
 func (fuzz *fuzzBinary) linkerDeps(ctx DepsContext, deps Deps) Deps {
-	deps.StaticLibs = append(deps.StaticLibs,
-		config.LibFuzzerRuntimeLibrary(ctx.toolchain()))
+	if engine := FuzzEngine(ctx.Config()); engine == fuzzEngineLibFuzzer {
+		deps.StaticLibs = append(deps.StaticLibs,
+			config.LibFuzzerRuntimeLibrary(ctx.toolchain()))
+	} else if libs, ok := fuzzEngineRuntimeLibraries[engine]; ok {
+		deps.StaticLibs = append(deps.StaticLibs, libs...)
+	} else {
+		ctx.ModuleErrorf("FUZZ_ENGINE must be %s, %s or %s, not %q",
+			fuzzEngineLibFuzzer, fuzzEngineAfl, fuzzEngineHonggfuzz, engine)
+	}
 	deps = fuzz.binaryDecorator.linkerDeps(ctx, deps)
 	return deps
 }
@@ -386,6 +458,11 @@ func (s *fuzzPackager) GenerateBuildActions(ctx android.SingletonContext) {
 	// archive}).
 	archDirs := make(map[archOs][]fileToZip)
 
+	// Map between each architecture + host/device combination, and the manifest entries of the
+	// fuzz targets in its package.
+	manifests := make(map[archOs][]FuzzManifestEntry)
+	engine := FuzzEngine(ctx.Config())
+
 	// Map tracking whether each shared library has an install rule to avoid duplicate install rules from
 	// multiple fuzzers that depend on the same shared library.
 	sharedLibraryInstalled := make(map[string]bool)
@@ -532,6 +609,29 @@ func (s *fuzzPackager) GenerateBuildActions(ctx android.SingletonContext) {
 
 		s.fuzzTargets[module.Name()] = true
 		archDirs[archOs] = append(archDirs[archOs], fileToZip{fuzzZip, ""})
+
+		sanitizer := "none"
+		if ccModule.sanitize != nil {
+			if ccModule.sanitize.isSanitizerEnabled(Hwasan) {
+				sanitizer = Hwasan.name()
+			} else if ccModule.sanitize.isSanitizerEnabled(Asan) {
+				sanitizer = Asan.name()
+			}
+		}
+		entry := FuzzManifestEntry{
+			Name:       module.Name(),
+			Zip:        fuzzZip.Base(),
+			Engine:     engine,
+			Sanitizer:  sanitizer,
+			Arch:       archString,
+			Host:       ccModule.Host(),
+			CorpusSize: len(fuzzModule.corpus),
+			FuzzConfig: fuzzModule.Properties.Fuzz_config,
+		}
+		if fuzzModule.dictionary != nil {
+			entry.Dictionary = fuzzModule.dictionary.Base()
+		}
+		manifests[archOs] = append(manifests[archOs], entry)
 	})
 
 	var archOsList []archOs
@@ -545,9 +645,14 @@ func (s *fuzzPackager) GenerateBuildActions(ctx android.SingletonContext) {
 		arch := archOs.arch
 		hostOrTarget := archOs.hostOrTarget
 		builder := android.NewRuleBuilder(pctx, ctx)
-		outputFile := android.PathForOutput(ctx, "fuzz-"+hostOrTarget+"-"+arch+".zip")
+		packageName := fuzzPackageName("fuzz-", engine, hostOrTarget, arch)
+		outputFile := android.PathForOutput(ctx, packageName+".zip")
 		s.packages = append(s.packages, outputFile)
 
+		manifest := android.PathForOutput(ctx, packageName+".json")
+		WriteFuzzManifest(ctx, manifest, manifests[archOs])
+		s.packages = append(s.packages, manifest)
+
 		command := builder.Command().BuiltTool("soong_zip").
 			Flag("-j").
 			FlagWithOutput("-o ", outputFile).
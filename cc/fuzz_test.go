@@ -0,0 +1,143 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cc
+
+import (
+	"encoding/json"
+	"testing"
+
+	"android/soong/android"
+)
+
+var prepareForFuzzPackagingTest = android.GroupFixturePreparers(
+	prepareForCcTest,
+	android.FixtureRegisterWithContext(func(ctx android.RegistrationContext) {
+		ctx.RegisterSingletonType("cc_fuzz_packaging", fuzzPackagingFactory)
+	}),
+	android.FixtureAddFile("corpus/a", nil),
+	android.FixtureAddFile("corpus/b", nil),
+	android.FixtureAddFile("foo.dict", nil),
+)
+
+func TestFuzzManifest(t *testing.T) {
+	result := prepareForFuzzPackagingTest.RunTestWithBp(t, `
+		cc_fuzz {
+			name: "foo_fuzzer",
+			srcs: ["foo.c"],
+			corpus: ["corpus/a", "corpus/b"],
+			dictionary: "foo.dict",
+			fuzz_config: {
+				cc: ["foo@example.com"],
+			},
+		}
+
+		cc_fuzz {
+			name: "bar_fuzzer",
+			srcs: ["bar.c"],
+		}
+
+		cc_fuzz {
+			name: "baz_fuzzer",
+			srcs: ["baz.c"],
+			fuzz_config: {
+				fuzz_on_haiku_device: false,
+			},
+		}
+	`)
+
+	packager := result.SingletonForTests("cc_fuzz_packaging")
+	packager.Output("fuzz-target-arm64.zip")
+
+	var entries []FuzzManifestEntry
+	content := android.ContentFromFileRuleForTests(t, packager.Output("fuzz-target-arm64.json"))
+	if err := json.Unmarshal([]byte(content), &entries); err != nil {
+		t.Fatalf("invalid manifest %q: %s", content, err)
+	}
+
+	// baz_fuzzer is not exported to the fuzzing infrastructure.
+	android.AssertIntEquals(t, "manifest entries", 2, len(entries))
+
+	bar := entries[0]
+	android.AssertStringEquals(t, "name", "bar_fuzzer", bar.Name)
+	android.AssertIntEquals(t, "corpus size", 0, bar.CorpusSize)
+	android.AssertStringEquals(t, "dictionary", "", bar.Dictionary)
+	if bar.FuzzConfig != nil {
+		t.Errorf("expected no fuzz config for bar_fuzzer, got %s", bar.FuzzConfig)
+	}
+
+	foo := entries[1]
+	android.AssertStringEquals(t, "name", "foo_fuzzer", foo.Name)
+	android.AssertStringEquals(t, "zip", "foo_fuzzer.zip", foo.Zip)
+	android.AssertStringEquals(t, "engine", "libfuzzer", foo.Engine)
+	android.AssertStringEquals(t, "arch", "arm64", foo.Arch)
+	android.AssertBoolEquals(t, "host", false, foo.Host)
+	android.AssertIntEquals(t, "corpus size", 2, foo.CorpusSize)
+	android.AssertStringEquals(t, "dictionary", "foo.dict", foo.Dictionary)
+	if foo.FuzzConfig == nil {
+		t.Fatalf("expected fuzz config for foo_fuzzer")
+	}
+	android.AssertDeepEquals(t, "fuzz config cc", []string{"foo@example.com"}, foo.FuzzConfig.Cc)
+}
+
+func TestFuzzEngineAfl(t *testing.T) {
+	result := android.GroupFixturePreparers(
+		prepareForFuzzPackagingTest,
+		android.FixtureMergeEnv(map[string]string{"FUZZ_ENGINE": "afl"}),
+	).RunTestWithBp(t, `
+		cc_fuzz {
+			name: "foo_fuzzer",
+			srcs: ["foo.c"],
+		}
+
+		cc_library_static {
+			name: "libafl_driver",
+			srcs: ["afl_driver.c"],
+		}
+
+		cc_library_static {
+			name: "afl-compiler-rt",
+			srcs: ["afl_compiler_rt.c"],
+		}
+	`)
+
+	foo := result.ModuleForTests("foo_fuzzer", "android_arm64_armv8-a_fuzzer")
+	cFlags := foo.Rule("cc").Args["cFlags"]
+	android.AssertStringDoesContain(t, "cflags", cFlags, "-fsanitize-coverage=trace-pc-guard")
+	android.AssertStringDoesNotContain(t, "cflags", cFlags, "fuzzer-no-link")
+
+	libFlags := foo.Rule("ld").Args["libFlags"]
+	android.AssertStringDoesContain(t, "lib flags", libFlags, "libafl_driver.a")
+	android.AssertStringDoesContain(t, "lib flags", libFlags, "afl-compiler-rt.a")
+	android.AssertStringDoesNotContain(t, "lib flags", libFlags, "libclang_rt.fuzzer")
+
+	packager := result.SingletonForTests("cc_fuzz_packaging")
+	packager.Output("fuzz-afl-target-arm64.zip")
+	content := android.ContentFromFileRuleForTests(t, packager.Output("fuzz-afl-target-arm64.json"))
+	android.AssertStringDoesContain(t, "manifest", content, `"engine": "afl"`)
+}
+
+func TestFuzzEngineInvalid(t *testing.T) {
+	android.GroupFixturePreparers(
+		prepareForCcTest,
+		android.FixtureMergeEnv(map[string]string{"FUZZ_ENGINE": "radamsa"}),
+	).ExtendWithErrorHandler(android.FixtureExpectsAtLeastOneErrorMatchingPattern(
+		`FUZZ_ENGINE must be libfuzzer, afl or honggfuzz, not "radamsa"`)).
+		RunTestWithBp(t, `
+		cc_fuzz {
+			name: "foo_fuzzer",
+			srcs: ["foo.c"],
+		}
+	`)
+}
@@ -0,0 +1,116 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cc
+
+import (
+	"strings"
+
+	"github.com/google/blueprint"
+
+	"android/soong/android"
+)
+
+// The layering check verifies that every header included by the sources of a module comes from the
+// module itself or from one of its direct dependencies, and not from a library that is only
+// reachable through a chain of export_*_lib_headers or through a hard-coded include_dirs path.
+//
+// After each source is compiled, layering_check reads the depfile written by the compiler and looks
+// up the headers it lists in an index of the include directories exported by the transitive
+// dependencies of the module.  A header under a directory exported by a module that is not a
+// direct dependency is an error.  Headers of modules that are not dependencies at all are not
+// indexed, so including them through a hard-coded include_dirs path is not detected.  The check
+// relies on the depfiles being kept after ninja has read them, which soong_ui does by running
+// ninja with -d keepdepfile.
+
+func init() {
+	pctx.HostBinToolVariable("layeringCheckCmd", "layering_check")
+}
+
+var layeringCheck = pctx.AndroidStaticRule("layeringCheck",
+	blueprint.RuleParams{
+		Command:     "$layeringCheckCmd --exports $exports $layeringCheckFlags -o $out $in",
+		CommandDeps: []string{"$layeringCheckCmd"},
+	},
+	"exports", "layeringCheckFlags")
+
+type LayeringCheckProperties struct {
+	// whether to check that every header included by the sources of this module comes from the
+	// module itself or from one of its direct shared_libs, static_libs, whole_static_libs or
+	// header_libs dependencies.
+	Layering_check *bool
+}
+
+type layeringCheckFeature struct {
+	Properties LayeringCheckProperties
+}
+
+// layeringCheckInfo contains the include directories a module exports itself, as opposed to the
+// directories it re-exports from its dependencies.
+type layeringCheckInfo struct {
+	ExportedDirs android.Paths
+}
+
+var layeringCheckInfoProvider = blueprint.NewProvider(layeringCheckInfo{})
+
+func (layering *layeringCheckFeature) props() []interface{} {
+	return []interface{}{&layering.Properties}
+}
+
+func (layering *layeringCheckFeature) begin(ctx BaseModuleContext) {
+}
+
+func (layering *layeringCheckFeature) deps(ctx DepsContext, deps Deps) Deps {
+	return deps
+}
+
+func (layering *layeringCheckFeature) flags(ctx ModuleContext, flags Flags) Flags {
+	if !Bool(layering.Properties.Layering_check) {
+		return flags
+	}
+
+	allowed := []string{android.RemoveOptionalPrebuiltPrefix(ctx.ModuleName())}
+	ctx.VisitDirectDeps(func(dep android.Module) {
+		if _, ok := ctx.OtherModuleDependencyTag(dep).(libraryDependencyTag); ok {
+			allowed = append(allowed, android.RemoveOptionalPrebuiltPrefix(ctx.OtherModuleName(dep)))
+		}
+	})
+
+	// Write the index of the include directories exported by the transitive dependencies, with
+	// one line per directory and module that exports it.
+	var lines []string
+	ctx.VisitDepsDepthFirst(func(dep android.Module) {
+		if !ctx.OtherModuleHasProvider(dep, layeringCheckInfoProvider) {
+			return
+		}
+		info := ctx.OtherModuleProvider(dep, layeringCheckInfoProvider).(layeringCheckInfo)
+		name := android.RemoveOptionalPrebuiltPrefix(ctx.OtherModuleName(dep))
+		for _, dir := range info.ExportedDirs {
+			lines = append(lines, dir.String()+" "+name)
+		}
+	})
+	android.WriteFileRule(ctx, layeringCheckExportsPath(ctx), strings.Join(android.SortedUniqueStrings(lines), "\n"))
+
+	flags.LayeringCheck = true
+	flags.LayeringCheckFlags = append(flags.LayeringCheckFlags,
+		"--module "+ctx.ModuleName(),
+		"--allow "+strings.Join(android.SortedUniqueStrings(allowed), ","))
+	return flags
+}
+
+// layeringCheckExportsPath returns the path to the index of the include directories exported by
+// the transitive dependencies of the module.
+func layeringCheckExportsPath(ctx android.ModuleContext) android.ModuleOutPath {
+	return android.PathForModuleOut(ctx, "layering_check", "exports.txt")
+}
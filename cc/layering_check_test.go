@@ -0,0 +1,82 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cc
+
+import (
+	"testing"
+
+	"android/soong/android"
+)
+
+func TestLayeringCheck(t *testing.T) {
+	ctx := testCc(t, `
+		cc_library_headers {
+			name: "libbar_headers",
+			export_include_dirs: ["bar/include"],
+			export_header_lib_headers: ["libbaz_headers"],
+			header_libs: ["libbaz_headers"],
+		}
+
+		cc_library_headers {
+			name: "libbaz_headers",
+			export_system_include_dirs: ["baz/include"],
+		}
+
+		cc_library_shared {
+			name: "libfoo",
+			srcs: ["foo.c", "start.s"],
+			header_libs: ["libbar_headers"],
+			stl: "none",
+			system_shared_libs: [],
+			layering_check: true,
+		}
+
+		cc_library_shared {
+			name: "libqux",
+			srcs: ["qux.c"],
+			export_include_dirs: ["qux/include"],
+			stl: "none",
+			system_shared_libs: [],
+		}
+	`)
+
+	libfoo := ctx.ModuleForTests("libfoo", "android_arm64_armv8-a_shared")
+
+	// Only the include directories of the dependencies of libfoo are indexed.
+	exports := libfoo.Output("layering_check/exports.txt")
+	content := android.ContentFromFileRuleForTests(t, exports)
+	android.AssertStringEquals(t, "exports", "bar/include libbar_headers\nbaz/include libbaz_headers", content)
+
+	check := libfoo.Output("obj/foo.layering")
+	android.AssertPathRelativeToTopEquals(t, "input", "out/soong/.intermediates/libfoo/android_arm64_armv8-a_shared/obj/foo.o", check.Input)
+	android.AssertPathsRelativeToTopEquals(t, "implicits", []string{
+		"out/soong/.intermediates/libfoo/android_arm64_armv8-a_shared/obj/foo.o.d",
+		"out/soong/.intermediates/libfoo/android_arm64_armv8-a_shared/layering_check/exports.txt",
+	}, check.Implicits)
+	android.AssertPathsRelativeToTopEquals(t, "compile implicit outputs", []string{
+		"out/soong/.intermediates/libfoo/android_arm64_armv8-a_shared/obj/foo.o.d",
+	}, libfoo.Output("obj/foo.o").ImplicitOutputs.Paths())
+	android.AssertStringEquals(t, "flags", "--module libfoo --allow libbar_headers,libfoo", check.Args["layeringCheckFlags"])
+	android.AssertStringListContains(t, "link implicits", libfoo.Rule("ld").Implicits.Strings(), check.Output.String())
+
+	// Assembly sources that are not preprocessed don't write depfiles, and are not checked.
+	if params := libfoo.MaybeOutput("obj/start.layering"); params.Rule != nil {
+		t.Errorf("unexpected layering check for start.s")
+	}
+
+	if params := ctx.ModuleForTests("libqux", "android_arm64_armv8-a_shared").MaybeRule("layeringCheck"); params.Rule != nil {
+		t.Errorf("expected no layering check for libqux")
+	}
+}
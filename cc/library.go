@@ -383,6 +383,10 @@ type flagExporter struct {
 	flags      []string      // Exported raw flags.
 	deps       android.Paths
 	headers    android.Paths
+
+	// Include directories and system include directories exported by this module itself, excluding
+	// those re-exported from its dependencies.
+	ownDirs android.Paths
 }
 
 // exportedIncludes returns the effective include paths for this module and
@@ -401,8 +405,12 @@ func (f *flagExporter) exportedIncludes(ctx ModuleContext) android.Paths {
 // exportIncludes registers the include directories and system include directories to be exported
 // transitively to modules depending on this module.
 func (f *flagExporter) exportIncludes(ctx ModuleContext) {
-	f.dirs = append(f.dirs, f.exportedIncludes(ctx)...)
-	f.systemDirs = append(f.systemDirs, android.PathsForModuleSrc(ctx, f.Properties.Export_system_include_dirs)...)
+	dirs := f.exportedIncludes(ctx)
+	systemDirs := android.PathsForModuleSrc(ctx, f.Properties.Export_system_include_dirs)
+	f.dirs = append(f.dirs, dirs...)
+	f.systemDirs = append(f.systemDirs, systemDirs...)
+	f.ownDirs = append(f.ownDirs, dirs...)
+	f.ownDirs = append(f.ownDirs, systemDirs...)
 }
 
 func (f *flagExporter) exportExtraFlags(ctx ModuleContext) {
@@ -413,8 +421,10 @@ func (f *flagExporter) exportExtraFlags(ctx ModuleContext) {
 // exported transitively both as system include directories to modules depending on this module.
 func (f *flagExporter) exportIncludesAsSystem(ctx ModuleContext) {
 	// all dirs are force exported as system
-	f.systemDirs = append(f.systemDirs, f.exportedIncludes(ctx)...)
-	f.systemDirs = append(f.systemDirs, android.PathsForModuleSrc(ctx, f.Properties.Export_system_include_dirs)...)
+	dirs := f.exportedIncludes(ctx)
+	dirs = append(dirs, android.PathsForModuleSrc(ctx, f.Properties.Export_system_include_dirs)...)
+	f.systemDirs = append(f.systemDirs, dirs...)
+	f.ownDirs = append(f.ownDirs, dirs...)
 }
 
 // reexportDirs registers the given directories as include directories to be exported transitively
@@ -464,6 +474,9 @@ func (f *flagExporter) setProvider(ctx android.ModuleContext) {
 		// sysprop headers.
 		GeneratedHeaders: f.headers,
 	})
+	ctx.SetProvider(layeringCheckInfoProvider, layeringCheckInfo{
+		ExportedDirs: android.FirstUniquePaths(f.ownDirs),
+	})
 }
 
 // libraryDecorator wraps baseCompiler, baseLinker and baseInstaller to provide library-specific
@@ -509,6 +522,11 @@ type libraryDecorator struct {
 	// Location of the linked, unstripped library for shared libraries
 	unstrippedOutputFile android.Path
 
+	// Location of the split DWARF package of the unstripped shared library, and of its copy in the
+	// symbols directory for device modules
+	splitDwarfPackage        android.OptionalPath
+	symbolsSplitDwarfPackage android.OptionalPath
+
 	// Location of the file that should be copied to dist dir when requested
 	distFile android.Path
 
@@ -1120,6 +1138,7 @@ func (library *libraryDecorator) linkerSpecifiedDeps(specifiedDeps specifiedDeps
 	}
 
 	specifiedDeps.sharedLibs = append(specifiedDeps.sharedLibs, properties.Shared_libs...)
+	specifiedDeps.staticLibs = append(specifiedDeps.staticLibs, properties.Static_libs...)
 
 	// Must distinguish nil and [] in system_shared_libs - ensure that [] in
 	// either input list doesn't come out as nil.
@@ -1130,6 +1149,7 @@ func (library *libraryDecorator) linkerSpecifiedDeps(specifiedDeps specifiedDeps
 	}
 
 	specifiedDeps.sharedLibs = android.FirstUniqueStrings(specifiedDeps.sharedLibs)
+	specifiedDeps.staticLibs = android.FirstUniqueStrings(specifiedDeps.staticLibs)
 	if len(specifiedDeps.systemSharedLibs) > 0 {
 		// Skip this if systemSharedLibs is either nil or [], to ensure they are
 		// retained.
@@ -1160,7 +1180,13 @@ func (library *libraryDecorator) linkStatic(ctx ModuleContext,
 		}
 	}
 
-	transformObjToStaticLib(ctx, library.objects.objFiles, deps.WholeStaticLibsFromPrebuilts, builderFlags, outputFile, objs.tidyFiles)
+	// Copy the tidy files first, appending to objs.tidyFiles could overwrite the paths after them in
+	// a backing array shared with other Objects.
+	staticLibDeps := android.CopyOfPaths(objs.tidyFiles)
+	staticLibDeps = append(staticLibDeps, objs.layeringCheckFiles...)
+	staticLibDeps = append(staticLibDeps, objs.staticAnalyzerFiles...)
+	transformObjToStaticLib(ctx, library.objects.objFiles, deps.WholeStaticLibsFromPrebuilts, builderFlags, outputFile,
+		staticLibDeps)
 
 	library.coverageOutputFile = transformCoverageFilesToZip(ctx, library.objects, ctx.ModuleName())
 
@@ -1261,6 +1287,12 @@ func (library *libraryDecorator) linkShared(ctx ModuleContext,
 	}
 	library.unstrippedOutputFile = outputFile
 
+	if flags.SplitDwarf && !library.buildStubs() {
+		dwpFile := android.PathForModuleOut(ctx, "unstripped", fileName+".dwp")
+		library.stripper.PackSplitDwarf(ctx, outputFile, dwpFile)
+		library.splitDwarfPackage = android.OptionalPathForPath(dwpFile)
+	}
+
 	outputFile = maybeInjectBoringSSLHash(ctx, outputFile, library.Properties.Inject_bssl_hash, fileName)
 
 	if Bool(library.baseLinker.Properties.Use_version_lib) {
@@ -1290,6 +1322,8 @@ func (library *libraryDecorator) linkShared(ctx ModuleContext,
 	linkerDeps = append(linkerDeps, deps.SharedLibsDeps...)
 	linkerDeps = append(linkerDeps, deps.LateSharedLibsDeps...)
 	linkerDeps = append(linkerDeps, objs.tidyFiles...)
+	linkerDeps = append(linkerDeps, objs.layeringCheckFiles...)
+	linkerDeps = append(linkerDeps, objs.staticAnalyzerFiles...)
 
 	if Bool(library.Properties.Sort_bss_symbols_by_size) && !library.buildStubs() {
 		unsortedOutputFile := android.PathForModuleOut(ctx, "unsorted", fileName)
@@ -1682,6 +1716,8 @@ func (library *libraryDecorator) install(ctx ModuleContext, file android.Path) {
 		}
 
 		library.baseInstaller.install(ctx, file)
+		library.symbolsSplitDwarfPackage = InstallSplitDwarfPackage(ctx, library.baseInstaller.installDir(ctx),
+			file.Base(), library.splitDwarfPackage)
 	}
 
 	if Bool(library.Properties.Static_ndk_lib) && library.static() &&
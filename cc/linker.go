@@ -553,6 +553,7 @@ func (linker *baseLinker) link(ctx ModuleContext,
 
 func (linker *baseLinker) linkerSpecifiedDeps(specifiedDeps specifiedDeps) specifiedDeps {
 	specifiedDeps.sharedLibs = append(specifiedDeps.sharedLibs, linker.Properties.Shared_libs...)
+	specifiedDeps.staticLibs = append(specifiedDeps.staticLibs, linker.Properties.Static_libs...)
 
 	// Must distinguish nil and [] in system_shared_libs - ensure that [] in
 	// either input list doesn't come out as nil.
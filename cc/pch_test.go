@@ -0,0 +1,63 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cc
+
+import (
+	"testing"
+
+	"android/soong/android"
+)
+
+func TestPch(t *testing.T) {
+	ctx := testCc(t, `
+		cc_library {
+			name: "libfoo",
+			srcs: ["foo.cpp", "bar.c", "baz.mm"],
+			cflags: ["-DFOO"],
+			pch: "include/pch.h",
+		}
+	`)
+
+	for _, variant := range []string{"android_arm64_armv8-a_shared", "android_arm64_armv8-a_static"} {
+		libfoo := ctx.ModuleForTests("libfoo", variant)
+
+		pch := libfoo.Rule("ccPch")
+		android.AssertPathRelativeToTopEquals(t, "pch output",
+			"out/soong/.intermediates/libfoo/"+variant+"/obj/pch/include/pch.pch", pch.Output)
+		android.AssertStringEquals(t, "pch input", "include/pch.h", pch.Input.String())
+		android.AssertStringDoesContain(t, "pch cflags", pch.Args["cFlags"], "-DFOO")
+
+		cpp := libfoo.Output("obj/foo.o")
+		android.AssertStringDoesContain(t, "foo.cpp cflags", cpp.Args["cFlags"], "-include-pch "+pch.Output.String())
+		android.AssertStringListContains(t, "foo.cpp implicits", cpp.Implicits.Strings(), pch.Output.String())
+
+		// The precompiled header is C++, so it is not used for C or Objective-C++ sources.
+		for _, obj := range []string{"obj/bar.o", "obj/baz.o"} {
+			params := libfoo.Output(obj)
+			android.AssertStringDoesNotContain(t, obj+" cflags", params.Args["cFlags"], "-include-pch")
+			android.AssertStringListDoesNotContain(t, obj+" implicits", params.Implicits.Strings(), pch.Output.String())
+		}
+	}
+}
+
+func TestPchNotHeader(t *testing.T) {
+	testCcError(t, `module "libfoo".*: pch: foo.cpp is not a C\+\+ header`, `
+		cc_library {
+			name: "libfoo",
+			srcs: ["foo.cpp"],
+			pch: "foo.cpp",
+		}
+	`)
+}
@@ -37,6 +37,11 @@ var (
 	}
 	asanLdflags = []string{"-Wl,-u,__asan_preinit"}
 
+	msanCflags = []string{
+		"-fno-omit-frame-pointer",
+		"-fsanitize-memory-track-origins",
+	}
+
 	hwasanCflags = []string{"-fno-omit-frame-pointer", "-Wno-frame-larger-than=",
 		"-fsanitize-hwaddress-abi=platform",
 		"-fno-experimental-new-pass-manager",
@@ -85,6 +90,7 @@ const (
 	Asan SanitizerType = iota + 1
 	Hwasan
 	tsan
+	msan
 	intOverflow
 	cfi
 	scs
@@ -101,6 +107,8 @@ func (t SanitizerType) variationName() string {
 		return "hwasan"
 	case tsan:
 		return "tsan"
+	case msan:
+		return "msan"
 	case intOverflow:
 		return "intOverflow"
 	case cfi:
@@ -127,6 +135,8 @@ func (t SanitizerType) name() string {
 		return "memtag_heap"
 	case tsan:
 		return "thread"
+	case msan:
+		return "memory"
 	case intOverflow:
 		return "integer_overflow"
 	case cfi:
@@ -148,6 +158,8 @@ func (*Module) SanitizerSupported(t SanitizerType) bool {
 		return true
 	case tsan:
 		return true
+	case msan:
+		return true
 	case intOverflow:
 		return true
 	case cfi:
@@ -172,6 +184,7 @@ type SanitizeUserProps struct {
 	// main sanitizers
 	Address   *bool `android:"arch_variant"`
 	Thread    *bool `android:"arch_variant"`
+	Memory    *bool `android:"arch_variant"`
 	Hwaddress *bool `android:"arch_variant"`
 
 	// local sanitizers
@@ -191,7 +204,7 @@ type SanitizeUserProps struct {
 
 	// Sanitizers to run in the diagnostic mode (as opposed to the release mode).
 	// Replaces abort() on error with a human-readable error message.
-	// Address and Thread sanitizers always run in diagnostic mode.
+	// Address, Thread and Memory sanitizers always run in diagnostic mode.
 	Diag struct {
 		Undefined        *bool    `android:"arch_variant"`
 		Cfi              *bool    `android:"arch_variant"`
@@ -306,6 +319,10 @@ func (sanitize *sanitize) begin(ctx BaseModuleContext) {
 			s.Thread = boolPtr(true)
 		}
 
+		if found, globalSanitizers = removeFromList("memory", globalSanitizers); found && s.Memory == nil {
+			s.Memory = boolPtr(true)
+		}
+
 		if found, globalSanitizers = removeFromList("fuzzer", globalSanitizers); found && s.Fuzzer == nil {
 			s.Fuzzer = boolPtr(true)
 		}
@@ -458,6 +475,7 @@ func (sanitize *sanitize) begin(ctx BaseModuleContext) {
 		s.Address = nil
 		s.Fuzzer = nil
 		s.Thread = nil
+		s.Memory = nil
 	}
 
 	if Bool(s.All_undefined) {
@@ -471,14 +489,21 @@ func (sanitize *sanitize) begin(ctx BaseModuleContext) {
 		// TODO(ccross): error for compile_multilib = "32"?
 	}
 
+	// MSan is only supported for glibc host builds on x86_64, and can't be combined with the
+	// other sanitizers that replace the allocator.
+	if !ctx.Host() || ctx.Os() != android.Linux || ctx.Arch().ArchType != android.X86_64 ||
+		Bool(s.Address) || Bool(s.Thread) || Bool(s.Hwaddress) {
+		s.Memory = nil
+	}
+
 	if ctx.Os() != android.Windows && (Bool(s.All_undefined) || Bool(s.Undefined) || Bool(s.Address) || Bool(s.Thread) ||
-		Bool(s.Fuzzer) || Bool(s.Safestack) || Bool(s.Cfi) || Bool(s.Integer_overflow) || len(s.Misc_undefined) > 0 ||
+		Bool(s.Memory) || Bool(s.Fuzzer) || Bool(s.Safestack) || Bool(s.Cfi) || Bool(s.Integer_overflow) || len(s.Misc_undefined) > 0 ||
 		Bool(s.Scudo) || Bool(s.Hwaddress) || Bool(s.Scs) || Bool(s.Memtag_heap)) {
 		sanitize.Properties.SanitizerEnabled = true
 	}
 
-	// Disable Scudo if ASan or TSan is enabled, or if it's disabled globally.
-	if Bool(s.Address) || Bool(s.Thread) || Bool(s.Hwaddress) || ctx.Config().DisableScudo() {
+	// Disable Scudo if ASan, TSan or MSan is enabled, or if it's disabled globally.
+	if Bool(s.Address) || Bool(s.Thread) || Bool(s.Memory) || Bool(s.Hwaddress) || ctx.Config().DisableScudo() {
 		s.Scudo = nil
 	}
 
@@ -589,6 +614,10 @@ func (sanitize *sanitize) flags(ctx ModuleContext, flags Flags) Flags {
 		}
 	}
 
+	if Bool(sanitize.Properties.Sanitize.Memory) {
+		flags.Local.CFlags = append(flags.Local.CFlags, msanCflags...)
+	}
+
 	if Bool(sanitize.Properties.Sanitize.Hwaddress) {
 		flags.Local.CFlags = append(flags.Local.CFlags, hwasanCflags...)
 		if Bool(sanitize.Properties.Sanitize.Writeonly) {
@@ -597,7 +626,13 @@ func (sanitize *sanitize) flags(ctx ModuleContext, flags Flags) Flags {
 	}
 
 	if Bool(sanitize.Properties.Sanitize.Fuzzer) {
-		flags.Local.CFlags = append(flags.Local.CFlags, "-fsanitize=fuzzer-no-link")
+		// Engines other than libFuzzer provide their own coverage callbacks, and only need
+		// the coverage instrumentation.
+		if coverageFlags, ok := fuzzEngineCoverageFlags[FuzzEngine(ctx.Config())]; ok {
+			flags.Local.CFlags = append(flags.Local.CFlags, coverageFlags...)
+		} else {
+			flags.Local.CFlags = append(flags.Local.CFlags, "-fsanitize=fuzzer-no-link")
+		}
 
 		// TODO(b/131771163): LTO and Fuzzer support is mutually incompatible.
 		_, flags.Local.LdFlags = removeFromList("-flto", flags.Local.LdFlags)
@@ -754,6 +789,8 @@ func (sanitize *sanitize) getSanitizerBoolPtr(t SanitizerType) *bool {
 		return sanitize.Properties.Sanitize.Hwaddress
 	case tsan:
 		return sanitize.Properties.Sanitize.Thread
+	case msan:
+		return sanitize.Properties.Sanitize.Memory
 	case intOverflow:
 		return sanitize.Properties.Sanitize.Integer_overflow
 	case cfi:
@@ -774,6 +811,7 @@ func (sanitize *sanitize) isUnsanitizedVariant() bool {
 	return !sanitize.isSanitizerEnabled(Asan) &&
 		!sanitize.isSanitizerEnabled(Hwasan) &&
 		!sanitize.isSanitizerEnabled(tsan) &&
+		!sanitize.isSanitizerEnabled(msan) &&
 		!sanitize.isSanitizerEnabled(cfi) &&
 		!sanitize.isSanitizerEnabled(scs) &&
 		!sanitize.isSanitizerEnabled(memtag_heap) &&
@@ -785,6 +823,7 @@ func (sanitize *sanitize) isVariantOnProductionDevice() bool {
 	return !sanitize.isSanitizerEnabled(Asan) &&
 		!sanitize.isSanitizerEnabled(Hwasan) &&
 		!sanitize.isSanitizerEnabled(tsan) &&
+		!sanitize.isSanitizerEnabled(msan) &&
 		!sanitize.isSanitizerEnabled(Fuzzer)
 }
 
@@ -796,6 +835,8 @@ func (sanitize *sanitize) SetSanitizer(t SanitizerType, b bool) {
 		sanitize.Properties.Sanitize.Hwaddress = boolPtr(b)
 	case tsan:
 		sanitize.Properties.Sanitize.Thread = boolPtr(b)
+	case msan:
+		sanitize.Properties.Sanitize.Memory = boolPtr(b)
 	case intOverflow:
 		sanitize.Properties.Sanitize.Integer_overflow = boolPtr(b)
 	case cfi:
@@ -1051,6 +1092,10 @@ func sanitizerRuntimeMutator(mctx android.BottomUpMutatorContext) {
 			sanitizers = append(sanitizers, "thread")
 		}
 
+		if Bool(c.sanitize.Properties.Sanitize.Memory) {
+			sanitizers = append(sanitizers, "memory")
+		}
+
 		if Bool(c.sanitize.Properties.Sanitize.Safestack) {
 			sanitizers = append(sanitizers, "safe-stack")
 		}
@@ -1100,7 +1145,7 @@ func sanitizerRuntimeMutator(mctx android.BottomUpMutatorContext) {
 			mctx.AddFarVariationDependencies(variations, depTag, noteDep)
 		}
 
-		if Bool(c.sanitize.Properties.Sanitize.Fuzzer) {
+		if Bool(c.sanitize.Properties.Sanitize.Fuzzer) && FuzzEngine(mctx.Config()) == fuzzEngineLibFuzzer {
 			sanitizers = append(sanitizers, "fuzzer-no-link")
 		}
 
@@ -1129,6 +1174,8 @@ func sanitizerRuntimeMutator(mctx android.BottomUpMutatorContext) {
 			}
 		} else if Bool(c.sanitize.Properties.Sanitize.Thread) {
 			runtimeLibrary = config.ThreadSanitizerRuntimeLibrary(toolchain)
+		} else if Bool(c.sanitize.Properties.Sanitize.Memory) {
+			runtimeLibrary = config.MemorySanitizerRuntimeLibrary(toolchain)
 		} else if Bool(c.sanitize.Properties.Sanitize.Scudo) {
 			if len(diagSanitizers) == 0 && !c.sanitize.Properties.UbsanRuntimeDep {
 				runtimeLibrary = config.ScudoMinimalRuntimeLibrary(toolchain)
@@ -202,3 +202,102 @@ func TestAsan(t *testing.T) {
 	t.Run("host", func(t *testing.T) { check(t, result, result.Config.BuildOSTarget.String()) })
 	t.Run("device", func(t *testing.T) { check(t, result, "android_arm64_armv8-a") })
 }
+
+func TestMsan(t *testing.T) {
+	bp := `
+		cc_binary {
+			name: "bin_with_msan",
+			host_supported: true,
+			srcs: ["foo.c"],
+			shared_libs: ["libshared"],
+			static_libs: ["libstatic"],
+			sanitize: {
+				memory: true,
+			}
+		}
+
+		cc_binary {
+			name: "bin_no_msan",
+			host_supported: true,
+			srcs: ["foo.c"],
+			static_libs: ["libstatic"],
+		}
+
+		cc_library_shared {
+			name: "libshared",
+			host_supported: true,
+			srcs: ["foo.c"],
+		}
+
+		cc_library_static {
+			name: "libstatic",
+			host_supported: true,
+			srcs: ["foo.c"],
+		}
+	`
+
+	result := android.GroupFixturePreparers(
+		prepareForCcTest,
+	).RunTestWithBp(t, bp)
+
+	host := result.Config.BuildOSTarget.String()
+
+	// The binary and its dependencies get msan variants on the host.
+	binWithMsan := result.ModuleForTests("bin_with_msan", host+"_msan")
+	android.AssertStringDoesContain(t, "bin_with_msan cflags",
+		binWithMsan.Rule("cc").Args["cFlags"], "-fsanitize=memory")
+	android.AssertStringDoesContain(t, "bin_with_msan ldflags",
+		binWithMsan.Rule("ld").Args["ldFlags"], "-fsanitize=memory")
+
+	libStaticMsan := result.ModuleForTests("libstatic", host+"_static_msan")
+	android.AssertStringDoesContain(t, "libstatic cflags",
+		libStaticMsan.Rule("cc").Args["cFlags"], "-fsanitize=memory")
+	android.AssertStringListContains(t, "bin_with_msan link implicits",
+		binWithMsan.Rule("ld").Implicits.Strings(), libStaticMsan.Output("libstatic.a").Output.String())
+
+	libSharedMsan := result.ModuleForTests("libshared", host+"_shared_msan")
+	android.AssertStringDoesContain(t, "libshared cflags",
+		libSharedMsan.Rule("cc").Args["cFlags"], "-fsanitize=memory")
+
+	// The binary without msan links against the uninstrumented variant of the static library.
+	binNoMsan := result.ModuleForTests("bin_no_msan", host)
+	libStatic := result.ModuleForTests("libstatic", host+"_static")
+	android.AssertStringDoesNotContain(t, "libstatic cflags",
+		libStatic.Rule("cc").Args["cFlags"], "-fsanitize=memory")
+	android.AssertStringListContains(t, "bin_no_msan link implicits",
+		binNoMsan.Rule("ld").Implicits.Strings(), libStatic.Output("libstatic.a").Output.String())
+
+	// MSan is not supported on the device.
+	device := result.ModuleForTests("bin_with_msan", "android_arm64_armv8-a")
+	android.AssertStringDoesNotContain(t, "device cflags",
+		device.Rule("cc").Args["cFlags"], "-fsanitize=memory")
+}
+
+func TestMsanSanitizeHost(t *testing.T) {
+	bp := `
+		cc_binary {
+			name: "bin",
+			host_supported: true,
+			srcs: ["foo.c"],
+			static_libs: ["libstatic"],
+		}
+
+		cc_library_static {
+			name: "libstatic",
+			host_supported: true,
+			srcs: ["foo.c"],
+		}
+	`
+
+	result := android.GroupFixturePreparers(
+		prepareForCcTest,
+		android.FixtureModifyProductVariables(func(variables android.FixtureProductVariables) {
+			variables.SanitizeHost = []string{"memory"}
+		}),
+	).RunTestWithBp(t, bp)
+
+	host := result.Config.BuildOSTarget.String()
+	bin := result.ModuleForTests("bin", host+"_msan")
+	android.AssertStringDoesContain(t, "bin cflags", bin.Rule("cc").Args["cFlags"], "-fsanitize=memory")
+	result.ModuleForTests("libstatic", host+"_static_msan")
+}
@@ -0,0 +1,95 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cc
+
+import (
+	"strings"
+
+	"android/soong/android"
+)
+
+// Split DWARF (debug fission) moves most of the debug info of every object into a .dwo file next
+// to it, so that the linker only has to process the small skeleton left in the object.  After
+// linking, llvm-dwp packs the .dwo files referenced by the unstripped binary or shared library
+// into a single .dwp file next to it, which debuggers look up by name.  The .dwp of a device module
+// is copied to the symbols directory next to the unstripped copy made by Make, and the .dwp of a
+// host module is installed next to it.
+
+type SplitDwarfProperties struct {
+	// whether to compile with -gsplit-dwarf and pack the split debug info of the linked output into
+	// a .dwp file.  Defaults to the SplitDwarf product variable or SOONG_SPLIT_DWARF.
+	Split_dwarf *bool `android:"arch_variant"`
+}
+
+type splitDwarfFeature struct {
+	Properties SplitDwarfProperties
+}
+
+func (splitDwarf *splitDwarfFeature) props() []interface{} {
+	return []interface{}{&splitDwarf.Properties}
+}
+
+func (splitDwarf *splitDwarfFeature) begin(ctx BaseModuleContext) {
+}
+
+func (splitDwarf *splitDwarfFeature) deps(ctx DepsContext, deps Deps) Deps {
+	return deps
+}
+
+func (splitDwarf *splitDwarfFeature) flags(ctx ModuleContext, flags Flags) Flags {
+	if !BoolDefault(splitDwarf.Properties.Split_dwarf, ctx.Config().SplitDwarf()) {
+		return flags
+	}
+	// The .dwo files are only written for ELF objects.
+	if ctx.Darwin() || ctx.Windows() {
+		return flags
+	}
+	// With LTO the objects contain bitcode, and the debug info is only generated when linking.
+	for _, flag := range append(flags.Global.CFlags, flags.Local.CFlags...) {
+		if strings.HasPrefix(flag, "-flto") {
+			return flags
+		}
+	}
+
+	flags.SplitDwarf = true
+	flags.Local.CFlags = append(flags.Local.CFlags, "-gsplit-dwarf")
+	return flags
+}
+
+// InstallSplitDwarfPackage installs the .dwp file of a binary or shared library that is installed
+// with the given name to dir.  For device modules it returns the copy in the symbols directory,
+// which is not installed by Make and has to be added to the dependencies of the module.
+func InstallSplitDwarfPackage(ctx android.ModuleContext, dir android.InstallPath, name string,
+	dwp android.OptionalPath) android.OptionalPath {
+
+	if !dwp.Valid() {
+		return android.OptionalPath{}
+	}
+
+	if ctx.Host() {
+		ctx.InstallFile(dir, name+".dwp", dwp.Path())
+		return android.OptionalPath{}
+	}
+
+	symbolsFile := dir.ToSymbolsPath(ctx).Join(ctx, name+".dwp")
+	ctx.Build(pctx, android.BuildParams{
+		Rule:        android.Cp,
+		Description: "install symbols " + symbolsFile.Base(),
+		Output:      symbolsFile,
+		Input:       dwp.Path(),
+		Default:     !ctx.Config().KatiEnabled(),
+	})
+	return android.OptionalPathForPath(symbolsFile)
+}
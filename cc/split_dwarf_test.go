@@ -0,0 +1,93 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cc
+
+import (
+	"testing"
+
+	"android/soong/android"
+)
+
+func TestSplitDwarf(t *testing.T) {
+	bp := `
+		cc_library_shared {
+			name: "libfoo",
+			srcs: ["foo.cpp", "bar.S"],
+		}
+
+		cc_library_shared {
+			name: "libnosplit",
+			srcs: ["foo.cpp"],
+			split_dwarf: false,
+		}
+
+		cc_library_shared {
+			name: "liblto",
+			srcs: ["foo.cpp"],
+			lto: {
+				thin: true,
+			},
+		}
+	`
+	result := android.GroupFixturePreparers(
+		prepareForCcTest,
+		android.FixtureModifyProductVariables(func(variables android.FixtureProductVariables) {
+			variables.SplitDwarf = BoolPtr(true)
+		}),
+	).RunTestWithBp(t, bp)
+
+	const variant = "android_arm64_armv8-a_shared"
+	libfoo := result.ModuleForTests("libfoo", variant)
+
+	cpp := libfoo.Output("obj/foo.o")
+	android.AssertStringDoesContain(t, "foo.cpp cflags", cpp.Args["cFlags"], "-gsplit-dwarf")
+	android.AssertPathsRelativeToTopEquals(t, "foo.cpp implicit outputs",
+		[]string{"out/soong/.intermediates/libfoo/" + variant + "/obj/foo.dwo"}, cpp.ImplicitOutputs.Paths())
+
+	// Assembly sources don't write .dwo files.
+	asm := libfoo.Output("obj/bar.o")
+	android.AssertDeepEquals(t, "bar.S implicit outputs", 0, len(asm.ImplicitOutputs))
+
+	dwp := libfoo.Output("unstripped/libfoo.so.dwp")
+	android.AssertPathRelativeToTopEquals(t, "dwp input",
+		"out/soong/.intermediates/libfoo/"+variant+"/unstripped/libfoo.so", dwp.Input)
+
+	symbols := libfoo.Output("out/target/product/test_device/symbols/system/lib64/libfoo.so.dwp")
+	android.AssertPathRelativeToTopEquals(t, "symbols dwp input",
+		"out/soong/.intermediates/libfoo/"+variant+"/unstripped/libfoo.so.dwp", symbols.Input)
+
+	for _, name := range []string{"libnosplit", "liblto"} {
+		module := result.ModuleForTests(name, variant)
+		cFlags := module.Output("obj/foo.o").Args["cFlags"]
+		android.AssertStringDoesNotContain(t, name+" cflags", cFlags, "-gsplit-dwarf")
+		if module.MaybeOutput("unstripped/"+name+".so.dwp").Rule != nil {
+			t.Errorf("%s: unexpected .dwp file", name)
+		}
+	}
+}
+
+func TestSplitDwarfProperty(t *testing.T) {
+	ctx := testCc(t, `
+		cc_binary {
+			name: "foo",
+			srcs: ["foo.cpp"],
+			split_dwarf: true,
+		}
+	`)
+
+	foo := ctx.ModuleForTests("foo", "android_arm64_armv8-a")
+	android.AssertStringDoesContain(t, "foo.cpp cflags", foo.Output("obj/foo.o").Args["cFlags"], "-gsplit-dwarf")
+	foo.Output("unstripped/foo.dwp")
+}
@@ -0,0 +1,234 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cc
+
+import (
+	"github.com/google/blueprint"
+
+	"android/soong/android"
+)
+
+// The static analyzer runs clang --analyze over every C-like source of a module, with the same
+// flags that clang-tidy uses.  Calls between the sources of the module are followed with the cross
+// translation unit analysis: the AST of every source is dumped next to its object, and
+// clang-extdef-mapping lists the functions each source defines in an index that the analyzer uses
+// to find the AST defining a function.  The report of every source is written next to its object
+// as a .sarif or .plist file, and `m static-analyzer` collects the reports of all modules into
+// out/soong/static_analyzer.zip.
+
+var (
+	// Rule to dump the AST of a source for the analysis of the other sources of the module.
+	staticAnalyzerAst = pctx.AndroidStaticRule("staticAnalyzerAst",
+		blueprint.RuleParams{
+			Command:     "$ccCmd -emit-ast $cFlags -MD -MF ${out}.d -o $out $in",
+			CommandDeps: []string{"$ccCmd"},
+			Depfile:     "${out}.d",
+			Deps:        blueprint.DepsGCC,
+		},
+		"ccCmd", "cFlags")
+
+	// Rule to list the functions defined by a source, mapped to the AST dump of the source.
+	staticAnalyzerExtdefMap = pctx.AndroidStaticRule("staticAnalyzerExtdefMap",
+		blueprint.RuleParams{
+			Command:     "${config.ClangBin}/clang-extdef-mapping $in -- $cFlags | sed 's| [^ ]*$$| $ast|' > $out",
+			CommandDeps: []string{"${config.ClangBin}/clang-extdef-mapping"},
+		},
+		"cFlags", "ast")
+
+	// Rule to merge the function maps of the sources of a module into the index read by the
+	// analyzer.  Functions that are defined by more than one source, such as inline functions of
+	// headers, are dropped, as the analyzer rejects an index with duplicate entries.
+	staticAnalyzerIndex = pctx.AndroidStaticRule("staticAnalyzerIndex",
+		blueprint.RuleParams{
+			Command: "sort -u $in | " +
+				"awk '{ n[$$1]++; l[$$1] = $$0 } END { for (k in n) if (n[k] == 1) print l[k] }' | " +
+				"sort > $out",
+		})
+
+	// Rule for invoking the clang static analyzer.
+	staticAnalyzer = pctx.AndroidStaticRule("staticAnalyzer",
+		blueprint.RuleParams{
+			Command: "rm -f $out && $ccCmd --analyze --analyzer-output $format $cFlags " +
+				"-Xanalyzer -analyzer-config " +
+				"-Xanalyzer experimental-enable-naive-ctu-analysis=true,ctu-dir=.,ctu-index-name=$index " +
+				"$staticAnalyzerFlags -o $out $in",
+			CommandDeps: []string{"$ccCmd"},
+		},
+		"ccCmd", "cFlags", "format", "index", "staticAnalyzerFlags")
+)
+
+type StaticAnalyzerProperties struct {
+	// whether to run the clang static analyzer over C-like sources.  Defaults to
+	// WITH_STATIC_ANALYZER.
+	Static_analyzer *bool
+
+	// Extra flags to pass to clang when running the static analyzer, e.g.
+	// ["-Xanalyzer", "-analyzer-disable-checker=deadcode"].
+	Static_analyzer_flags []string
+}
+
+type staticAnalyzerFeature struct {
+	Properties StaticAnalyzerProperties
+}
+
+func (analyzer *staticAnalyzerFeature) props() []interface{} {
+	return []interface{}{&analyzer.Properties}
+}
+
+func (analyzer *staticAnalyzerFeature) begin(ctx BaseModuleContext) {
+}
+
+func (analyzer *staticAnalyzerFeature) deps(ctx DepsContext, deps Deps) Deps {
+	return deps
+}
+
+func (analyzer *staticAnalyzerFeature) flags(ctx ModuleContext, flags Flags) Flags {
+	if !BoolDefault(analyzer.Properties.Static_analyzer, ctx.Config().StaticAnalyzer()) {
+		return flags
+	}
+
+	format := ctx.Config().GetenvWithDefault("STATIC_ANALYZER_OUTPUT", "sarif")
+	if format != "sarif" && format != "plist" {
+		ctx.ModuleErrorf("STATIC_ANALYZER_OUTPUT must be sarif or plist, not %q", format)
+		return flags
+	}
+
+	flags.StaticAnalyzer = true
+	flags.StaticAnalyzerOutput = format
+	flags.StaticAnalyzerFlags = append(flags.StaticAnalyzerFlags,
+		checkNinjaAndShellEscapeList(ctx, "static_analyzer_flags", analyzer.Properties.Static_analyzer_flags)...)
+	return flags
+}
+
+// staticAnalyzerSource is a source to analyze, with the command and flags used to compile it.
+type staticAnalyzerSource struct {
+	src    android.Path
+	obj    android.Path
+	ccCmd  string
+	cFlags string
+}
+
+// transformSourcesToStaticAnalyzerReports generates the rules that run the static analyzer over the
+// given sources, and returns the reports.
+func transformSourcesToStaticAnalyzerReports(ctx android.ModuleContext, subdir string,
+	srcs []staticAnalyzerSource, flags builderFlags, pathDeps android.Paths,
+	cFlagsDeps android.Paths) android.Paths {
+
+	var asts, extdefMaps android.Paths
+	for _, s := range srcs {
+		ast := android.ObjPathWithExt(ctx, subdir, s.src, "ast")
+		ctx.Build(pctx, android.BuildParams{
+			Rule:        staticAnalyzerAst,
+			Description: "clang ast " + s.src.Rel(),
+			Output:      ast,
+			Input:       s.src,
+			Implicits:   cFlagsDeps,
+			OrderOnly:   pathDeps,
+			Args: map[string]string{
+				"ccCmd":  s.ccCmd,
+				"cFlags": s.cFlags,
+			},
+		})
+		asts = append(asts, ast)
+
+		extdefMap := android.ObjPathWithExt(ctx, subdir, s.src, "extdef")
+		ctx.Build(pctx, android.BuildParams{
+			Rule:        staticAnalyzerExtdefMap,
+			Description: "clang-extdef-mapping " + s.src.Rel(),
+			Output:      extdefMap,
+			Input:       s.src,
+			// Depend on the AST dump, since clang-extdef-mapping doesn't support exporting
+			// dependencies.
+			Implicit:  ast,
+			Implicits: cFlagsDeps,
+			OrderOnly: pathDeps,
+			Args: map[string]string{
+				"cFlags": s.cFlags,
+				"ast":    ast.String(),
+			},
+		})
+		extdefMaps = append(extdefMaps, extdefMap)
+	}
+
+	index := android.PathForModuleObj(ctx, subdir, "externalDefMap.txt")
+	ctx.Build(pctx, android.BuildParams{
+		Rule:        staticAnalyzerIndex,
+		Description: "static analyzer index",
+		Output:      index,
+		Inputs:      extdefMaps,
+	})
+
+	var reports android.Paths
+	for _, s := range srcs {
+		report := android.ObjPathWithExt(ctx, subdir, s.src, flags.staticAnalyzerOutput)
+		ctx.Build(pctx, android.BuildParams{
+			Rule:        staticAnalyzer,
+			Description: "clang static analyzer " + s.src.Rel(),
+			Output:      report,
+			Input:       s.src,
+			// We must depend on the object, since the analyzer doesn't support exporting
+			// dependencies.
+			Implicit:  s.obj,
+			Implicits: append(append(android.Paths{index}, asts...), cFlagsDeps...),
+			OrderOnly: pathDeps,
+			Args: map[string]string{
+				"ccCmd":               s.ccCmd,
+				"cFlags":              s.cFlags,
+				"format":              flags.staticAnalyzerOutput,
+				"index":               index.String(),
+				"staticAnalyzerFlags": flags.staticAnalyzerFlags,
+			},
+		})
+		reports = append(reports, report)
+	}
+	return reports
+}
+
+func staticAnalyzerSingleton() android.Singleton {
+	return &staticAnalyzerSingletonType{}
+}
+
+type staticAnalyzerSingletonType struct {
+	reports android.Path
+}
+
+// GenerateBuildActions collects the static analyzer reports of all modules into a single zip file.
+func (s *staticAnalyzerSingletonType) GenerateBuildActions(ctx android.SingletonContext) {
+	var reports android.Paths
+	ctx.VisitAllModules(func(module android.Module) {
+		if c, ok := module.(*Module); ok {
+			reports = append(reports, c.staticAnalyzerFiles...)
+		}
+	})
+	if len(reports) == 0 {
+		return
+	}
+
+	zipFile := android.PathForOutput(ctx, "static_analyzer.zip")
+	ctx.Build(pctx, android.BuildParams{
+		Rule:        zip,
+		Description: "zip static analyzer reports",
+		Output:      zipFile,
+		Inputs:      reports,
+	})
+	ctx.Phony("static-analyzer", zipFile)
+	s.reports = zipFile
+}
+
+func (s *staticAnalyzerSingletonType) MakeVars(ctx android.MakeVarsContext) {
+	if s.reports != nil {
+		ctx.DistForGoal("static-analyzer", s.reports)
+	}
+}
@@ -0,0 +1,102 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cc
+
+import (
+	"testing"
+
+	"android/soong/android"
+)
+
+func TestStaticAnalyzer(t *testing.T) {
+	ctx := testCc(t, `
+		cc_library_shared {
+			name: "libfoo",
+			srcs: ["foo.cpp", "bar.c", "start.S"],
+			cflags: ["-DFOO"],
+			static_analyzer: true,
+			static_analyzer_flags: ["-Xanalyzer", "-analyzer-disable-checker=deadcode"],
+		}
+
+		cc_library_shared {
+			name: "libqux",
+			srcs: ["qux.c"],
+		}
+	`)
+
+	const variant = "android_arm64_armv8-a_shared"
+	const objDir = "out/soong/.intermediates/libfoo/" + variant + "/obj/"
+	libfoo := ctx.ModuleForTests("libfoo", variant)
+
+	index := libfoo.Output("obj/externalDefMap.txt")
+	android.AssertPathsRelativeToTopEquals(t, "index inputs",
+		[]string{objDir + "foo.extdef", objDir + "bar.extdef"}, index.Inputs)
+
+	extdef := libfoo.Output("obj/foo.extdef")
+	android.AssertStringEquals(t, "extdef ast", objDir+"foo.ast", extdef.Args["ast"])
+
+	ast := libfoo.Output("obj/foo.ast")
+	android.AssertStringDoesContain(t, "ast cflags", ast.Args["cFlags"], "-DFOO")
+
+	report := libfoo.Output("obj/foo.sarif")
+	android.AssertStringDoesContain(t, "report cflags", report.Args["cFlags"], "-DFOO")
+	android.AssertStringEquals(t, "report format", "sarif", report.Args["format"])
+	android.AssertStringEquals(t, "report index", objDir+"externalDefMap.txt", report.Args["index"])
+	android.AssertStringEquals(t, "report flags",
+		"-Xanalyzer -analyzer-disable-checker=deadcode", report.Args["staticAnalyzerFlags"])
+	android.AssertStringListContains(t, "report implicits", report.Implicits.Strings(), objDir+"bar.ast")
+	android.AssertStringListContains(t, "link implicits", libfoo.Rule("ld").Implicits.Strings(), report.Output.String())
+
+	// Assembly sources are not analyzed.
+	if params := libfoo.MaybeOutput("obj/start.sarif"); params.Rule != nil {
+		t.Errorf("unexpected static analyzer report for start.S")
+	}
+
+	if params := ctx.ModuleForTests("libqux", variant).MaybeRule("staticAnalyzer"); params.Rule != nil {
+		t.Errorf("expected no static analyzer report for libqux")
+	}
+
+	zip := ctx.SingletonForTests("static_analyzer").Output("static_analyzer.zip")
+	android.AssertPathsRelativeToTopEquals(t, "zip inputs",
+		[]string{objDir + "foo.sarif", objDir + "bar.sarif"}, zip.Inputs)
+}
+
+func TestStaticAnalyzerPlist(t *testing.T) {
+	result := android.GroupFixturePreparers(
+		prepareForCcTest,
+		android.FixtureMergeEnv(map[string]string{
+			"WITH_STATIC_ANALYZER":   "true",
+			"STATIC_ANALYZER_OUTPUT": "plist",
+		}),
+	).RunTestWithBp(t, `
+		cc_library_shared {
+			name: "libfoo",
+			srcs: ["foo.cpp"],
+		}
+
+		cc_library_shared {
+			name: "libbar",
+			srcs: ["bar.cpp"],
+			static_analyzer: false,
+		}
+	`)
+
+	report := result.ModuleForTests("libfoo", "android_arm64_armv8-a_shared").Output("obj/foo.plist")
+	android.AssertStringEquals(t, "report format", "plist", report.Args["format"])
+
+	if params := result.ModuleForTests("libbar", "android_arm64_armv8-a_shared").MaybeRule("staticAnalyzer"); params.Rule != nil {
+		t.Errorf("expected no static analyzer report for libbar")
+	}
+}
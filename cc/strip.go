@@ -88,6 +88,12 @@ func (stripper *Stripper) StripExecutableOrSharedLib(actx android.ModuleContext,
 	stripper.strip(actx, in, out, flags, false)
 }
 
+// PackSplitDwarf packs the .dwo files of a binary or shared library that was compiled with
+// -gsplit-dwarf into a .dwp file, which is kept next to the unstripped output.
+func (stripper *Stripper) PackSplitDwarf(actx android.ModuleContext, in android.Path, out android.ModuleOutPath) {
+	transformBinaryToDwp(actx, in, out)
+}
+
 // StripStaticLib strips a static library from its debug symbols and other
 // debugging information. The helper function flagsToStripFlags may be used to
 // generate the flags argument.
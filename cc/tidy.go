@@ -18,12 +18,53 @@ import (
 	"regexp"
 	"strings"
 
+	"github.com/google/blueprint"
 	"github.com/google/blueprint/proptools"
 
 	"android/soong/android"
 	"android/soong/cc/config"
 )
 
+// A module with a tidy_baseline only fails when clang-tidy finds warnings that are not listed in
+// the baseline.  The warnings printed for every source are saved next to its object, and
+// tidy_baseline compares them with the baseline.  With TIDY_UPDATE_BASELINE=true, the warnings of
+// all the variants of a module are written to a new baseline instead, and `m tidy-baselines`
+// collects the new baselines into out/soong/tidy_baselines.zip, which can be extracted at the top
+// of the tree.
+//
+// With TIDY_EXPORT_FIXES=true, the fixes suggested by clang-tidy for every source are exported to
+// a YAML file next to its object, and `m tidy-fixes` collects them into
+// out/soong/tidy_fixes.zip, to be applied with clang-apply-replacements.
+
+func init() {
+	pctx.HostBinToolVariable("tidyBaselineCmd", "tidy_baseline")
+}
+
+var (
+	// Rule for invoking clang-tidy and saving the warnings it prints, to be compared with the
+	// baseline of the module.  The warnings are only printed if clang-tidy fails.
+	clangTidyLog = pctx.AndroidStaticRule("clangTidyLog",
+		blueprint.RuleParams{
+			Command: "rm -f $out && (${config.ClangBin}/clang-tidy $tidyFlags $in -- $cFlags > $out.tmp || " +
+				"(cat $out.tmp; rm -f $out.tmp; false)) && mv $out.tmp $out",
+			CommandDeps: []string{"${config.ClangBin}/clang-tidy"},
+		},
+		"cFlags", "tidyFlags")
+
+	tidyBaseline = pctx.AndroidStaticRule("tidyBaseline",
+		blueprint.RuleParams{
+			Command:     "$tidyBaselineCmd $tidyBaselineFlags -o $out $in",
+			CommandDeps: []string{"$tidyBaselineCmd"},
+		},
+		"tidyBaselineFlags")
+
+	mergeTidyBaselines = pctx.AndroidStaticRule("mergeTidyBaselines",
+		blueprint.RuleParams{
+			Command:     "$tidyBaselineCmd --merge -o $out $in",
+			CommandDeps: []string{"$tidyBaselineCmd"},
+		})
+)
+
 type TidyProperties struct {
 	// whether to run clang-tidy over C-like sources.
 	Tidy *bool
@@ -36,6 +77,10 @@ type TidyProperties struct {
 
 	// Checks that should be treated as errors.
 	Tidy_checks_as_errors []string
+
+	// File listing the clang-tidy warnings that the sources of this module already have.  Warnings
+	// that are not in the file are errors, and tidy_checks_as_errors is ignored.
+	Tidy_baseline *string `android:"path"`
 }
 
 type tidyFeature struct {
@@ -83,6 +128,10 @@ func (tidy *tidyFeature) flags(ctx ModuleContext, flags Flags) Flags {
 	}
 
 	flags.Tidy = true
+	flags.TidyExportFixes = ctx.Config().IsEnvTrue("TIDY_EXPORT_FIXES")
+	if tidy.Properties.Tidy_baseline != nil {
+		flags.TidyBaseline = android.PathForModuleSrc(ctx, *tidy.Properties.Tidy_baseline)
+	}
 
 	// Add global WITH_TIDY_FLAGS and local tidy_flags.
 	withTidyFlags := ctx.Config().Getenv("WITH_TIDY_FLAGS")
@@ -155,11 +204,13 @@ func (tidy *tidyFeature) flags(ctx ModuleContext, flags Flags) Flags {
 	tidyChecks = tidyChecks + ",-bugprone-branch-clone"
 	flags.TidyFlags = append(flags.TidyFlags, tidyChecks)
 
-	if ctx.Config().IsEnvTrue("WITH_TIDY") {
+	if ctx.Config().IsEnvTrue("WITH_TIDY") || flags.TidyBaseline != nil {
 		// WITH_TIDY=1 enables clang-tidy globally. There could be many unexpected
 		// warnings from new checks and many local tidy_checks_as_errors and
 		// -warnings-as-errors can break a global build.
 		// So allow all clang-tidy warnings.
+		// With a baseline, the warnings are compared with the baseline after
+		// clang-tidy has run, so clang-tidy must not fail on them either.
 		inserted := false
 		for i, s := range flags.TidyFlags {
 			if strings.Contains(s, "-warnings-as-errors=") {
@@ -185,3 +236,107 @@ func (tidy *tidyFeature) flags(ctx ModuleContext, flags Flags) Flags {
 	}
 	return flags
 }
+
+// transformTidyLogsToBaselineCheck generates the rule that compares the warnings saved by
+// clang-tidy for the sources of a module with the baseline of the module.  It returns the stamp
+// file of the check, or with TIDY_UPDATE_BASELINE=true, the new baseline and no stamp file.
+func transformTidyLogsToBaselineCheck(ctx android.ModuleContext, subdir string, tidyFiles android.Paths,
+	flags builderFlags) (stamp android.Path, newBaseline android.Path) {
+
+	if ctx.Config().IsEnvTrue("TIDY_UPDATE_BASELINE") {
+		out := android.PathForModuleObj(ctx, subdir, "tidy_baseline.txt")
+		ctx.Build(pctx, android.BuildParams{
+			Rule:        tidyBaseline,
+			Description: "update tidy baseline",
+			Output:      out,
+			Inputs:      tidyFiles,
+			Args: map[string]string{
+				"tidyBaselineFlags": "--update",
+			},
+		})
+		return nil, out
+	}
+
+	out := android.PathForModuleObj(ctx, subdir, "tidy_baseline.stamp")
+	ctx.Build(pctx, android.BuildParams{
+		Rule:        tidyBaseline,
+		Description: "check tidy baseline",
+		Output:      out,
+		Inputs:      tidyFiles,
+		Implicit:    flags.tidyBaseline,
+		Args: map[string]string{
+			"tidyBaselineFlags": "--baseline " + flags.tidyBaseline.String() + " --module " + ctx.ModuleName(),
+		},
+	})
+	return out, nil
+}
+
+func tidySingleton() android.Singleton {
+	return &tidySingletonType{}
+}
+
+type tidySingletonType struct {
+	fixes     android.Path
+	baselines android.Path
+}
+
+// GenerateBuildActions collects the fixes exported by clang-tidy into a single zip file, and
+// merges the new baselines of the variants of every module.
+func (t *tidySingletonType) GenerateBuildActions(ctx android.SingletonContext) {
+	var fixes android.Paths
+	newBaselines := make(map[string]android.Paths)
+	ctx.VisitAllModules(func(module android.Module) {
+		c, ok := module.(*Module)
+		if !ok {
+			return
+		}
+		fixes = append(fixes, c.tidyFixFiles...)
+		if len(c.tidyBaselineFiles) > 0 {
+			baseline := c.flags.TidyBaseline.String()
+			newBaselines[baseline] = append(newBaselines[baseline], c.tidyBaselineFiles...)
+		}
+	})
+
+	if len(fixes) > 0 {
+		zipFile := android.PathForOutput(ctx, "tidy_fixes.zip")
+		ctx.Build(pctx, android.BuildParams{
+			Rule:        zip,
+			Description: "zip tidy fixes",
+			Output:      zipFile,
+			Inputs:      fixes,
+		})
+		ctx.Phony("tidy-fixes", zipFile)
+		t.fixes = zipFile
+	}
+
+	if len(newBaselines) > 0 {
+		dir := android.PathForOutput(ctx, "tidy_baselines")
+		zipFile := android.PathForOutput(ctx, "tidy_baselines.zip")
+		rule := android.NewRuleBuilder(pctx, ctx)
+		cmd := rule.Command().BuiltTool("soong_zip").
+			FlagWithOutput("-o ", zipFile).
+			FlagWithArg("-C ", dir.String())
+		for _, baseline := range android.SortedStringKeys(newBaselines) {
+			merged := dir.Join(ctx, baseline)
+			ctx.Build(pctx, android.BuildParams{
+				Rule:        mergeTidyBaselines,
+				Description: "merge tidy baselines " + baseline,
+				Output:      merged,
+				Inputs:      newBaselines[baseline],
+			})
+			cmd.FlagWithInput("-f ", merged)
+		}
+		rule.Build("tidy_baselines", "zip tidy baselines")
+		ctx.Phony("tidy-baselines", zipFile)
+		t.baselines = zipFile
+	}
+}
+
+func (t *tidySingletonType) MakeVars(ctx android.MakeVarsContext) {
+	if t.fixes != nil {
+		ctx.DistForGoal("tidy-fixes", t.fixes)
+	}
+	if t.baselines != nil {
+		ctx.DistForGoal("tidy-baselines", t.baselines)
+	}
+}
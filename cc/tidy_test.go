@@ -0,0 +1,141 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cc
+
+import (
+	"testing"
+
+	"github.com/google/blueprint/proptools"
+
+	"android/soong/android"
+)
+
+const tidyBaselineBp = `
+	cc_library_shared {
+		name: "libfoo",
+		srcs: ["foo.cpp", "bar.cpp"],
+		tidy: true,
+		tidy_checks_as_errors: ["modernize-*"],
+		tidy_baseline: "tidy_baseline.txt",
+	}
+`
+
+func TestTidyBaseline(t *testing.T) {
+	result := android.GroupFixturePreparers(
+		prepareForCcTest,
+		android.FixtureAddFile("tidy_baseline.txt", nil),
+	).RunTestWithBp(t, tidyBaselineBp)
+
+	const objDir = "out/soong/.intermediates/libfoo/android_arm64_armv8-a_shared/obj/"
+	libfoo := result.ModuleForTests("libfoo", "android_arm64_armv8-a_shared")
+
+	tidy := libfoo.Rule("clangTidyLog")
+	android.AssertStringDoesContain(t, "tidy flags", tidy.Args["tidyFlags"], "-warnings-as-errors=-*")
+	android.AssertStringDoesNotContain(t, "tidy flags", tidy.Args["tidyFlags"], "-warnings-as-errors=modernize-*")
+
+	check := libfoo.Output("obj/tidy_baseline.stamp")
+	android.AssertPathsRelativeToTopEquals(t, "check inputs",
+		[]string{objDir + "foo.tidy", objDir + "bar.tidy"}, check.Inputs)
+	android.AssertStringEquals(t, "check flags",
+		"--baseline tidy_baseline.txt --module libfoo", check.Args["tidyBaselineFlags"])
+	android.AssertStringListContains(t, "link implicits", libfoo.Rule("ld").Implicits.Strings(), check.Output.String())
+
+	if params := result.SingletonForTests("tidy").MaybeOutput("tidy_baselines.zip"); params.Rule != nil {
+		t.Errorf("unexpected tidy baselines without TIDY_UPDATE_BASELINE")
+	}
+}
+
+func TestTidyUpdateBaseline(t *testing.T) {
+	result := android.GroupFixturePreparers(
+		prepareForCcTest,
+		android.FixtureAddFile("tidy_baseline.txt", nil),
+		android.FixtureMergeEnv(map[string]string{"TIDY_UPDATE_BASELINE": "true"}),
+	).RunTestWithBp(t, tidyBaselineBp)
+
+	libfoo := result.ModuleForTests("libfoo", "android_arm64_armv8-a_shared")
+	if params := libfoo.MaybeOutput("obj/tidy_baseline.stamp"); params.Rule != nil {
+		t.Errorf("unexpected tidy baseline check with TIDY_UPDATE_BASELINE")
+	}
+	newBaseline := libfoo.Output("obj/tidy_baseline.txt")
+	android.AssertStringEquals(t, "update flags", "--update", newBaseline.Args["tidyBaselineFlags"])
+
+	tidy := result.SingletonForTests("tidy")
+	merged := tidy.Output("tidy_baselines/tidy_baseline.txt")
+	android.AssertStringListContains(t, "merged inputs", merged.Inputs.Strings(), newBaseline.Output.String())
+	tidy.Output("tidy_baselines.zip")
+}
+
+func TestTidyExportFixes(t *testing.T) {
+	result := android.GroupFixturePreparers(
+		prepareForCcTest,
+		android.FixtureMergeEnv(map[string]string{"TIDY_EXPORT_FIXES": "true"}),
+	).RunTestWithBp(t, `
+		cc_library_shared {
+			name: "libfoo",
+			srcs: ["foo.cpp"],
+			tidy: true,
+		}
+	`)
+
+	const fixes = "out/soong/.intermediates/libfoo/android_arm64_armv8-a_shared/obj/foo.tidy.yaml"
+	tidy := result.ModuleForTests("libfoo", "android_arm64_armv8-a_shared").Output("obj/foo.tidy")
+	android.AssertPathRelativeToTopEquals(t, "tidy implicit output", fixes, tidy.ImplicitOutput)
+	android.AssertStringDoesContain(t, "tidy flags", tidy.Args["tidyFlags"], "-export-fixes="+fixes)
+
+	zip := result.SingletonForTests("tidy").Output("tidy_fixes.zip")
+	android.AssertStringListContains(t, "zip inputs", zip.Inputs.Strings(), fixes)
+}
+
+func TestTidyExportFixesRBE(t *testing.T) {
+	bp := `
+		cc_library_shared {
+			name: "libfoo",
+			srcs: ["foo.cpp"],
+			tidy: true,
+		}
+	`
+	prepareForRBE := android.GroupFixturePreparers(
+		prepareForCcTest,
+		android.FixtureMergeEnv(map[string]string{
+			"RBE_CLANG_TIDY": "true",
+		}),
+		android.FixtureModifyProductVariables(func(variables android.FixtureProductVariables) {
+			variables.UseRBE = proptools.BoolPtr(true)
+		}),
+	)
+
+	result := android.GroupFixturePreparers(
+		prepareForRBE,
+		android.FixtureMergeEnv(map[string]string{
+			"TIDY_EXPORT_FIXES": "true",
+		}),
+	).RunTestWithBp(t, bp)
+
+	const fixes = "out/soong/.intermediates/libfoo/android_arm64_armv8-a_shared/obj/foo.tidy.yaml"
+	tidy := result.ModuleForTests("libfoo", "android_arm64_armv8-a_shared").Rule("clangTidyFixesRE")
+	android.AssertPathRelativeToTopEquals(t, "tidy implicit output", fixes, tidy.ImplicitOutput)
+	android.AssertStringEquals(t, "remote output", fixes,
+		android.StringRelativeToTop(result.Config, tidy.Args["tidyFixFile"]))
+
+	// Without -export-fixes the remote outputs are unchanged.
+	result = prepareForRBE.RunTestWithBp(t, bp)
+	tidy = result.ModuleForTests("libfoo", "android_arm64_armv8-a_shared").Rule("clangTidyRE")
+	if tidy.ImplicitOutput != nil {
+		t.Errorf("unexpected tidy implicit output %s", tidy.ImplicitOutput)
+	}
+	if _, ok := tidy.Args["tidyFixFile"]; ok {
+		t.Errorf("unexpected tidyFixFile argument %q", tidy.Args["tidyFixFile"])
+	}
+}
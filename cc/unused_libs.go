@@ -0,0 +1,154 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cc
+
+import (
+	"strings"
+
+	"github.com/google/blueprint"
+
+	"android/soong/android"
+)
+
+// When CheckUnusedLibs is enabled for the product, the unstripped output of every binary and
+// shared library is compared with the shared_libs and static_libs listed in its Android.bp file.
+// A shared library that defines none of the undefined dynamic symbols of the output, or a static
+// library none of whose symbols were linked into the output, is reported.  The check of each
+// module prints its unused libraries as warnings, and `m unused-libs-check` merges the results of
+// all modules into out/soong/unused_libs.json.
+
+func init() {
+	pctx.HostBinToolVariable("unusedLibsCmd", "unused_libs")
+}
+
+var (
+	unusedLibs = pctx.AndroidStaticRule("unusedLibs",
+		blueprint.RuleParams{
+			Command:     "$unusedLibsCmd --nm ${config.ClangBin}/llvm-nm --module $module --bp $bp $libs -o $out $in",
+			CommandDeps: []string{"$unusedLibsCmd", "${config.ClangBin}/llvm-nm"},
+		},
+		"module", "bp", "libs")
+
+	mergeUnusedLibsReports = pctx.AndroidStaticRule("mergeUnusedLibsReports",
+		blueprint.RuleParams{
+			Command:        "$unusedLibsCmd --merge -o $out @$out.rsp",
+			CommandDeps:    []string{"$unusedLibsCmd"},
+			Rspfile:        "$out.rsp",
+			RspfileContent: "$in",
+		})
+)
+
+// checkUnusedLibs creates the rule that checks the linked output of a binary or shared library for
+// unused shared_libs and static_libs, and returns its report.
+func checkUnusedLibs(ctx ModuleContext, c *Module) android.OptionalPath {
+	// Prebuilts and stubs don't list the libraries they are linked against.
+	if c.compiler == nil || c.IsStubs() || !(c.Binary() || c.Shared()) {
+		return android.OptionalPath{}
+	}
+	linked := c.UnstrippedOutputFile()
+	if linked == nil {
+		return android.OptionalPath{}
+	}
+
+	// Only the libraries listed in the Android.bp file are checked, not the ones added implicitly
+	// such as the system_shared_libs, the STL or the sanitizer runtimes.
+	specified := c.linker.linkerSpecifiedDeps(specifiedDeps{})
+
+	var libs []string
+	var implicits android.Paths
+	seen := make(map[string]bool)
+	ctx.VisitDirectDeps(func(dep android.Module) {
+		tag, ok := ctx.OtherModuleDependencyTag(dep).(libraryDependencyTag)
+		if !ok {
+			return
+		}
+		name := android.RemoveOptionalPrebuiltPrefix(ctx.OtherModuleName(dep))
+
+		var kind string
+		var lib android.Path
+		if tag.shared() && inList(name, specified.sharedLibs) &&
+			ctx.OtherModuleHasProvider(dep, SharedLibraryInfoProvider) {
+			kind = "shared"
+			lib = ctx.OtherModuleProvider(dep, SharedLibraryInfoProvider).(SharedLibraryInfo).SharedLibrary
+		} else if tag.static() && !tag.wholeStatic && inList(name, specified.staticLibs) &&
+			ctx.OtherModuleHasProvider(dep, StaticLibraryInfoProvider) {
+			kind = "static"
+			lib = ctx.OtherModuleProvider(dep, StaticLibraryInfoProvider).(StaticLibraryInfo).StaticLibrary
+		}
+		if lib == nil || seen[kind+name] {
+			return
+		}
+		seen[kind+name] = true
+		libs = append(libs, "--"+kind+" "+name+"="+lib.String())
+		implicits = append(implicits, lib)
+	})
+	if len(libs) == 0 {
+		return android.OptionalPath{}
+	}
+
+	report := android.PathForModuleOut(ctx, "unused_libs.json")
+	ctx.Build(pctx, android.BuildParams{
+		Rule:        unusedLibs,
+		Description: "check unused libs " + linked.Base(),
+		Output:      report,
+		Input:       linked,
+		Implicits:   implicits,
+		Args: map[string]string{
+			"module": ctx.ModuleName(),
+			"bp":     ctx.BlueprintsFile(),
+			"libs":   strings.Join(libs, " "),
+		},
+	})
+	return android.OptionalPathForPath(report)
+}
+
+func unusedLibsSingleton() android.Singleton {
+	return &unusedLibsSingletonType{}
+}
+
+type unusedLibsSingletonType struct {
+	report android.Path
+}
+
+// GenerateBuildActions merges the reports of all modules into a single report that lists the
+// modules with unused libraries.
+func (u *unusedLibsSingletonType) GenerateBuildActions(ctx android.SingletonContext) {
+	if !ctx.Config().CheckUnusedLibs() {
+		return
+	}
+
+	var reports android.Paths
+	ctx.VisitAllModules(func(module android.Module) {
+		if c, ok := module.(*Module); ok && c.unusedLibsReport.Valid() {
+			reports = append(reports, c.unusedLibsReport.Path())
+		}
+	})
+
+	report := android.PathForOutput(ctx, "unused_libs.json")
+	ctx.Build(pctx, android.BuildParams{
+		Rule:        mergeUnusedLibsReports,
+		Description: "merge unused libs reports",
+		Output:      report,
+		Inputs:      reports,
+	})
+	ctx.Phony("unused-libs-check", report)
+	u.report = report
+}
+
+func (u *unusedLibsSingletonType) MakeVars(ctx android.MakeVarsContext) {
+	if u.report != nil {
+		ctx.DistForGoal("unused-libs-check", u.report)
+	}
+}
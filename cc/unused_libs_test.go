@@ -0,0 +1,86 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cc
+
+import (
+	"testing"
+
+	"android/soong/android"
+)
+
+const unusedLibsBp = `
+	cc_library_shared {
+		name: "libshared",
+		srcs: ["shared.c"],
+	}
+
+	cc_library_static {
+		name: "libstatic",
+		srcs: ["static.c"],
+	}
+
+	cc_library_static {
+		name: "libwhole",
+		srcs: ["whole.c"],
+	}
+
+	cc_binary {
+		name: "bin",
+		srcs: ["bin.c"],
+		shared_libs: ["libshared"],
+		static_libs: ["libstatic"],
+		whole_static_libs: ["libwhole"],
+	}
+`
+
+func TestUnusedLibs(t *testing.T) {
+	result := android.GroupFixturePreparers(
+		prepareForCcTest,
+		android.FixtureModifyProductVariables(func(variables android.FixtureProductVariables) {
+			variables.CheckUnusedLibs = BoolPtr(true)
+		}),
+	).RunTestWithBp(t, unusedLibsBp)
+
+	bin := result.ModuleForTests("bin", "android_arm64_armv8-a")
+	check := bin.Rule("unusedLibs")
+	android.AssertPathRelativeToTopEquals(t, "input",
+		"out/soong/.intermediates/bin/android_arm64_armv8-a/unstripped/bin", check.Input)
+
+	// Only the libraries listed in shared_libs and static_libs are checked, not libc or
+	// whole_static_libs.
+	android.AssertStringEquals(t, "libs",
+		"--shared libshared=out/soong/.intermediates/libshared/android_arm64_armv8-a_shared/libshared.so "+
+			"--static libstatic=out/soong/.intermediates/libstatic/android_arm64_armv8-a_static/libstatic.a",
+		android.StringRelativeToTop(result.Config, check.Args["libs"]))
+
+	// The static library has nothing to link.
+	libstatic := result.ModuleForTests("libstatic", "android_arm64_armv8-a_static")
+	if params := libstatic.MaybeRule("unusedLibs"); params.Rule != nil {
+		t.Errorf("unexpected check of a static library")
+	}
+
+	merge := result.SingletonForTests("unused_libs").Rule("mergeUnusedLibsReports")
+	android.AssertPathRelativeToTopEquals(t, "report", "out/soong/unused_libs.json", merge.Output)
+	android.AssertPathsRelativeToTopEquals(t, "reports",
+		[]string{"out/soong/.intermediates/bin/android_arm64_armv8-a/unused_libs.json"}, merge.Inputs)
+}
+
+func TestUnusedLibsDisabled(t *testing.T) {
+	ctx := testCc(t, unusedLibsBp)
+
+	if params := ctx.ModuleForTests("bin", "android_arm64_armv8-a").MaybeRule("unusedLibs"); params.Rule != nil {
+		t.Errorf("unexpected check when CheckUnusedLibs is not set")
+	}
+}
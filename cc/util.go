@@ -75,20 +75,31 @@ func flagsToBuilderFlags(in Flags) builderFlags {
 		localCppFlags:        strings.Join(in.Local.CppFlags, " "),
 		localLdFlags:         strings.Join(in.Local.LdFlags, " "),
 
-		aidlFlags:     strings.Join(in.aidlFlags, " "),
-		rsFlags:       strings.Join(in.rsFlags, " "),
-		libFlags:      strings.Join(in.libFlags, " "),
-		extraLibFlags: strings.Join(in.extraLibFlags, " "),
-		tidyFlags:     strings.Join(in.TidyFlags, " "),
-		sAbiFlags:     strings.Join(in.SAbiFlags, " "),
-		toolchain:     in.Toolchain,
-		gcovCoverage:  in.GcovCoverage,
-		tidy:          in.Tidy,
-		sAbiDump:      in.SAbiDump,
-		emitXrefs:     in.EmitXrefs,
+		aidlFlags:            strings.Join(in.aidlFlags, " "),
+		rsFlags:              strings.Join(in.rsFlags, " "),
+		libFlags:             strings.Join(in.libFlags, " "),
+		extraLibFlags:        strings.Join(in.extraLibFlags, " "),
+		tidyFlags:            strings.Join(in.TidyFlags, " "),
+		layeringCheckFlags:   strings.Join(in.LayeringCheckFlags, " "),
+		staticAnalyzerFlags:  strings.Join(in.StaticAnalyzerFlags, " "),
+		staticAnalyzerOutput: in.StaticAnalyzerOutput,
+		sAbiFlags:            strings.Join(in.SAbiFlags, " "),
+		toolchain:            in.Toolchain,
+		gcovCoverage:         in.GcovCoverage,
+		tidy:                 in.Tidy,
+		tidyExportFixes:      in.TidyExportFixes,
+		tidyBaseline:         in.TidyBaseline,
+		layeringCheck:        in.LayeringCheck,
+		staticAnalyzer:       in.StaticAnalyzer,
+		sAbiDump:             in.SAbiDump,
+		emitXrefs:            in.EmitXrefs,
+		splitDwarf:           in.SplitDwarf,
 
 		systemIncludeFlags: strings.Join(in.SystemIncludeFlags, " "),
 
+		pchHeader: in.PchHeader,
+		pch:       in.Pch,
+
 		assemblerWithCpp: in.AssemblerWithCpp,
 		groupStaticLibs:  in.GroupStaticLibs,
 
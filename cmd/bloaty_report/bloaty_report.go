@@ -0,0 +1,360 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// bloaty_report builds the size report of a native binary or library from the CSV files written by
+// bloaty for its sections, symbols and compile units.  With --merge it combines the reports of all
+// modules into a single report, and with --diff it compares two merged reports and fails if a
+// module grew by more than the thresholds.
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	module       = flag.String("module", "", "name of the module that is measured")
+	variant      = flag.String("variant", "", "variant of the module that is measured")
+	file         = flag.String("file", "", "path of the file that is measured")
+	sections     = flag.String("sections", "", "CSV file written by bloaty -d sections")
+	symbols      = flag.String("symbols", "", "CSV file written by bloaty -d symbols")
+	compileUnits = flag.String("compileunits", "", "CSV file written by bloaty -d compileunits")
+	output       = flag.String("o", "", "report or diff to write")
+
+	merge    = flag.Bool("merge", false, "merge the module reports listed in the file passed with -l")
+	list     = flag.String("l", "", "file containing a space separated list of module reports to merge")
+	diff     = flag.Bool("diff", false, "compare the two merged reports passed as arguments")
+	minBytes = flag.Uint64("threshold_bytes", 4096,
+		"minimum growth in bytes of a module for it to be reported as a regression")
+	minPercent = flag.Float64("threshold_percent", 1,
+		"minimum growth in percent of a module for it to be reported as a regression")
+)
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: bloaty_report --module <name> --variant <variant> --file <file> "+
+		"--sections <csv> --symbols <csv> --compileunits <csv> -o <report>\n")
+	fmt.Fprintf(os.Stderr, "       bloaty_report --merge -l <list> -o <report>\n")
+	fmt.Fprintf(os.Stderr, "       bloaty_report --diff [--threshold_bytes <n>] [--threshold_percent <p>] "+
+		"[-o <diff>] <old report> <new report>\n")
+	flag.PrintDefaults()
+	os.Exit(2)
+}
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+
+	var err error
+	switch {
+	case *merge:
+		if *list == "" || *output == "" {
+			usage()
+		}
+		err = runMerge()
+	case *diff:
+		if flag.NArg() != 2 {
+			usage()
+		}
+		err = runDiff(flag.Arg(0), flag.Arg(1))
+	default:
+		if *module == "" || *sections == "" || *output == "" {
+			usage()
+		}
+		err = runReport()
+	}
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "bloaty_report:", err)
+		os.Exit(1)
+	}
+}
+
+// sizeEntry is the size of a section, symbol or compile unit.
+type sizeEntry struct {
+	Name     string `json:"name"`
+	VMSize   uint64 `json:"vm_size"`
+	FileSize uint64 `json:"file_size"`
+}
+
+// moduleReport is the size report of the file installed by a module.
+type moduleReport struct {
+	Module       string      `json:"module"`
+	Variant      string      `json:"variant"`
+	File         string      `json:"file"`
+	VMSize       uint64      `json:"vm_size"`
+	FileSize     uint64      `json:"file_size"`
+	Sections     []sizeEntry `json:"sections"`
+	Symbols      []sizeEntry `json:"symbols,omitempty"`
+	CompileUnits []sizeEntry `json:"compile_units,omitempty"`
+}
+
+func (r *moduleReport) key() string {
+	return r.Module + " (" + r.Variant + ")"
+}
+
+// report is the merged size report of all modules.
+type report struct {
+	Modules []moduleReport `json:"modules"`
+}
+
+func runReport() error {
+	r := moduleReport{Module: *module, Variant: *variant, File: *file}
+
+	var err error
+	if r.Sections, err = readCSVFile(*sections); err != nil {
+		return err
+	}
+	for _, s := range r.Sections {
+		r.VMSize += s.VMSize
+		r.FileSize += s.FileSize
+	}
+	if *symbols != "" {
+		if r.Symbols, err = readCSVFile(*symbols); err != nil {
+			return err
+		}
+	}
+	if *compileUnits != "" {
+		if r.CompileUnits, err = readCSVFile(*compileUnits); err != nil {
+			return err
+		}
+	}
+
+	return writeJSON(*output, r)
+}
+
+func runMerge() error {
+	b, err := ioutil.ReadFile(*list)
+	if err != nil {
+		return err
+	}
+
+	merged := report{Modules: []moduleReport{}}
+	for _, path := range strings.Fields(string(b)) {
+		var r moduleReport
+		if err := readJSON(path, &r); err != nil {
+			return err
+		}
+		merged.Modules = append(merged.Modules, r)
+	}
+	sort.Slice(merged.Modules, func(i, j int) bool {
+		return merged.Modules[i].key() < merged.Modules[j].key()
+	})
+
+	return writeJSON(*output, merged)
+}
+
+func runDiff(oldFile, newFile string) error {
+	var oldReport, newReport report
+	if err := readJSON(oldFile, &oldReport); err != nil {
+		return err
+	}
+	if err := readJSON(newFile, &newReport); err != nil {
+		return err
+	}
+
+	text, regressions := diffReports(oldReport, newReport, *minBytes, *minPercent)
+	if *output != "" {
+		if err := ioutil.WriteFile(*output, []byte(text), 0666); err != nil {
+			return err
+		}
+	} else {
+		fmt.Print(text)
+	}
+
+	if len(regressions) > 0 {
+		return fmt.Errorf("%d modules grew by more than %d bytes and %g%%:\n  %s\n"+
+			"Check the size report diff for the sections and symbols that grew.",
+			len(regressions), *minBytes, *minPercent, strings.Join(regressions, "\n  "))
+	}
+	return nil
+}
+
+// readCSV reads the sizes written by bloaty --csv, whose first column is the name of the section,
+// symbol or compile unit, followed by the vmsize and filesize columns.
+func readCSV(r io.Reader) ([]sizeEntry, error) {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("missing CSV header")
+	}
+
+	vmColumn, fileColumn := -1, -1
+	for i, column := range records[0] {
+		switch column {
+		case "vmsize":
+			vmColumn = i
+		case "filesize":
+			fileColumn = i
+		}
+	}
+	if vmColumn < 1 || fileColumn < 1 {
+		return nil, fmt.Errorf("unexpected CSV header %q", records[0])
+	}
+
+	entries := make([]sizeEntry, 0, len(records)-1)
+	for _, record := range records[1:] {
+		vmSize, err := strconv.ParseUint(record[vmColumn], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		fileSize, err := strconv.ParseUint(record[fileColumn], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, sizeEntry{Name: record[0], VMSize: vmSize, FileSize: fileSize})
+	}
+	return entries, nil
+}
+
+func readCSVFile(path string) ([]sizeEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	entries, err := readCSV(f)
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %w", path, err)
+	}
+	return entries, nil
+}
+
+func readJSON(path string, v interface{}) error {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return fmt.Errorf("error reading %s: %w", path, err)
+	}
+	return nil
+}
+
+func writeJSON(path string, v interface{}) error {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(b, '\n'), 0666)
+}
+
+// maxDiffEntries is the number of sections and symbols listed for a module that grew.
+const maxDiffEntries = 10
+
+// diffReports returns a description of the modules that changed size between two reports, and the
+// modules that grew by more than both thresholds.
+func diffReports(oldReport, newReport report, minBytes uint64, minPercent float64) (string, []string) {
+	oldModules := make(map[string]*moduleReport)
+	for i := range oldReport.Modules {
+		oldModules[oldReport.Modules[i].key()] = &oldReport.Modules[i]
+	}
+
+	var sb strings.Builder
+	var regressions, added []string
+	for i := range newReport.Modules {
+		n := &newReport.Modules[i]
+		o, ok := oldModules[n.key()]
+		if !ok {
+			added = append(added, n.key())
+			continue
+		}
+		delete(oldModules, n.key())
+
+		if n.FileSize == o.FileSize {
+			continue
+		}
+		delta := int64(n.FileSize) - int64(o.FileSize)
+		percent := 100 * float64(delta) / float64(o.FileSize)
+
+		regression := delta > 0 && uint64(delta) > minBytes && percent > minPercent
+		marker := ""
+		if regression {
+			regressions = append(regressions, n.key())
+			marker = " REGRESSION"
+		}
+		fmt.Fprintf(&sb, "%s: %d -> %d bytes (%+d, %+.1f%%)%s\n",
+			n.key(), o.FileSize, n.FileSize, delta, percent, marker)
+
+		if regression {
+			writeEntryDiffs(&sb, "sections", o.Sections, n.Sections)
+			writeEntryDiffs(&sb, "symbols", o.Symbols, n.Symbols)
+			writeEntryDiffs(&sb, "compile units", o.CompileUnits, n.CompileUnits)
+		}
+	}
+
+	var removed []string
+	for key := range oldModules {
+		removed = append(removed, key)
+	}
+	sort.Strings(removed)
+
+	for _, key := range added {
+		fmt.Fprintf(&sb, "%s: added\n", key)
+	}
+	for _, key := range removed {
+		fmt.Fprintf(&sb, "%s: removed\n", key)
+	}
+
+	return sb.String(), regressions
+}
+
+// writeEntryDiffs lists the sections, symbols or compile units whose size changed the most.
+func writeEntryDiffs(w io.Writer, kind string, oldEntries, newEntries []sizeEntry) {
+	type entryDiff struct {
+		name  string
+		delta int64
+	}
+
+	sizes := make(map[string]int64)
+	for _, e := range oldEntries {
+		sizes[e.Name] -= int64(e.FileSize)
+	}
+	for _, e := range newEntries {
+		sizes[e.Name] += int64(e.FileSize)
+	}
+
+	var diffs []entryDiff
+	for name, delta := range sizes {
+		if delta != 0 {
+			diffs = append(diffs, entryDiff{name, delta})
+		}
+	}
+	if len(diffs) == 0 {
+		return
+	}
+	sort.Slice(diffs, func(i, j int) bool {
+		if diffs[i].delta != diffs[j].delta {
+			return diffs[i].delta > diffs[j].delta
+		}
+		return diffs[i].name < diffs[j].name
+	})
+	if len(diffs) > maxDiffEntries {
+		diffs = diffs[:maxDiffEntries]
+	}
+
+	fmt.Fprintf(w, "  %s:\n", kind)
+	for _, d := range diffs {
+		fmt.Fprintf(w, "    %+d %s\n", d.delta, d.name)
+	}
+}
@@ -0,0 +1,116 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadCSV(t *testing.T) {
+	input := `symbols,vmsize,filesize
+foo(),1024,1024
+[section .rodata],512,600
+"bar(int, int)",16,16
+`
+	got, err := readCSV(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []sizeEntry{
+		{Name: "foo()", VMSize: 1024, FileSize: 1024},
+		{Name: "[section .rodata]", VMSize: 512, FileSize: 600},
+		{Name: "bar(int, int)", VMSize: 16, FileSize: 16},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestReadCSVErrors(t *testing.T) {
+	testCases := []struct {
+		name  string
+		input string
+	}{
+		{"empty", ""},
+		{"missing columns", "sections,size\n.text,12\n"},
+		{"invalid size", "sections,vmsize,filesize\n.text,12,twelve\n"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := readCSV(strings.NewReader(tc.input)); err == nil {
+				t.Errorf("expected an error")
+			}
+		})
+	}
+}
+
+func testModule(name string, size uint64, sections ...sizeEntry) moduleReport {
+	return moduleReport{
+		Module:   name,
+		Variant:  "android_arm64_armv8-a_shared",
+		FileSize: size,
+		Sections: sections,
+	}
+}
+
+func TestDiffReports(t *testing.T) {
+	oldReport := report{Modules: []moduleReport{
+		testModule("libbig", 100000, sizeEntry{Name: ".text", FileSize: 90000}, sizeEntry{Name: ".data", FileSize: 10000}),
+		testModule("libsmall", 1000),
+		testModule("libsame", 5000),
+		testModule("libshrunk", 50000),
+		testModule("libgone", 100),
+	}}
+	newReport := report{Modules: []moduleReport{
+		testModule("libbig", 110000, sizeEntry{Name: ".text", FileSize: 100000}, sizeEntry{Name: ".data", FileSize: 10000}),
+		testModule("libsmall", 2000),
+		testModule("libsame", 5000),
+		testModule("libshrunk", 40000),
+		testModule("libnew", 100),
+	}}
+
+	text, regressions := diffReports(oldReport, newReport, 4096, 1)
+
+	wantRegressions := []string{"libbig (android_arm64_armv8-a_shared)"}
+	if !reflect.DeepEqual(regressions, wantRegressions) {
+		t.Errorf("expected regressions %q, got %q", wantRegressions, regressions)
+	}
+
+	wantText := `libbig (android_arm64_armv8-a_shared): 100000 -> 110000 bytes (+10000, +10.0%) REGRESSION
+  sections:
+    +10000 .text
+libsmall (android_arm64_armv8-a_shared): 1000 -> 2000 bytes (+1000, +100.0%)
+libshrunk (android_arm64_armv8-a_shared): 50000 -> 40000 bytes (-10000, -20.0%)
+libnew (android_arm64_armv8-a_shared): added
+libgone (android_arm64_armv8-a_shared): removed
+`
+	if text != wantText {
+		t.Errorf("expected diff:\n%s\ngot:\n%s", wantText, text)
+	}
+}
+
+func TestDiffReportsPercentThreshold(t *testing.T) {
+	oldReport := report{Modules: []moduleReport{testModule("libhuge", 10000000)}}
+	newReport := report{Modules: []moduleReport{testModule("libhuge", 10050000)}}
+
+	if _, regressions := diffReports(oldReport, newReport, 4096, 1); len(regressions) != 0 {
+		t.Errorf("expected growth below 1%% to be ignored, got %q", regressions)
+	}
+	if _, regressions := diffReports(oldReport, newReport, 4096, 0); len(regressions) != 1 {
+		t.Errorf("expected a regression without a percent threshold, got %q", regressions)
+	}
+}
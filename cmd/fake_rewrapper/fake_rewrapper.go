@@ -0,0 +1,461 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// fake_rewrapper is a stand-in for the remote execution wrapper and its proxy that runs actions
+// locally, for testing the command lines built by the remoteexec package without access to a
+// remote execution service.
+//
+// Actions with a remote exec strategy are run in a scratch directory that only contains the files
+// declared with --inputs, --input_list_paths and --toolchain_inputs, laid out as they are in the
+// exec root.  An action that references a file of the exec root that was not declared fails, as it
+// would when run remotely, and the declared outputs are copied back to the exec root when it
+// succeeds.  Actions with the local exec strategy are run in place.
+//
+// When the binary is invoked as "bootstrap" there is no proxy to start or stop, it only writes an
+// empty metrics file to $RBE_output_dir on -shutdown as soong_ui expects from the proxy, so that a
+// directory containing a bootstrap and a rewrapper symlink to it can be used as RBE_DIR:
+//
+//   mkdir -p $RBE_DIR
+//   ln -s $(which fake_rewrapper) $RBE_DIR/bootstrap
+//   ln -s $(which fake_rewrapper) $RBE_DIR/rewrapper
+//   USE_RBE=true RBE_DIR=$RBE_DIR RBE_WRAPPER=$RBE_DIR/rewrapper m
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// wrapperFlags are the flags of the remote execution wrapper written by remoteexec.REParams.
+type wrapperFlags struct {
+	labels            string
+	platform          string
+	execStrategy      string
+	inputs            string
+	inputListPaths    string
+	outputFiles       string
+	outputDirectories string
+	toolchainInputs   string
+	envVarAllowlist   string
+	execRoot          string
+
+	keepScratchDir bool
+}
+
+func parseFlags(args []string) (*wrapperFlags, []string, error) {
+	f := &wrapperFlags{}
+	fs := flag.NewFlagSet("fake_rewrapper", flag.ContinueOnError)
+	fs.StringVar(&f.labels, "labels", "", "labels identifying the action")
+	fs.StringVar(&f.platform, "platform", "", "platform properties of the action")
+	fs.StringVar(&f.execStrategy, "exec_strategy", "local", "local, remote or remote_local_fallback")
+	fs.StringVar(&f.inputs, "inputs", "", "comma separated list of input files and directories")
+	fs.StringVar(&f.inputListPaths, "input_list_paths", "", "comma separated list of files listing inputs")
+	fs.StringVar(&f.outputFiles, "output_files", "", "comma separated list of output files")
+	fs.StringVar(&f.outputDirectories, "output_directories", "", "comma separated list of output directories")
+	fs.StringVar(&f.toolchainInputs, "toolchain_inputs", "", "comma separated list of toolchain binaries")
+	fs.StringVar(&f.envVarAllowlist, "env_var_allowlist", "", "comma separated list of environment variables")
+	fs.StringVar(&f.execRoot, "exec_root", os.Getenv("RBE_exec_root"), "the root of the inputs and outputs")
+	fs.BoolVar(&f.keepScratchDir, "fake_keep_scratch_dir", false, "keep the scratch directory of the action")
+	if err := fs.Parse(args); err != nil {
+		return nil, nil, err
+	}
+	if len(fs.Args()) == 0 {
+		return nil, nil, errors.New("missing command after --")
+	}
+	return f, fs.Args(), nil
+}
+
+func splitList(s string) []string {
+	var ret []string
+	for _, e := range strings.Split(s, ",") {
+		if e = strings.TrimSpace(e); e != "" {
+			ret = append(ret, e)
+		}
+	}
+	return ret
+}
+
+func main() {
+	if filepath.Base(os.Args[0]) == "bootstrap" {
+		if err := bootstrap(os.Args[1:]); err != nil {
+			fmt.Fprintln(os.Stderr, "fake_rewrapper:", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	flags, command, err := parseFlags(os.Args[1:])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "fake_rewrapper:", err)
+		os.Exit(1)
+	}
+
+	if err := run(flags, command, os.Stdout, os.Stderr); err != nil {
+		if exit, ok := err.(*exec.ExitError); ok {
+			os.Exit(exit.ExitCode())
+		}
+		fmt.Fprintln(os.Stderr, "fake_rewrapper:", err)
+		os.Exit(1)
+	}
+}
+
+// The metrics file that the proxy writes to $RBE_output_dir when it is shut down, see
+// ui/build/rbe.go.
+const rbeMetricsFile = "rbe_metrics.pb"
+
+// bootstrap stands in for the tool that starts and stops the proxy.  There is no proxy, but the
+// metrics it would have written on shutdown are dumped by soong_ui, so an empty metrics proto is
+// written instead.
+func bootstrap(args []string) error {
+	for _, arg := range args {
+		if arg != "-shutdown" && arg != "--shutdown" {
+			continue
+		}
+		dir := os.Getenv("RBE_output_dir")
+		if dir == "" {
+			return errors.New("RBE_output_dir is not set")
+		}
+		if err := os.MkdirAll(dir, 0777); err != nil {
+			return err
+		}
+		return ioutil.WriteFile(filepath.Join(dir, rbeMetricsFile), nil, 0666)
+	}
+	return nil
+}
+
+func run(flags *wrapperFlags, command []string, stdout, stderr io.Writer) error {
+	if flags.execStrategy == "local" {
+		cmd := exec.Command(command[0], command[1:]...)
+		cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, stdout, stderr
+		return cmd.Run()
+	}
+
+	action, err := newAction(flags, command)
+	if err != nil {
+		return err
+	}
+
+	if undeclared := action.undeclaredArgs(); len(undeclared) > 0 {
+		return undeclaredInputsError(undeclared)
+	}
+
+	scratchDir, err := ioutil.TempDir("", "fake_rewrapper")
+	if err != nil {
+		return err
+	}
+	keep := flags.keepScratchDir
+	defer func() {
+		if !keep {
+			os.RemoveAll(scratchDir)
+		}
+	}()
+
+	output, err := action.runIn(scratchDir)
+	stdout.Write(output)
+	if err != nil {
+		keep = true
+		fmt.Fprintf(stderr, "The failing action was run by fake_rewrapper in %s\n", scratchDir)
+		if undeclared := action.undeclaredInOutput(string(output)); len(undeclared) > 0 {
+			return undeclaredInputsError(undeclared)
+		}
+		return err
+	}
+
+	return action.copyOutputs(scratchDir)
+}
+
+// action is a command to run as if it was run remotely.  All paths are relative to the exec root.
+type action struct {
+	execRoot string
+	// The working directory of the command relative to the exec root.
+	workDir string
+
+	command           []string
+	inputs            []string
+	outputFiles       []string
+	outputDirectories []string
+	env               []string
+}
+
+func newAction(flags *wrapperFlags, command []string) (*action, error) {
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	execRoot := flags.execRoot
+	if execRoot == "" {
+		execRoot = wd
+	}
+	if execRoot, err = filepath.Abs(execRoot); err != nil {
+		return nil, err
+	}
+	workDir, err := filepath.Rel(execRoot, wd)
+	if err != nil || workDir == ".." || strings.HasPrefix(workDir, "../") {
+		return nil, fmt.Errorf("working directory %q is not in the exec root %q", wd, execRoot)
+	}
+
+	a := &action{
+		execRoot: execRoot,
+		workDir:  workDir,
+		command:  command,
+	}
+
+	rel := func(path string) (string, error) {
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(wd, path)
+		}
+		r, err := filepath.Rel(execRoot, path)
+		if err != nil || r == ".." || strings.HasPrefix(r, "../") {
+			return "", fmt.Errorf("%q is not in the exec root %q", path, execRoot)
+		}
+		return r, nil
+	}
+
+	addInputs := func(paths []string) error {
+		for _, path := range paths {
+			r, err := rel(path)
+			if err != nil {
+				return err
+			}
+			a.inputs = append(a.inputs, r)
+		}
+		return nil
+	}
+
+	if err := addInputs(splitList(flags.inputs)); err != nil {
+		return nil, err
+	}
+	if err := addInputs(splitList(flags.toolchainInputs)); err != nil {
+		return nil, err
+	}
+	for _, listPath := range splitList(flags.inputListPaths) {
+		data, err := ioutil.ReadFile(listPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read input list: %w", err)
+		}
+		if err := addInputs(append([]string{listPath}, strings.Fields(string(data))...)); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, path := range splitList(flags.outputFiles) {
+		r, err := rel(path)
+		if err != nil {
+			return nil, err
+		}
+		a.outputFiles = append(a.outputFiles, r)
+	}
+	for _, path := range splitList(flags.outputDirectories) {
+		r, err := rel(path)
+		if err != nil {
+			return nil, err
+		}
+		a.outputDirectories = append(a.outputDirectories, r)
+	}
+
+	// Remote actions only see the allowed environment variables.
+	for _, name := range splitList(flags.envVarAllowlist) {
+		if value, ok := os.LookupEnv(name); ok {
+			a.env = append(a.env, name+"="+value)
+		}
+	}
+	a.env = append(a.env, "PATH=/usr/bin:/bin")
+
+	return a, nil
+}
+
+// declared returns true if the path relative to the exec root is a declared input or output, or is
+// in a declared directory.
+func (a *action) declared(path string) bool {
+	for _, lists := range [][]string{a.inputs, a.outputFiles, a.outputDirectories} {
+		for _, declared := range lists {
+			if path == declared || strings.HasPrefix(path, declared+"/") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// undeclaredFiles returns the words that name existing files in the exec root that were not
+// declared.
+func (a *action) undeclaredFiles(words []string) []string {
+	found := make(map[string]bool)
+	for _, word := range words {
+		path := filepath.Clean(word)
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(a.execRoot, a.workDir, path)
+		}
+		rel, err := filepath.Rel(a.execRoot, path)
+		if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, "../") {
+			continue
+		}
+		if found[rel] || a.declared(rel) {
+			continue
+		}
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			found[rel] = true
+		}
+	}
+
+	var ret []string
+	for path := range found {
+		ret = append(ret, path)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// undeclaredArgs returns the files of the exec root that are referenced by the arguments of the
+// command, either directly or as the value of a --flag=value argument, that were not declared.
+func (a *action) undeclaredArgs() []string {
+	var words []string
+	for _, arg := range a.command {
+		words = append(words, arg)
+		if i := strings.IndexByte(arg, '='); strings.HasPrefix(arg, "-") && i > 0 {
+			words = append(words, arg[i+1:])
+		}
+	}
+	return a.undeclaredFiles(words)
+}
+
+// undeclaredInOutput returns the files of the exec root that are mentioned in the output of a
+// failed command that were not declared, which are the likely cause of the failure.
+func (a *action) undeclaredInOutput(output string) []string {
+	return a.undeclaredFiles(strings.FieldsFunc(output, func(r rune) bool {
+		return strings.ContainsRune(" \t\n'\"`:,;()[]{}<>=", r)
+	}))
+}
+
+func undeclaredInputsError(undeclared []string) error {
+	return fmt.Errorf("the action reads files that are not declared as remote inputs, add them to "+
+		"the Inputs, RSPFiles or ToolchainInputs of its REParams:\n  %s", strings.Join(undeclared, "\n  "))
+}
+
+// runIn copies the declared inputs into the scratch directory and runs the command there.  It
+// returns the combined output of the command.
+func (a *action) runIn(scratchDir string) ([]byte, error) {
+	for _, input := range a.inputs {
+		from := filepath.Join(a.execRoot, input)
+		if _, err := os.Stat(from); err != nil {
+			return nil, fmt.Errorf("declared input %q does not exist", input)
+		}
+		if err := copyPath(from, filepath.Join(scratchDir, input)); err != nil {
+			return nil, err
+		}
+	}
+
+	// The remote execution service creates the directories of the outputs.
+	for _, output := range a.outputFiles {
+		if err := os.MkdirAll(filepath.Join(scratchDir, filepath.Dir(output)), 0777); err != nil {
+			return nil, err
+		}
+	}
+	for _, dir := range a.outputDirectories {
+		if err := os.MkdirAll(filepath.Join(scratchDir, dir), 0777); err != nil {
+			return nil, err
+		}
+	}
+	workDir := filepath.Join(scratchDir, a.workDir)
+	if err := os.MkdirAll(workDir, 0777); err != nil {
+		return nil, err
+	}
+
+	// A relative path to the tool is evaluated relative to the working directory, so the copy of
+	// the tool in the scratch directory is run.
+	cmd := exec.Command(a.command[0], a.command[1:]...)
+	cmd.Dir = workDir
+	cmd.Env = a.env
+	buf := &bytes.Buffer{}
+	cmd.Stdout = buf
+	cmd.Stderr = buf
+	err := cmd.Run()
+	return buf.Bytes(), err
+}
+
+// copyOutputs copies the declared outputs from the scratch directory to the exec root.
+func (a *action) copyOutputs(scratchDir string) error {
+	for _, output := range a.outputFiles {
+		from := filepath.Join(scratchDir, output)
+		if info, err := os.Stat(from); err != nil || info.IsDir() {
+			return fmt.Errorf("declared output %q was not created", output)
+		}
+		if err := copyPath(from, filepath.Join(a.execRoot, output)); err != nil {
+			return err
+		}
+	}
+	for _, dir := range a.outputDirectories {
+		to := filepath.Join(a.execRoot, dir)
+		if err := os.RemoveAll(to); err != nil {
+			return err
+		}
+		if err := copyPath(filepath.Join(scratchDir, dir), to); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copyPath copies a file, or a directory recursively, following symlinks.
+func copyPath(from, to string) error {
+	info, err := os.Stat(from)
+	if err != nil {
+		return err
+	}
+
+	if info.IsDir() {
+		if err := os.MkdirAll(to, 0777); err != nil {
+			return err
+		}
+		entries, err := ioutil.ReadDir(from)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if err := copyPath(filepath.Join(from, entry.Name()), filepath.Join(to, entry.Name())); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(to), 0777); err != nil {
+		return err
+	}
+	in, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	os.Remove(to)
+	out, err := os.OpenFile(to, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,209 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// setUpExecRoot creates an exec root with a few source files and a tool, and changes the working
+// directory to it.
+func setUpExecRoot(t *testing.T) func() {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "fake_rewrapper_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	files := map[string]string{
+		"src/a.txt":           "a\n",
+		"src/b.txt":           "b\n",
+		"src/include/c.h":     "c\n",
+		"out/inputs.rsp":      "src/a.txt src/b.txt\n",
+		"prebuilts/tool/cat2": "#!/bin/sh\ncat \"$@\"\n",
+	}
+	for file, contents := range files {
+		os.MkdirAll(filepath.Dir(file), 0777)
+		if err := ioutil.WriteFile(file, []byte(contents), 0777); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return func() {
+		os.Chdir(wd)
+		os.RemoveAll(dir)
+	}
+}
+
+func runWrapper(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	flags, command, err := parseFlags(args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	err = run(flags, command, stdout, stderr)
+	return stdout.String(), err
+}
+
+func TestParseFlags(t *testing.T) {
+	flags, command, err := parseFlags([]string{"--labels=type=tool", "--platform=Pool=default",
+		"--exec_strategy=remote", "--inputs=a,b", "--output_files=out", "--env_var_allowlist=LANG",
+		"--", "cat", "a", "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if flags.execStrategy != "remote" || flags.inputs != "a,b" || flags.outputFiles != "out" {
+		t.Errorf("unexpected flags %+v", flags)
+	}
+	if !reflect.DeepEqual(command, []string{"cat", "a", "b"}) {
+		t.Errorf("unexpected command %q", command)
+	}
+
+	if _, _, err := parseFlags([]string{"--inputs=a", "--"}); err == nil {
+		t.Errorf("expected an error for a missing command")
+	}
+}
+
+func TestRemoteAction(t *testing.T) {
+	defer setUpExecRoot(t)()
+
+	_, err := runWrapper(t, "--exec_strategy=remote", "--inputs=src/a.txt,src/b.txt",
+		"--toolchain_inputs=prebuilts/tool/cat2", "--output_files=out/ab.txt",
+		"--", "/bin/sh", "-c", "prebuilts/tool/cat2 src/a.txt src/b.txt > out/ab.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, err := ioutil.ReadFile("out/ab.txt"); err != nil || string(data) != "a\nb\n" {
+		t.Errorf("out/ab.txt = %q, %v", data, err)
+	}
+}
+
+func TestRemoteActionInputList(t *testing.T) {
+	defer setUpExecRoot(t)()
+
+	_, err := runWrapper(t, "--exec_strategy=remote", "--input_list_paths=out/inputs.rsp",
+		"--output_files=out/ab.txt",
+		"--", "/bin/sh", "-c", "cat $(cat out/inputs.rsp) > out/ab.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, err := ioutil.ReadFile("out/ab.txt"); err != nil || string(data) != "a\nb\n" {
+		t.Errorf("out/ab.txt = %q, %v", data, err)
+	}
+}
+
+func TestUndeclaredArgument(t *testing.T) {
+	defer setUpExecRoot(t)()
+
+	_, err := runWrapper(t, "--exec_strategy=remote", "--inputs=src/a.txt",
+		"--output_files=out/ab.txt",
+		"--", "/bin/cat", "src/a.txt", "src/b.txt", "--include=src/include/c.h")
+	if err == nil {
+		t.Fatal("expected an error for undeclared inputs")
+	}
+	if !strings.HasSuffix(err.Error(), "\n  src/b.txt\n  src/include/c.h") {
+		t.Errorf("unexpected error %q", err)
+	}
+}
+
+func TestUndeclaredRead(t *testing.T) {
+	defer setUpExecRoot(t)()
+
+	// The script reads src/b.txt, which is not visible in the scratch directory.
+	_, err := runWrapper(t, "--exec_strategy=remote", "--inputs=src/a.txt",
+		"--output_files=out/ab.txt",
+		"--", "/bin/sh", "-c", "cat src/a.txt src/b.txt > out/ab.txt")
+	if err == nil {
+		t.Fatal("expected an error for an undeclared input")
+	}
+	if !strings.HasSuffix(err.Error(), "\n  src/b.txt") {
+		t.Errorf("unexpected error %q", err)
+	}
+	if _, err := os.Stat("out/ab.txt"); !os.IsNotExist(err) {
+		t.Errorf("expected the output not to be copied from a failed action")
+	}
+}
+
+func TestMissingOutput(t *testing.T) {
+	defer setUpExecRoot(t)()
+
+	_, err := runWrapper(t, "--exec_strategy=remote", "--inputs=src/a.txt",
+		"--output_files=out/missing.txt", "--", "/bin/cat", "src/a.txt")
+	if err == nil || !strings.Contains(err.Error(), `"out/missing.txt" was not created`) {
+		t.Errorf("expected an error for a missing output, got %v", err)
+	}
+}
+
+func TestOutputDirectory(t *testing.T) {
+	defer setUpExecRoot(t)()
+
+	_, err := runWrapper(t, "--exec_strategy=remote_local_fallback", "--inputs=src/include",
+		"--output_directories=out/gen",
+		"--", "/bin/sh", "-c", "cp src/include/c.h out/gen/c.h")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, err := ioutil.ReadFile("out/gen/c.h"); err != nil || string(data) != "c\n" {
+		t.Errorf("out/gen/c.h = %q, %v", data, err)
+	}
+}
+
+func TestLocalAction(t *testing.T) {
+	defer setUpExecRoot(t)()
+
+	// Local actions are run in place, without checking their inputs.
+	out, err := runWrapper(t, "--exec_strategy=local", "--", "/bin/cat", "src/a.txt", "src/b.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "a\nb\n" {
+		t.Errorf("unexpected output %q", out)
+	}
+}
+
+func TestBootstrapShutdown(t *testing.T) {
+	defer setUpExecRoot(t)()
+
+	os.Setenv("RBE_output_dir", "out/rbe")
+	defer os.Unsetenv("RBE_output_dir")
+
+	if err := bootstrap(nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat("out/rbe/rbe_metrics.pb"); !os.IsNotExist(err) {
+		t.Errorf("expected no metrics to be written when starting, got %v", err)
+	}
+
+	if err := bootstrap([]string{"-shutdown"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat("out/rbe/rbe_metrics.pb"); err != nil {
+		t.Errorf("expected metrics to be written on shutdown: %v", err)
+	}
+}
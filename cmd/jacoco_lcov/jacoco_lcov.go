@@ -0,0 +1,223 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// jacoco_lcov converts an XML coverage report written by jacoco into the LCOV tracefile format.
+// JaCoCo names source files by the path of their package, with --sources the names are replaced
+// with the paths of the source files in the tree that end with the package path.
+package main
+
+import (
+	"encoding/xml"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+var (
+	output  = flag.String("o", "", "LCOV tracefile to write")
+	sources = flag.String("sources", "", "file containing a space separated list of the source files of the report")
+)
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: jacoco_lcov [--sources <list>] -o <lcov> <jacoco xml report>\n")
+	flag.PrintDefaults()
+	os.Exit(2)
+}
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+
+	if flag.NArg() != 1 || *output == "" {
+		usage()
+	}
+
+	if err := run(flag.Arg(0)); err != nil {
+		fmt.Fprintln(os.Stderr, "jacoco_lcov:", err)
+		os.Exit(1)
+	}
+}
+
+func run(input string) error {
+	f, err := os.Open(input)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r, err := readReport(f)
+	if err != nil {
+		return fmt.Errorf("error reading %s: %w", input, err)
+	}
+
+	var sourceFiles []string
+	if *sources != "" {
+		b, err := ioutil.ReadFile(*sources)
+		if err != nil {
+			return err
+		}
+		sourceFiles = strings.Fields(string(b))
+	}
+
+	out, err := os.Create(*output)
+	if err != nil {
+		return err
+	}
+	if err := writeLcov(out, r, sourceFiles); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+type counter struct {
+	Type    string `xml:"type,attr"`
+	Missed  int    `xml:"missed,attr"`
+	Covered int    `xml:"covered,attr"`
+}
+
+type method struct {
+	Name     string    `xml:"name,attr"`
+	Desc     string    `xml:"desc,attr"`
+	Line     int       `xml:"line,attr"`
+	Counters []counter `xml:"counter"`
+}
+
+type class struct {
+	Name           string   `xml:"name,attr"`
+	SourceFileName string   `xml:"sourcefilename,attr"`
+	Methods        []method `xml:"method"`
+}
+
+type line struct {
+	Nr int `xml:"nr,attr"`
+	Mi int `xml:"mi,attr"`
+	Ci int `xml:"ci,attr"`
+	Mb int `xml:"mb,attr"`
+	Cb int `xml:"cb,attr"`
+}
+
+type sourceFile struct {
+	Name  string `xml:"name,attr"`
+	Lines []line `xml:"line"`
+}
+
+type pkg struct {
+	Name        string       `xml:"name,attr"`
+	Classes     []class      `xml:"class"`
+	SourceFiles []sourceFile `xml:"sourcefile"`
+}
+
+type report struct {
+	Name     string `xml:"name,attr"`
+	Packages []pkg  `xml:"package"`
+}
+
+func readReport(r io.Reader) (*report, error) {
+	var rep report
+	if err := xml.NewDecoder(r).Decode(&rep); err != nil {
+		return nil, err
+	}
+	return &rep, nil
+}
+
+// sourcePath returns the path of the source file in sourceFiles whose path ends with the path of
+// the source file in its package, or the path in its package if there is none.
+func sourcePath(pkgPath string, sourceFiles []string) string {
+	for _, s := range sourceFiles {
+		if s == pkgPath || strings.HasSuffix(s, "/"+pkgPath) {
+			return s
+		}
+	}
+	return pkgPath
+}
+
+func methodCovered(m method) bool {
+	for _, c := range m.Counters {
+		if c.Type == "METHOD" {
+			return c.Covered > 0
+		}
+	}
+	return false
+}
+
+// writeLcov writes a record for each source file in the report.  JaCoCo doesn't count how many
+// times a line was executed, so covered lines and methods are reported with a count of 1.
+func writeLcov(w io.Writer, r *report, sourceFiles []string) error {
+	var sb strings.Builder
+	for _, p := range r.Packages {
+		for _, sf := range p.SourceFiles {
+			pkgPath := sf.Name
+			if p.Name != "" {
+				pkgPath = p.Name + "/" + sf.Name
+			}
+
+			fmt.Fprintf(&sb, "TN:%s\n", r.Name)
+			fmt.Fprintf(&sb, "SF:%s\n", sourcePath(pkgPath, sourceFiles))
+
+			functionsFound, functionsHit := 0, 0
+			for _, c := range p.Classes {
+				if c.SourceFileName != sf.Name {
+					continue
+				}
+				for _, m := range c.Methods {
+					name := strings.Replace(c.Name, "/", ".", -1) + "." + m.Name + m.Desc
+					hits := 0
+					if methodCovered(m) {
+						hits = 1
+						functionsHit++
+					}
+					functionsFound++
+					fmt.Fprintf(&sb, "FN:%d,%s\n", m.Line, name)
+					fmt.Fprintf(&sb, "FNDA:%d,%s\n", hits, name)
+				}
+			}
+			fmt.Fprintf(&sb, "FNF:%d\n", functionsFound)
+			fmt.Fprintf(&sb, "FNH:%d\n", functionsHit)
+
+			branchesFound, branchesHit := 0, 0
+			for _, l := range sf.Lines {
+				for i := 0; i < l.Cb+l.Mb; i++ {
+					taken := "0"
+					if i < l.Cb {
+						taken = "1"
+						branchesHit++
+					}
+					branchesFound++
+					fmt.Fprintf(&sb, "BRDA:%d,0,%d,%s\n", l.Nr, i, taken)
+				}
+			}
+			fmt.Fprintf(&sb, "BRF:%d\n", branchesFound)
+			fmt.Fprintf(&sb, "BRH:%d\n", branchesHit)
+
+			linesHit := 0
+			for _, l := range sf.Lines {
+				hits := 0
+				if l.Ci > 0 {
+					hits = 1
+					linesHit++
+				}
+				fmt.Fprintf(&sb, "DA:%d,%d\n", l.Nr, hits)
+			}
+			fmt.Fprintf(&sb, "LF:%d\n", len(sf.Lines))
+			fmt.Fprintf(&sb, "LH:%d\n", linesHit)
+			fmt.Fprintln(&sb, "end_of_record")
+		}
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
@@ -0,0 +1,100 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+const testReport = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<!DOCTYPE report PUBLIC "-//JACOCO//DTD Report 1.1//EN" "report.dtd">
+<report name="foo">
+  <sessioninfo id="host-1234" start="1" dump="2"/>
+  <package name="com/android/foo">
+    <class name="com/android/foo/Foo" sourcefilename="Foo.java">
+      <method name="&lt;init&gt;" desc="()V" line="3">
+        <counter type="INSTRUCTION" missed="0" covered="3"/>
+        <counter type="METHOD" missed="0" covered="1"/>
+      </method>
+      <method name="bar" desc="(Z)I" line="5">
+        <counter type="INSTRUCTION" missed="2" covered="4"/>
+        <counter type="METHOD" missed="0" covered="1"/>
+      </method>
+      <method name="baz" desc="()V" line="11">
+        <counter type="INSTRUCTION" missed="4" covered="0"/>
+        <counter type="METHOD" missed="1" covered="0"/>
+      </method>
+    </class>
+    <sourcefile name="Foo.java">
+      <line nr="3" mi="0" ci="3" mb="0" cb="0"/>
+      <line nr="5" mi="0" ci="2" mb="1" cb="1"/>
+      <line nr="6" mi="2" ci="0" mb="0" cb="0"/>
+      <line nr="11" mi="4" ci="0" mb="0" cb="0"/>
+    </sourcefile>
+  </package>
+</report>
+`
+
+func TestWriteLcov(t *testing.T) {
+	r, err := readReport(strings.NewReader(testReport))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var sb strings.Builder
+	sources := []string{"frameworks/bar/src/com/android/bar/Bar.java", "frameworks/foo/src/com/android/foo/Foo.java"}
+	if err := writeLcov(&sb, r, sources); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `TN:foo
+SF:frameworks/foo/src/com/android/foo/Foo.java
+FN:3,com.android.foo.Foo.<init>()V
+FNDA:1,com.android.foo.Foo.<init>()V
+FN:5,com.android.foo.Foo.bar(Z)I
+FNDA:1,com.android.foo.Foo.bar(Z)I
+FN:11,com.android.foo.Foo.baz()V
+FNDA:0,com.android.foo.Foo.baz()V
+FNF:3
+FNH:2
+BRDA:5,0,0,1
+BRDA:5,0,1,0
+BRF:2
+BRH:1
+DA:3,1
+DA:5,1
+DA:6,0
+DA:11,0
+LF:4
+LH:2
+end_of_record
+`
+	if sb.String() != want {
+		t.Errorf("expected:\n%s\ngot:\n%s", want, sb.String())
+	}
+}
+
+func TestSourcePath(t *testing.T) {
+	sources := []string{"a/src/com/android/Foo.java", "b/android/Bar.java"}
+
+	if got := sourcePath("com/android/Foo.java", sources); got != sources[0] {
+		t.Errorf("expected %q, got %q", sources[0], got)
+	}
+	// Bar.java is in a directory that doesn't match its package.
+	if got := sourcePath("com/android/Bar.java", sources); got != "com/android/Bar.java" {
+		t.Errorf("expected the package path, got %q", got)
+	}
+}
@@ -0,0 +1,165 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// layering_check reads the depfiles written by the compiler next to a list of object files, and
+// fails if any of the headers they list is under an include directory exported by a module that
+// is not a direct dependency of the module being compiled.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"android/soong/makedeps"
+)
+
+var (
+	exportsFile = flag.String("exports", "", "file with a line of \"<dir> <module>\" for every include directory exported by a dependency")
+	module      = flag.String("module", "", "name of the module being checked")
+	allow       = flag.String("allow", "", "comma separated list of the module and its direct dependencies")
+	output      = flag.String("o", "", "stamp file to write when the check passes")
+)
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: layering_check --exports <file> --module <name> --allow <modules> -o <out> <obj.o>...\n")
+	flag.PrintDefaults()
+	os.Exit(2)
+}
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+
+	if *exportsFile == "" || *module == "" || *output == "" || flag.NArg() == 0 {
+		usage()
+	}
+
+	if err := run(); err != nil {
+		fmt.Fprintln(os.Stderr, "layering_check:", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	f, err := os.Open(*exportsFile)
+	if err != nil {
+		return err
+	}
+	exports, err := readExports(f)
+	f.Close()
+	if err != nil {
+		return fmt.Errorf("error reading %s: %w", *exportsFile, err)
+	}
+
+	allowed := make(map[string]bool)
+	for _, m := range strings.Split(*allow, ",") {
+		allowed[m] = true
+	}
+
+	var violations []string
+	for _, obj := range flag.Args() {
+		depFile := obj + ".d"
+		data, err := ioutil.ReadFile(depFile)
+		if os.IsNotExist(err) {
+			return fmt.Errorf("%s does not exist, the layering check needs ninja to be run with -d keepdepfile",
+				depFile)
+		} else if err != nil {
+			return err
+		}
+		deps, err := makedeps.Parse(depFile, bytes.NewBuffer(data))
+		if err != nil {
+			return fmt.Errorf("error parsing %s: %w", depFile, err)
+		}
+		violations = append(violations, checkDeps(exports, allowed, *module, deps)...)
+	}
+
+	if len(violations) > 0 {
+		return fmt.Errorf("%s includes headers from modules that are not direct dependencies:\n%s\n"+
+			"Add the modules to the shared_libs, static_libs or header_libs of %s.",
+			*module, strings.Join(violations, "\n"), *module)
+	}
+
+	return ioutil.WriteFile(*output, nil, 0666)
+}
+
+// exports maps an exported include directory to the modules that export it.
+type exports map[string][]string
+
+func readExports(r io.Reader) (exports, error) {
+	ret := make(exports)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("invalid line %q", line)
+		}
+		dir := filepath.Clean(fields[0])
+		ret[dir] = append(ret[dir], fields[1])
+	}
+	return ret, scanner.Err()
+}
+
+// owners returns the modules that export the closest include directory containing the header, and
+// whether any directory containing the header is exported by one of the allowed modules.
+func (e exports) owners(header string, allowed map[string]bool) ([]string, bool) {
+	var closest []string
+	for dir := filepath.Dir(filepath.Clean(header)); dir != "." && dir != "/"; dir = filepath.Dir(dir) {
+		modules := e[dir]
+		for _, m := range modules {
+			if allowed[m] {
+				return nil, true
+			}
+		}
+		if closest == nil {
+			closest = modules
+		}
+	}
+	return closest, false
+}
+
+// checkDeps returns an error line for every header in the depfile of a source that is exported by
+// a module that is not allowed.  Headers that are not under any exported include directory, for
+// example those in local_include_dirs, are not checked.
+func checkDeps(e exports, allowed map[string]bool, module string, deps *makedeps.Deps) []string {
+	if len(deps.Inputs) == 0 {
+		return nil
+	}
+	// The first input of a depfile written by clang is the source file itself.
+	source, headers := deps.Inputs[0], deps.Inputs[1:]
+
+	var violations []string
+	for _, header := range headers {
+		owners, ok := e.owners(header, allowed)
+		if ok || len(owners) == 0 {
+			continue
+		}
+		owners = append([]string(nil), owners...)
+		sort.Strings(owners)
+		violations = append(violations, fmt.Sprintf("  %s: %s is exported by %s, which is not a direct dependency of %s",
+			source, header, strings.Join(owners, ", "), module))
+	}
+	return violations
+}
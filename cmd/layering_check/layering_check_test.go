@@ -0,0 +1,116 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"android/soong/makedeps"
+)
+
+const testExports = `
+external/bar/include libbar
+external/baz/include libbaz
+external/baz/include libbaz_headers
+external/foo/include libfoo
+frameworks/qux libqux
+frameworks/qux/include libqux_headers
+`
+
+func TestReadExports(t *testing.T) {
+	e, err := readExports(strings.NewReader(testExports))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := e["external/baz/include"], []string{"libbaz", "libbaz_headers"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("external/baz/include: expected %q, got %q", want, got)
+	}
+
+	if _, err := readExports(strings.NewReader("external/bar/include\n")); err == nil {
+		t.Errorf("expected an error for a line without a module")
+	}
+}
+
+func TestCheckDeps(t *testing.T) {
+	e, err := readExports(strings.NewReader(testExports))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name    string
+		allowed []string
+		headers []string
+		want    []string
+	}{
+		{
+			name:    "direct dependency",
+			allowed: []string{"libfoo", "libbar"},
+			headers: []string{"external/foo/include/foo.h", "external/bar/include/bar/bar.h"},
+		},
+		{
+			name:    "local header",
+			allowed: []string{"libfoo"},
+			headers: []string{"external/foo/src/private.h", "bionic/libc/include/stdio.h"},
+		},
+		{
+			name:    "transitive dependency",
+			allowed: []string{"libfoo", "libbar"},
+			headers: []string{"external/bar/include/bar.h", "external/baz/include/baz.h"},
+			want: []string{
+				"  external/foo/foo.cpp: external/baz/include/baz.h is exported by libbaz, libbaz_headers, " +
+					"which is not a direct dependency of libfoo",
+			},
+		},
+		{
+			name:    "any exporter",
+			allowed: []string{"libfoo", "libbaz_headers"},
+			headers: []string{"external/baz/include/baz.h"},
+		},
+		{
+			name:    "nested directories",
+			allowed: []string{"libfoo", "libqux"},
+			headers: []string{"frameworks/qux/include/qux.h"},
+		},
+		{
+			name:    "closest exporter",
+			allowed: []string{"libfoo"},
+			headers: []string{"frameworks/qux/include/qux.h"},
+			want: []string{
+				"  external/foo/foo.cpp: frameworks/qux/include/qux.h is exported by libqux_headers, " +
+					"which is not a direct dependency of libfoo",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			allowed := make(map[string]bool)
+			for _, m := range tc.allowed {
+				allowed[m] = true
+			}
+			deps := &makedeps.Deps{
+				Output: "out/foo.o",
+				Inputs: append([]string{"external/foo/foo.cpp"}, tc.headers...),
+			}
+			got := checkDeps(e, allowed, "libfoo", deps)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("expected %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
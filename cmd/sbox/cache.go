@@ -0,0 +1,390 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"android/soong/cmd/sbox/sbox_proto"
+)
+
+// The action cache stores the outputs of sbox commands in a local content-addressed directory.
+// The key of a command is a hash of its command line, its copy rules and the contents of its
+// inputs, so a command whose inputs are identical to a previous run restores the outputs of that
+// run instead of running again.  The cache directory contains:
+//
+//   ac/<key[:2]>/<key>        a JSON actionCacheEntry listing the outputs of a command
+//   cas/<digest[:2]>/<digest> the contents of an output, named by their sha256 digest
+//
+// The least recently used entries and blobs are evicted when the cache grows larger than its
+// maximum size.
+//
+// The cache directory may be shared by concurrent builds, so the result of every lookup is
+// appended to a stats file in the sandbox directory of the build instead, which is specific to an
+// output directory.
+
+// The version of the key and entry format, changing it invalidates all existing entries.
+const actionCacheVersion = "1"
+
+const (
+	// The file in the sandboxes root that a "hit" or "miss" line is appended to for every
+	// cacheable command, read by soong_ui in ui/build/action_cache.go.
+	actionCacheStatsFile = "sbox_cache_stats"
+	actionCacheLockFile  = "lock"
+	actionCacheTrimFile  = "last_trim"
+
+	// The minimum time between two checks of the size of the cache.
+	actionCacheTrimInterval = time.Minute
+)
+
+type actionCacheOutput struct {
+	// The path of the output relative to the $PWD when sbox was run.
+	Path       string `json:"path"`
+	Digest     string `json:"digest"`
+	Executable bool   `json:"executable,omitempty"`
+}
+
+type actionCacheEntry struct {
+	Outputs []actionCacheOutput `json:"outputs"`
+}
+
+type actionCache struct {
+	dir       string
+	maxSize   int64
+	statsFile string
+}
+
+func newActionCache(dir string, maxSize int64, statsFile string) *actionCache {
+	if dir == "" {
+		return nil
+	}
+	return &actionCache{dir: dir, maxSize: maxSize, statsFile: statsFile}
+}
+
+// cacheable returns true if the outputs of the command can be stored in the action cache.  Only
+// commands that run in the sandbox directory with copies of their inputs, or in a hermetic
+// sandbox, are cached, as other commands can read files that are not part of the key.  The
+// contents of the files listed in the depfile of a command are not known before running it, so
+// commands that write depfiles are never cached, and neither are commands that don't list their
+// inputs.
+func cacheable(command *sbox_proto.Command) bool {
+	return (command.GetChdir() || command.GetHermetic()) &&
+		len(command.GetInputs()) > 0 && len(command.GetCopyAfter()) > 0 &&
+		!strings.Contains(command.GetCommand(), depFilePlaceholder)
+}
+
+// actionKey returns the key of the command in the action cache.
+func actionKey(command *sbox_proto.Command) (string, error) {
+	h := sha256.New()
+	fmt.Fprintf(h, "version %s\n", actionCacheVersion)
+	fmt.Fprintf(h, "command %q\n", command.GetCommand())
+	fmt.Fprintf(h, "chdir %t hermetic %t\n", command.GetChdir(), command.GetHermetic())
+	for _, c := range command.GetCopyBefore() {
+		fmt.Fprintf(h, "copy_before %q %q %t\n", c.GetFrom(), c.GetTo(), c.GetExecutable())
+	}
+	for _, c := range command.GetCopyAfter() {
+		fmt.Fprintf(h, "copy_after %q %q %t\n", c.GetFrom(), c.GetTo(), c.GetExecutable())
+	}
+	for _, rspFile := range command.GetRspFiles() {
+		fmt.Fprintf(h, "rsp_file %q\n", rspFile.GetFile())
+		for _, mapping := range rspFile.GetPathMappings() {
+			fmt.Fprintf(h, "path_mapping %q %q\n", mapping.GetFrom(), mapping.GetTo())
+		}
+	}
+
+	inputs := append([]string(nil), command.GetInputs()...)
+	sort.Strings(inputs)
+	for _, input := range inputs {
+		digest, err := fileDigest(input)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "input %q %s\n", input, digest)
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// fileDigest returns the sha256 digest of the contents of a file.  Directories only contribute
+// their name, as only their existence can be depended on.
+func fileDigest(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	if info.IsDir() {
+		return "dir", nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	return hashReader(sha256.New(), f)
+}
+
+func hashReader(h hash.Hash, r io.Reader) (string, error) {
+	if _, err := io.Copy(h, r); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func (c *actionCache) entryPath(key string) string {
+	return filepath.Join(c.dir, "ac", key[:2], key)
+}
+
+func (c *actionCache) blobPath(digest string) string {
+	return filepath.Join(c.dir, "cas", digest[:2], digest)
+}
+
+// lookup restores the outputs of the command with the given key from the cache.  It returns false
+// if the cache has no complete entry for the key.
+func (c *actionCache) lookup(key string) (bool, error) {
+	data, err := ioutil.ReadFile(c.entryPath(key))
+	if os.IsNotExist(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	var entry actionCacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		// A corrupt entry is treated as a miss, and will be replaced after running the command.
+		return false, nil
+	}
+
+	// Blobs may have been evicted independently of the entry, check that all of them exist before
+	// touching any of the outputs.
+	for _, output := range entry.Outputs {
+		if _, err := os.Stat(c.blobPath(output.Digest)); err != nil {
+			return false, nil
+		}
+	}
+
+	now := time.Now()
+	for _, output := range entry.Outputs {
+		blob := c.blobPath(output.Digest)
+		if err := copyOneFile(blob, output.Path, false, false); err != nil {
+			return false, fmt.Errorf("error restoring %q from the action cache: %w", output.Path, err)
+		}
+		perm := os.FileMode(0644)
+		if output.Executable {
+			perm = 0755
+		}
+		if err := os.Chmod(output.Path, perm); err != nil {
+			return false, err
+		}
+		if err := os.Chtimes(output.Path, now, now); err != nil {
+			return false, err
+		}
+		// Mark the blob as recently used.
+		os.Chtimes(blob, now, now)
+	}
+	os.Chtimes(c.entryPath(key), now, now)
+
+	return true, nil
+}
+
+// store adds the outputs of the command with the given key to the cache.
+func (c *actionCache) store(key string, command *sbox_proto.Command) error {
+	var entry actionCacheEntry
+	for _, copyPair := range command.GetCopyAfter() {
+		path := copyPair.GetTo()
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		digest, err := c.storeBlob(path)
+		if err != nil {
+			return err
+		}
+		entry.Outputs = append(entry.Outputs, actionCacheOutput{
+			Path:       path,
+			Digest:     digest,
+			Executable: info.Mode()&0100 != 0,
+		})
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomically(c.entryPath(key), func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+}
+
+// storeBlob adds the contents of a file to the cache and returns their digest.
+func (c *actionCache) storeBlob(path string) (string, error) {
+	digest, err := fileDigest(path)
+	if err != nil {
+		return "", err
+	}
+
+	blob := c.blobPath(digest)
+	if _, err := os.Stat(blob); err == nil {
+		now := time.Now()
+		return digest, os.Chtimes(blob, now, now)
+	}
+
+	in, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer in.Close()
+	return digest, writeFileAtomically(blob, func(w io.Writer) error {
+		_, err := io.Copy(w, in)
+		return err
+	})
+}
+
+// writeFileAtomically writes a file in the cache through a temporary file, so that concurrent
+// sbox processes never see a partially written file.
+func writeFileAtomically(path string, write func(io.Writer) error) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile(filepath.Dir(path), ".tmp-"+filepath.Base(path))
+	if err != nil {
+		return err
+	}
+	err = write(f)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
+
+// recordResult appends the result of a lookup to the stats file, which soong_ui reads to report
+// the number of hits and misses in the build metrics.  The file is locked while appending, as
+// ninja runs many sbox processes in parallel.
+func (c *actionCache) recordResult(hit bool) error {
+	result := "miss\n"
+	if hit {
+		result = "hit\n"
+	}
+	f, err := os.OpenFile(c.statsFile, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0666)
+	if err != nil {
+		return err
+	}
+	if err = syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err == nil {
+		_, err = f.WriteString(result)
+	}
+	// Closing the file releases the lock.
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// maybeTrim evicts the least recently used files from the cache if it is larger than its maximum
+// size.  The size is checked at most once every actionCacheTrimInterval, and only by one sbox
+// process at a time.
+func (c *actionCache) maybeTrim() error {
+	if c.maxSize <= 0 {
+		return nil
+	}
+
+	trimFile := filepath.Join(c.dir, actionCacheTrimFile)
+	if info, err := os.Stat(trimFile); err == nil && time.Since(info.ModTime()) < actionCacheTrimInterval {
+		return nil
+	}
+
+	lock, err := os.OpenFile(filepath.Join(c.dir, actionCacheLockFile), os.O_RDWR|os.O_CREATE, 0666)
+	if err != nil {
+		return err
+	}
+	defer lock.Close()
+	if err := syscall.Flock(int(lock.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		// Another sbox process is trimming the cache.
+		return nil
+	}
+	defer syscall.Flock(int(lock.Fd()), syscall.LOCK_UN)
+
+	if err := ioutil.WriteFile(trimFile, nil, 0666); err != nil {
+		return err
+	}
+	return trimCache(c.dir, c.maxSize)
+}
+
+// trimCache deletes the least recently used entries and blobs until the total size of the cache is
+// below 90% of maxSize.
+func trimCache(dir string, maxSize int64) error {
+	type cacheFile struct {
+		path    string
+		size    int64
+		modTime time.Time
+	}
+	var files []cacheFile
+	var total int64
+	for _, sub := range []string{"ac", "cas"} {
+		err := filepath.Walk(filepath.Join(dir, sub), func(path string, info os.FileInfo, err error) error {
+			if os.IsNotExist(err) {
+				return nil
+			} else if err != nil {
+				return err
+			}
+			if !info.IsDir() {
+				files = append(files, cacheFile{path, info.Size(), info.ModTime()})
+				total += info.Size()
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if total <= maxSize {
+		return nil
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].modTime.Before(files[j].modTime)
+	})
+
+	target := maxSize * 9 / 10
+	for _, file := range files {
+		if total <= target {
+			break
+		}
+		if err := os.Remove(file.path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		total -= file.size
+	}
+	return nil
+}
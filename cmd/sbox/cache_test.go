@@ -0,0 +1,212 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"android/soong/cmd/sbox/sbox_proto"
+
+	"github.com/golang/protobuf/proto"
+)
+
+func chdirTemp(t *testing.T) func() {
+	dir, err := ioutil.TempDir("", "sbox_cache_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	return func() {
+		os.Chdir(wd)
+		os.RemoveAll(dir)
+	}
+}
+
+func writeTestFile(t *testing.T, path, contents string, perm os.FileMode) {
+	if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path, []byte(contents), perm); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func testCacheCommand() *sbox_proto.Command {
+	return &sbox_proto.Command{
+		Command: proto.String("cp in __SBOX_SANDBOX_DIR__/out/out && cp tool __SBOX_SANDBOX_DIR__/out/tool"),
+		CopyAfter: []*sbox_proto.Copy{
+			{From: proto.String("out/out"), To: proto.String("gen/out")},
+			{From: proto.String("out/tool"), To: proto.String("gen/tool")},
+		},
+		Inputs: []string{"in", "tool"},
+		Chdir:  proto.Bool(true),
+	}
+}
+
+func TestCacheable(t *testing.T) {
+	command := testCacheCommand()
+	if !cacheable(command) {
+		t.Errorf("expected command to be cacheable")
+	}
+
+	command.Command = proto.String("gcc -MD -MF __SBOX_DEPFILE__ in")
+	if cacheable(command) {
+		t.Errorf("expected command with a depfile not to be cacheable")
+	}
+
+	command = testCacheCommand()
+	command.Inputs = nil
+	if cacheable(command) {
+		t.Errorf("expected command without inputs not to be cacheable")
+	}
+
+	command = testCacheCommand()
+	command.Chdir = nil
+	if cacheable(command) {
+		t.Errorf("expected command that is not sandboxed not to be cacheable")
+	}
+
+	command.Hermetic = proto.Bool(true)
+	if !cacheable(command) {
+		t.Errorf("expected hermetic command to be cacheable")
+	}
+}
+
+func TestActionKey(t *testing.T) {
+	defer chdirTemp(t)()
+
+	writeTestFile(t, "in", "input", 0666)
+	writeTestFile(t, "tool", "tool", 0777)
+
+	key := func(command *sbox_proto.Command) string {
+		t.Helper()
+		k, err := actionKey(command)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return k
+	}
+
+	first := key(testCacheCommand())
+	if second := key(testCacheCommand()); first != second {
+		t.Errorf("expected identical commands to have the same key")
+	}
+
+	command := testCacheCommand()
+	command.Command = proto.String(command.GetCommand() + " && true")
+	if key(command) == first {
+		t.Errorf("expected the key to change with the command line")
+	}
+
+	writeTestFile(t, "tool", "new tool", 0777)
+	if key(testCacheCommand()) == first {
+		t.Errorf("expected the key to change with the contents of the inputs")
+	}
+
+	os.Remove("in")
+	if _, err := actionKey(testCacheCommand()); err == nil {
+		t.Errorf("expected an error for a missing input")
+	}
+}
+
+func TestActionCache(t *testing.T) {
+	defer chdirTemp(t)()
+
+	writeTestFile(t, "in", "input", 0666)
+	writeTestFile(t, "tool", "tool", 0777)
+	writeTestFile(t, "gen/out", "output", 0666)
+	writeTestFile(t, "gen/tool", "tool", 0777)
+
+	cache := newActionCache("cache", 0, "sbox_cache_stats")
+	command := testCacheCommand()
+	key, err := actionKey(command)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if hit, err := cache.lookup(key); err != nil || hit {
+		t.Fatalf("lookup in empty cache = %t, %v", hit, err)
+	}
+	if err := cache.store(key, command); err != nil {
+		t.Fatal(err)
+	}
+
+	os.RemoveAll("gen")
+	if hit, err := cache.lookup(key); err != nil || !hit {
+		t.Fatalf("lookup after store = %t, %v", hit, err)
+	}
+	if data, err := ioutil.ReadFile("gen/out"); err != nil || string(data) != "output" {
+		t.Errorf("restored gen/out = %q, %v", data, err)
+	}
+	if info, err := os.Stat("gen/tool"); err != nil || info.Mode()&0100 == 0 {
+		t.Errorf("expected restored gen/tool to be executable")
+	}
+
+	// A missing blob turns the entry into a miss.
+	digest, _ := fileDigest("gen/out")
+	os.Remove(cache.blobPath(digest))
+	if hit, err := cache.lookup(key); err != nil || hit {
+		t.Errorf("lookup with missing blob = %t, %v", hit, err)
+	}
+
+	cache.recordResult(true)
+	cache.recordResult(false)
+	if data, err := ioutil.ReadFile("sbox_cache_stats"); err != nil ||
+		string(data) != "hit\nmiss\n" {
+		t.Errorf("stats = %q, %v", data, err)
+	}
+}
+
+func TestTrimCache(t *testing.T) {
+	defer chdirTemp(t)()
+
+	now := time.Now()
+	for i, file := range []string{"cas/aa/old", "ac/bb/middle", "cas/cc/new"} {
+		writeTestFile(t, filepath.Join("cache", file), "0123456789", 0666)
+		mtime := now.Add(time.Duration(i-3) * time.Hour)
+		os.Chtimes(filepath.Join("cache", file), mtime, mtime)
+	}
+
+	if err := trimCache("cache", 30); err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range []string{"cas/aa/old", "ac/bb/middle", "cas/cc/new"} {
+		if _, err := os.Stat(filepath.Join("cache", file)); err != nil {
+			t.Errorf("expected %s to be kept when the cache is not full", file)
+		}
+	}
+
+	if err := trimCache("cache", 28); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat("cache/cas/aa/old"); !os.IsNotExist(err) {
+		t.Errorf("expected the least recently used file to be evicted")
+	}
+	for _, file := range []string{"ac/bb/middle", "cas/cc/new"} {
+		if _, err := os.Stat(filepath.Join("cache", file)); err != nil {
+			t.Errorf("expected %s to be kept", file)
+		}
+	}
+}
@@ -0,0 +1,229 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// Hermetic commands are run with nsjail in a mount and user namespace.  The root of the namespace
+// is an empty tmpfs with the directories of the host system, the sandbox directory and the
+// directories of the declared inputs and tools bind mounted at the same paths they have outside
+// the namespace, so a command can't see most of the source and output directories.  Mounting the
+// directories instead of the individual files keeps the number of mounts low for rules with many
+// inputs, and the files that are visible but not declared are caught by tracing the command.
+
+// The host directories that are visible read-only inside the hermetic sandbox.
+var hermeticSystemDirs = []string{"/bin", "/dev", "/etc", "/lib", "/lib32", "/lib64", "/sbin", "/usr"}
+
+// writeNsjailConfig writes the nsjail configuration textproto for a hermetic command.  If chdir
+// is true the command runs in the sandbox directory, otherwise in the current directory.
+func writeNsjailConfig(configFile, tempDir string, chdir bool, inputs []string) error {
+	wd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	absTempDir, err := filepath.Abs(tempDir)
+	if err != nil {
+		return err
+	}
+	cwd := wd
+	if chdir {
+		cwd = absTempDir
+	}
+
+	buf := &strings.Builder{}
+	fmt.Fprintln(buf, `mode: ONCE`)
+	fmt.Fprintln(buf, `hostname: "android-build"`)
+	fmt.Fprintf(buf, "cwd: %q\n", cwd)
+	fmt.Fprintln(buf, `time_limit: 0`)
+	fmt.Fprintln(buf, `keep_env: true`)
+	fmt.Fprintln(buf, `log_level: WARNING`)
+
+	// Set high values, as nsjail uses low defaults.
+	for _, rlimit := range []string{"as", "core", "cpu", "fsize", "nofile"} {
+		fmt.Fprintf(buf, "rlimit_%s_type: SOFT\n", rlimit)
+	}
+
+	// Disable newcgroup, since it may require newer kernels.
+	fmt.Fprintln(buf, `clone_newcgroup: false`)
+
+	mount := func(src, dst string, rw bool, fstype string) {
+		fmt.Fprintln(buf, "mount {")
+		if src != "" {
+			fmt.Fprintf(buf, "  src: %q\n  is_bind: true\n", src)
+		}
+		fmt.Fprintf(buf, "  dst: %q\n", dst)
+		if fstype != "" {
+			fmt.Fprintf(buf, "  fstype: %q\n", fstype)
+		}
+		fmt.Fprintf(buf, "  rw: %t\n  mandatory: false\n}\n", rw)
+	}
+
+	for _, dir := range hermeticSystemDirs {
+		mount(dir, dir, false, "")
+	}
+	mount("", "/tmp", true, "tmpfs")
+	fmt.Fprintln(buf, `mount_proc: true`)
+
+	// An empty working directory to mount the inputs into.
+	mount("", wd, false, "tmpfs")
+
+	// Directories in $PATH outside the system directories, for example the path interposer
+	// directory.
+	for _, dir := range hermeticPathDirs() {
+		mount(dir, dir, false, "")
+	}
+
+	for _, dir := range inputDirs(wd, inputs) {
+		mount(dir, dir, false, "")
+	}
+
+	// The writable sandbox directory, mounted last so that it is not hidden by an input directory.
+	mount(absTempDir, absTempDir, true, "")
+
+	return ioutil.WriteFile(configFile, []byte(buf.String()), 0666)
+}
+
+// hermeticPathDirs returns the directories in $PATH that are mounted in the hermetic sandbox.
+func hermeticPathDirs() []string {
+	var dirs []string
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		if dir, err := filepath.Abs(dir); err == nil && !isSystemDir(dir) {
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+// inputDirs returns the absolute paths to mount in the hermetic sandbox to make the inputs
+// visible: the directories containing them, without the ones that are inside another directory
+// in the list.  Inputs in the working directory itself are returned as files.
+func inputDirs(wd string, inputs []string) []string {
+	var paths []string
+	for _, input := range inputs {
+		path := joinPath(wd, input)
+		if dir := filepath.Dir(path); dir != wd {
+			path = dir
+		}
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var ret []string
+	for _, path := range paths {
+		if len(ret) > 0 {
+			last := ret[len(ret)-1]
+			if path == last || strings.HasPrefix(path, last+"/") {
+				continue
+			}
+		}
+		ret = append(ret, path)
+	}
+	return ret
+}
+
+func isSystemDir(dir string) bool {
+	for _, systemDir := range hermeticSystemDirs {
+		if dir == systemDir || strings.HasPrefix(dir, systemDir+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// hermeticCmd returns a command that runs rawCommand in a hermetic sandbox in which only the
+// sandbox directory and the directories of the given inputs are visible.  It must be run with
+// traceFileAccesses to find the files it uses.  The nsjail configuration is written next to
+// the sandbox directory.
+func hermeticCmd(rawCommand, tempDir string, chdir bool, inputs []string) (*exec.Cmd, error) {
+	if runtime.GOOS != "linux" {
+		return nil, fmt.Errorf("hermetic sandboxing is only supported on Linux")
+	}
+	if nsjailPath == "" {
+		return nil, fmt.Errorf("--nsjail is required for hermetic commands")
+	}
+
+	configFile, err := filepath.Abs(tempDir + ".nsjail.cfg")
+	if err != nil {
+		return nil, err
+	}
+	if err := writeNsjailConfig(configFile, tempDir, chdir, inputs); err != nil {
+		return nil, fmt.Errorf("failed to write nsjail config: %w", err)
+	}
+
+	return exec.Command(nsjailPath, "--config", configFile, "--quiet", "--",
+		"/bin/bash", "-c", rawCommand), nil
+}
+
+// undeclaredInputs returns the files in the source or output directory that a hermetic command
+// accessed, or tried to access, from the absolute paths returned by traceFileAccesses, that
+// exist outside the sandbox but were not declared as inputs.
+func undeclaredInputs(accessed []string, tempDir string, inputs []string) []string {
+	declared := make(map[string]bool)
+	for _, input := range inputs {
+		declared[filepath.Clean(input)] = true
+	}
+
+	wd, _ := os.Getwd()
+	absTempDir := joinPath(wd, tempDir)
+	pathDirs := hermeticPathDirs()
+	inPathDir := func(path string) bool {
+		for _, dir := range pathDirs {
+			if strings.HasPrefix(path, dir+"/") {
+				return true
+			}
+		}
+		return false
+	}
+
+	found := make(map[string]bool)
+	for _, path := range accessed {
+		if strings.HasPrefix(path, absTempDir+"/") || path == absTempDir+".nsjail.cfg" || inPathDir(path) {
+			continue
+		}
+		rel, err := filepath.Rel(wd, path)
+		if err != nil || rel == "." || strings.HasPrefix(rel, "../") || declared[rel] || found[rel] {
+			continue
+		}
+		if info, err := os.Stat(path); err == nil && info.Mode().IsRegular() {
+			found[rel] = true
+		}
+	}
+
+	var ret []string
+	for path := range found {
+		ret = append(ret, path)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// missingDependencyError returns the error reported when a hermetic command accessed undeclared
+// inputs, whether or not it succeeded.
+func missingDependencyError(module string, undeclared []string) error {
+	if module == "" {
+		module = "command"
+	}
+	return fmt.Errorf("missing dependencies: %s accessed files in the hermetic sandbox that were "+
+		"not declared as inputs:\n  %s", module, strings.Join(undeclared, "\n  "))
+}
@@ -0,0 +1,156 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestUndeclaredInputs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sbox_hermetic_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, file := range []string{"foo/a.h", "foo/b.h", "foo/c.cpp", "out/sbox/x/out/c.o"} {
+		os.MkdirAll(filepath.Dir(file), 0777)
+		if err := ioutil.WriteFile(file, nil, 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	accessed := []string{
+		filepath.Join(dir, "foo/c.cpp"),
+		filepath.Join(dir, "foo/a.h"),
+		filepath.Join(dir, "foo/b.h"),
+		filepath.Join(dir, "foo/a.h"),
+		filepath.Join(dir, "foo"),
+		filepath.Join(dir, "foo/missing.h"),
+		filepath.Join(dir, "out/sbox/x/out/c.o"),
+		"/usr/include/stdio.h",
+	}
+
+	got := undeclaredInputs(accessed, "out/sbox/x", []string{"foo/c.cpp"})
+	want := []string{"foo/a.h", "foo/b.h"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("undeclaredInputs() = %q, want %q", got, want)
+	}
+
+	err = missingDependencyError("//foo:libfoo", got)
+	if !strings.Contains(err.Error(), "//foo:libfoo accessed files in the hermetic sandbox") ||
+		!strings.HasSuffix(err.Error(), "\n  foo/a.h\n  foo/b.h") {
+		t.Errorf("unexpected error %q", err)
+	}
+}
+
+func TestTraceFileAccesses(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("tracing is only supported on Linux")
+	}
+
+	dir, err := ioutil.TempDir("", "sbox_hermetic_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "a"), nil, 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	// The command succeeds even though it doesn't find b, and c is read by a child process in
+	// another directory.
+	cmd := exec.Command("bash", "-c", "cat a; test -f b || true; (cd / && cat "+dir+"/c 2>/dev/null; exit 0)")
+	cmd.Dir = dir
+	accessed, err := traceFileAccesses(cmd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := make(map[string]bool)
+	for _, path := range accessed {
+		found[path] = true
+	}
+	for _, want := range []string{"a", "b", "c"} {
+		if !found[filepath.Join(dir, want)] {
+			t.Errorf("expected %q in accessed files %q", filepath.Join(dir, want), accessed)
+		}
+	}
+
+	cmd = exec.Command("bash", "-c", "exit 3")
+	if _, err := traceFileAccesses(cmd); err == nil {
+		t.Errorf("expected an error from a failing command")
+	} else if exit, ok := err.(*exec.ExitError); !ok || exit.ExitCode() != 3 {
+		t.Errorf("expected exit status 3, got %v", err)
+	}
+}
+
+func TestWriteNsjailConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sbox_hermetic_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	configFile := filepath.Join(dir, "nsjail.cfg")
+	inputs := []string{"foo/a.h", "foo/bar/b.h", "foo/c.h"}
+	if err := writeNsjailConfig(configFile, filepath.Join(dir, "sbox"), true, inputs); err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(configFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := string(data)
+
+	for _, want := range []string{
+		`cwd: "` + filepath.Join(dir, "sbox") + `"`,
+		"time_limit: 0\n",
+		"mount {\n  dst: \"" + wd + "\"\n  fstype: \"tmpfs\"\n  rw: false\n",
+		"mount {\n  src: \"" + filepath.Join(dir, "sbox") + "\"\n  is_bind: true\n  dst: \"" + filepath.Join(dir, "sbox") + "\"\n  rw: true\n",
+		"mount {\n  src: \"" + filepath.Join(wd, "foo") + "\"\n  is_bind: true\n  dst: \"" + filepath.Join(wd, "foo") + "\"\n  rw: false\n",
+	} {
+		if !strings.Contains(config, want) {
+			t.Errorf("expected nsjail config to contain %q, got:\n%s", want, config)
+		}
+	}
+	if strings.Contains(config, `dst: "`+filepath.Join(wd, "foo/bar")+`"`) {
+		t.Errorf("expected foo/bar not to be mounted inside foo, got:\n%s", config)
+	}
+	if strings.Index(config, `dst: "`+filepath.Join(wd, "foo")+`"`) > strings.Index(config, `dst: "`+filepath.Join(dir, "sbox")+`"`) {
+		t.Errorf("expected the sandbox directory to be mounted after the inputs, got:\n%s", config)
+	}
+}
@@ -41,6 +41,9 @@ var (
 	sandboxesRoot string
 	manifestFile  string
 	keepOutDir    bool
+	nsjailPath    string
+	cacheDir      string
+	cacheMaxSize  int64
 )
 
 const (
@@ -55,6 +58,12 @@ func init() {
 		"textproto manifest describing the sandboxed command(s)")
 	flag.BoolVar(&keepOutDir, "keep-out-dir", false,
 		"whether to keep the sandbox directory when done")
+	flag.StringVar(&nsjailPath, "nsjail", "",
+		"path to nsjail, used to run hermetic commands")
+	flag.StringVar(&cacheDir, "cache-dir", "",
+		"directory of the local action cache, caching is disabled if empty")
+	flag.Int64Var(&cacheMaxSize, "cache-max-size", 50*1024*1024*1024,
+		"maximum size of the local action cache in bytes")
 }
 
 func usageViolation(violation string) {
@@ -158,13 +167,14 @@ func run() error {
 	// If there is more than one command in the manifest use a separate directory for each one.
 	useSubDir := len(manifest.Commands) > 1
 	var commandDepFiles []string
+	cache := newActionCache(cacheDir, cacheMaxSize, filepath.Join(sandboxesRoot, actionCacheStatsFile))
 
 	for i, command := range manifest.Commands {
 		localTempDir := tempDir
 		if useSubDir {
 			localTempDir = filepath.Join(localTempDir, strconv.Itoa(i))
 		}
-		depFile, err := runCommand(command, localTempDir)
+		depFile, err := runCommand(command, localTempDir, manifest.GetModule(), cache)
 		if err != nil {
 			// Running the command failed, keep the temporary output directory around in
 			// case a user wants to inspect it for debugging purposes.  Soong will delete
@@ -212,13 +222,31 @@ func readManifest(file string) (*sbox_proto.Manifest, error) {
 }
 
 // runCommand runs a single command from a manifest.  If the command references the
-// __SBOX_DEPFILE__ placeholder it returns the name of the depfile that was used.
-func runCommand(command *sbox_proto.Command, tempDir string) (depFile string, err error) {
+// __SBOX_DEPFILE__ placeholder it returns the name of the depfile that was used.  The module is
+// used in error messages.  If cache is not nil the outputs are restored from the action cache when
+// possible, and stored in it otherwise.
+func runCommand(command *sbox_proto.Command, tempDir string, module string, cache *actionCache) (depFile string, err error) {
 	rawCommand := command.GetCommand()
 	if rawCommand == "" {
 		return "", fmt.Errorf("command is required")
 	}
 
+	// Failures to use the action cache are not fatal, the command is run as if it was not cached.
+	var cacheKey string
+	if cache != nil && cacheable(command) {
+		if key, err := actionKey(command); err == nil {
+			hit, err := cache.lookup(key)
+			if err != nil {
+				hit = false
+			}
+			cache.recordResult(hit)
+			if hit {
+				return "", nil
+			}
+			cacheKey = key
+		}
+	}
+
 	pathToTempDirInSbox := tempDir
 	if command.GetChdir() {
 		pathToTempDirInSbox = "."
@@ -255,7 +283,16 @@ func runCommand(command *sbox_proto.Command, tempDir string) (depFile string, er
 		return "", err
 	}
 
-	cmd := exec.Command("bash", "-c", rawCommand)
+	var cmd *exec.Cmd
+	if command.GetHermetic() {
+		cmd, err = hermeticCmd(rawCommand, tempDir, command.GetChdir(), command.GetInputs())
+		if err != nil {
+			return "", err
+		}
+		defer os.Remove(tempDir + ".nsjail.cfg")
+	} else {
+		cmd = exec.Command("bash", "-c", rawCommand)
+	}
 	buf := &bytes.Buffer{}
 	cmd.Stdin = os.Stdin
 	cmd.Stdout = buf
@@ -273,7 +310,12 @@ func runCommand(command *sbox_proto.Command, tempDir string) (depFile string, er
 			return "", fmt.Errorf("Failed to update PATH: %w", err)
 		}
 	}
-	err = cmd.Run()
+	var accessed []string
+	if command.GetHermetic() {
+		accessed, err = traceFileAccesses(cmd)
+	} else {
+		err = cmd.Run()
+	}
 
 	if err != nil {
 		// The command failed, do a best effort copy of output files out of the sandbox.  This is
@@ -297,6 +339,14 @@ func runCommand(command *sbox_proto.Command, tempDir string) (depFile string, er
 	// Write the command's combined stdout/stderr.
 	os.Stdout.Write(buf.Bytes())
 
+	// Report undeclared inputs even if the command succeeded, it may have silently ignored a
+	// missing file or picked up a different one.
+	if command.GetHermetic() {
+		if undeclared := undeclaredInputs(accessed, tempDir, command.GetInputs()); len(undeclared) > 0 {
+			return "", missingDependencyError(module, undeclared)
+		}
+	}
+
 	if err != nil {
 		return "", err
 	}
@@ -335,6 +385,12 @@ func runCommand(command *sbox_proto.Command, tempDir string) (depFile string, er
 	// the created files match the declared files; now move them
 	err = moveFiles(command.CopyAfter, tempDir, "")
 
+	if err == nil && cacheKey != "" {
+		if cache.store(cacheKey, command) == nil {
+			cache.maybeTrim()
+		}
+	}
+
 	return depFile, nil
 }
 
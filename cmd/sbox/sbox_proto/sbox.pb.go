@@ -26,7 +26,9 @@ type Manifest struct {
 	Commands []*Command `protobuf:"bytes,1,rep,name=commands" json:"commands,omitempty"`
 	// If set, GCC-style dependency files from any command that references __SBOX_DEPFILE__ will be
 	// merged into the given output file relative to the $PWD when sbox was started.
-	OutputDepfile        *string  `protobuf:"bytes,2,opt,name=output_depfile,json=outputDepfile" json:"output_depfile,omitempty"`
+	OutputDepfile *string `protobuf:"bytes,2,opt,name=output_depfile,json=outputDepfile" json:"output_depfile,omitempty"`
+	// The label of the module that generated the manifest, used in error messages.
+	Module               *string  `protobuf:"bytes,3,opt,name=module" json:"module,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -71,6 +73,13 @@ func (m *Manifest) GetOutputDepfile() string {
 	return ""
 }
 
+func (m *Manifest) GetModule() string {
+	if m != nil && m.Module != nil {
+		return *m.Module
+	}
+	return ""
+}
+
 // SandboxManifest describes a command to run in the sandbox.
 type Command struct {
 	// A list of copy rules to run before the sandboxed command.  The from field is relative to the
@@ -89,10 +98,17 @@ type Command struct {
 	InputHash *string `protobuf:"bytes,5,opt,name=input_hash,json=inputHash" json:"input_hash,omitempty"`
 	// A list of files that will be copied before the sandboxed command, and whose contents should be
 	// copied as if they were listed in copy_before.
-	RspFiles             []*RspFile `protobuf:"bytes,6,rep,name=rsp_files,json=rspFiles" json:"rsp_files,omitempty"`
-	XXX_NoUnkeyedLiteral struct{}   `json:"-"`
-	XXX_unrecognized     []byte     `json:"-"`
-	XXX_sizecache        int32      `json:"-"`
+	RspFiles []*RspFile `protobuf:"bytes,6,rep,name=rsp_files,json=rspFiles" json:"rsp_files,omitempty"`
+	// If true, run the command in a mount and user namespace where only the sandbox directory, the
+	// inputs and the directories of the host system are visible.
+	Hermetic *bool `protobuf:"varint,7,opt,name=hermetic" json:"hermetic,omitempty"`
+	// The files relative to the $PWD when sbox was run that are read by the command.  They are
+	// visible at the same path in the hermetic sandbox, and their contents are part of the key of
+	// the command in the action cache.
+	Inputs               []string `protobuf:"bytes,8,rep,name=inputs" json:"inputs,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
 }
 
 func (m *Command) Reset()         { *m = Command{} }
@@ -162,6 +178,20 @@ func (m *Command) GetRspFiles() []*RspFile {
 	return nil
 }
 
+func (m *Command) GetHermetic() bool {
+	if m != nil && m.Hermetic != nil {
+		return *m.Hermetic
+	}
+	return false
+}
+
+func (m *Command) GetInputs() []string {
+	if m != nil {
+		return m.Inputs
+	}
+	return nil
+}
+
 // Copy describes a from-to pair of files to copy.  The paths may be relative, the root that they
 // are relative to is specific to the context the Copy is used in and will be different for
 // from and to.
@@ -332,27 +362,29 @@ func init() {
 }
 
 var fileDescriptor_9d0425bf0de86ed1 = []byte{
-	// 342 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x8c, 0x92, 0x5f, 0x4b, 0xc3, 0x30,
-	0x14, 0xc5, 0x69, 0xd7, 0xb9, 0xf6, 0xee, 0x0f, 0x18, 0x7c, 0xc8, 0x8b, 0x32, 0x0a, 0xc2, 0xa6,
-	0x30, 0xd0, 0x07, 0xdf, 0x9d, 0x22, 0x22, 0x0c, 0x24, 0xe0, 0x8b, 0x08, 0x25, 0xeb, 0x52, 0x5b,
-	0x58, 0x9b, 0x90, 0x64, 0xb0, 0x7d, 0x57, 0x3f, 0x8c, 0xe4, 0xa6, 0xd3, 0x82, 0x2f, 0xbe, 0xdd,
-	0x7b, 0x0e, 0xf7, 0xdc, 0x5f, 0xc2, 0x05, 0x30, 0x6b, 0xb9, 0x5f, 0x28, 0x2d, 0xad, 0x24, 0x91,
-	0xab, 0xd3, 0x0f, 0x88, 0x57, 0xbc, 0xa9, 0x0a, 0x61, 0x2c, 0x99, 0x43, 0x9c, 0xcb, 0xba, 0xe6,
-	0xcd, 0xc6, 0xd0, 0x60, 0xda, 0x9b, 0x0d, 0x6f, 0xc7, 0x0b, 0x1c, 0x78, 0xf0, 0x2a, 0xfb, 0xb1,
-	0xc9, 0x25, 0x4c, 0xe4, 0xce, 0xaa, 0x9d, 0xcd, 0x36, 0x42, 0x15, 0xd5, 0x56, 0xd0, 0x70, 0x1a,
-	0xcc, 0x12, 0x36, 0xf6, 0xea, 0xa3, 0x17, 0xd3, 0xaf, 0x00, 0x06, 0xed, 0x30, 0xb9, 0x86, 0x61,
-	0x2e, 0xd5, 0x21, 0x5b, 0x8b, 0x42, 0x6a, 0xd1, 0x2e, 0x80, 0xe3, 0x02, 0x75, 0x60, 0xe0, 0xec,
-	0x25, 0xba, 0xe4, 0x0c, 0xfa, 0x79, 0xb9, 0xa9, 0x34, 0xc6, 0xc6, 0xcc, 0x37, 0x84, 0xc2, 0xa0,
-	0x25, 0xa0, 0xbd, 0x69, 0x38, 0x4b, 0xd8, 0xb1, 0x25, 0x73, 0xc0, 0xe9, 0x8c, 0x17, 0x56, 0x68,
-	0x1a, 0xfd, 0xc9, 0x4e, 0x9c, 0x7b, 0xef, 0x4c, 0x72, 0x0e, 0x50, 0x35, 0x8e, 0xbc, 0xe4, 0xa6,
-	0xa4, 0x7d, 0xc4, 0x4e, 0x50, 0x79, 0xe6, 0xa6, 0x24, 0x57, 0x90, 0x68, 0xa3, 0x32, 0x87, 0x6f,
-	0xe8, 0x49, 0xf7, 0x17, 0x98, 0x51, 0x4f, 0xd5, 0x56, 0xb0, 0x58, 0xfb, 0xc2, 0xa4, 0x2f, 0x10,
-	0xb9, 0x74, 0x42, 0x20, 0x2a, 0xb4, 0xac, 0x69, 0x80, 0x50, 0x58, 0x93, 0x09, 0x84, 0x56, 0xd2,
-	0x10, 0x95, 0xd0, 0x4a, 0x72, 0x01, 0x20, 0xf6, 0x22, 0xdf, 0x59, 0xbe, 0xde, 0x0a, 0xda, 0xc3,
-	0x67, 0x75, 0x94, 0xf4, 0x0d, 0x06, 0xed, 0x02, 0x8c, 0x73, 0x5f, 0x7a, 0x8c, 0x73, 0xda, 0x1d,
-	0x8c, 0x15, 0xb7, 0x65, 0x56, 0x73, 0xa5, 0xaa, 0xe6, 0xd3, 0xd0, 0x10, 0xd1, 0x4e, 0x3d, 0xda,
-	0x2b, 0xb7, 0xe5, 0xca, 0x3b, 0x6c, 0xa4, 0x7e, 0x1b, 0x93, 0xde, 0xc0, 0xb0, 0x63, 0xfe, 0x87,
-	0x74, 0x39, 0x7a, 0xc7, 0x33, 0xc9, 0xf0, 0x4c, 0xbe, 0x03, 0x00, 0x00, 0xff, 0xff, 0x83, 0x82,
-	0xb0, 0xc3, 0x33, 0x02, 0x00, 0x00,
+	// 369 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x8d, 0x52, 0x4d, 0x4b, 0xc3, 0x40,
+	0x14, 0xa4, 0x69, 0xda, 0x24, 0xaf, 0x1f, 0xe0, 0x22, 0xb2, 0x08, 0x4a, 0x09, 0x08, 0x55, 0xa1,
+	0x60, 0x0f, 0xde, 0xad, 0x22, 0x22, 0x14, 0x64, 0xc1, 0x8b, 0x97, 0x90, 0x26, 0x1b, 0x13, 0x30,
+	0xd9, 0x65, 0x77, 0x03, 0xed, 0xbf, 0xf0, 0x27, 0xbb, 0x1f, 0x69, 0x2d, 0x78, 0xf1, 0xf6, 0x66,
+	0x86, 0x9d, 0x37, 0xf3, 0x12, 0x00, 0xb9, 0x61, 0xdb, 0x05, 0x17, 0x4c, 0x31, 0xe4, 0x9b, 0x39,
+	0x56, 0x10, 0xae, 0xd3, 0xa6, 0x2a, 0xa8, 0x54, 0xe8, 0x1a, 0xc2, 0x8c, 0xd5, 0x75, 0xda, 0xe4,
+	0x12, 0xf7, 0x66, 0xfd, 0xf9, 0x68, 0x39, 0x59, 0xd8, 0x07, 0x8f, 0x8e, 0x25, 0x07, 0x19, 0x5d,
+	0xc1, 0x94, 0xb5, 0x8a, 0xb7, 0x2a, 0xc9, 0x29, 0x2f, 0xaa, 0x2f, 0x8a, 0xbd, 0x59, 0x6f, 0x1e,
+	0x91, 0x89, 0x63, 0x9f, 0x1c, 0x89, 0xce, 0x60, 0x58, 0xb3, 0xbc, 0xd5, 0x72, 0xdf, 0xca, 0x1d,
+	0x8a, 0xbf, 0x3d, 0x08, 0x3a, 0x53, 0x74, 0x0b, 0xa3, 0x8c, 0xf1, 0x5d, 0xb2, 0xa1, 0x05, 0x13,
+	0xb4, 0x5b, 0x0c, 0xfb, 0xc5, 0x7c, 0x47, 0xc0, 0xc8, 0x2b, 0xab, 0xa2, 0x53, 0x18, 0x64, 0x65,
+	0x5e, 0x09, 0xbb, 0x2e, 0x24, 0x0e, 0x20, 0x0c, 0x41, 0x97, 0x4c, 0xef, 0xf1, 0xf4, 0x9e, 0x3d,
+	0xd4, 0x95, 0xec, 0xeb, 0x24, 0x2d, 0x14, 0x15, 0xd8, 0xff, 0xe3, 0x1d, 0x19, 0xf5, 0xc1, 0x88,
+	0xe8, 0x02, 0xa0, 0x6a, 0x4c, 0xa3, 0x32, 0x95, 0x25, 0x1e, 0xd8, 0xbc, 0x91, 0x65, 0x5e, 0x34,
+	0x81, 0x6e, 0x20, 0x12, 0x92, 0x27, 0xa6, 0x96, 0xc4, 0xc3, 0xe3, 0xeb, 0x10, 0xc9, 0x9f, 0x35,
+	0x4b, 0x42, 0xe1, 0x06, 0x89, 0xce, 0x21, 0x2c, 0xa9, 0xa8, 0xa9, 0xaa, 0x32, 0x1c, 0xd8, 0xa0,
+	0x07, 0x6c, 0x4e, 0x62, 0x4d, 0x25, 0x0e, 0xb5, 0x89, 0x3e, 0x89, 0x43, 0xf1, 0x2b, 0xf8, 0x26,
+	0x11, 0x42, 0xe0, 0x17, 0x82, 0xd5, 0xfa, 0x0e, 0xa6, 0x88, 0x9d, 0xd1, 0x14, 0x3c, 0xc5, 0x74,
+	0x65, 0xc3, 0xe8, 0x09, 0x5d, 0x02, 0xd0, 0x2d, 0xcd, 0x5a, 0x95, 0x6e, 0xba, 0xd3, 0x86, 0xe4,
+	0x88, 0x89, 0xdf, 0x21, 0xe8, 0x42, 0x59, 0x3b, 0xf3, 0x79, 0xf6, 0x76, 0x86, 0xbb, 0x87, 0x09,
+	0x4f, 0x55, 0x99, 0xd4, 0x29, 0xe7, 0x55, 0xf3, 0x29, 0xb5, 0xb3, 0xa9, 0x73, 0xe2, 0xea, 0xbc,
+	0x69, 0x69, 0xed, 0x14, 0x32, 0xe6, 0xbf, 0x40, 0xc6, 0x77, 0x30, 0x3a, 0x12, 0xff, 0x93, 0x74,
+	0x35, 0xfe, 0xb0, 0xbf, 0x5c, 0x62, 0x7f, 0xb9, 0x1f, 0x3d, 0x64, 0xbe, 0x31, 0x7f, 0x02, 0x00,
+	0x00,
 }
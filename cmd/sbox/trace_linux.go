@@ -0,0 +1,212 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"syscall"
+)
+
+// Hermetic commands are traced with ptrace to find every file they try to open, stat or execute,
+// including the ones that don't exist in the sandbox, so that a tool that silently tolerates a
+// missing undeclared file is still reported.
+
+const (
+	ptraceOptions = syscall.PTRACE_O_TRACESYSGOOD | syscall.PTRACE_O_TRACEFORK |
+		syscall.PTRACE_O_TRACEVFORK | syscall.PTRACE_O_TRACECLONE | syscall.PTRACE_O_TRACEEXEC |
+		syscall.PTRACE_O_TRACEEXIT | ptraceOExitKill
+
+	// PTRACE_O_EXITKILL kills the traced processes if sbox exits.
+	ptraceOExitKill = 0x100000
+
+	// The stop signal of a syscall stop with PTRACE_O_TRACESYSGOOD.
+	syscallStop = syscall.SIGTRAP | 0x80
+
+	atFdCwd = -100
+)
+
+// The path arguments of the syscalls that look up files, as the index of the dirfd argument or -1
+// if the path is relative to the current directory, and the index of the path.  Only x86_64 Linux
+// hosts are supported.
+var tracedSyscalls = map[uint64]struct{ dirfd, path int }{
+	2:   {-1, 0}, // open
+	4:   {-1, 0}, // stat
+	6:   {-1, 0}, // lstat
+	21:  {-1, 0}, // access
+	59:  {-1, 0}, // execve
+	89:  {-1, 0}, // readlink
+	257: {0, 1},  // openat
+	262: {0, 1},  // newfstatat
+	267: {0, 1},  // readlinkat
+	269: {0, 1},  // faccessat
+	322: {0, 1},  // execveat
+	332: {0, 1},  // statx
+	437: {0, 1},  // openat2
+	439: {0, 1},  // faccessat2
+}
+
+func syscallArg(regs *syscall.PtraceRegs, i int) uint64 {
+	return [...]uint64{regs.Rdi, regs.Rsi, regs.Rdx, regs.R10, regs.R8, regs.R9}[i]
+}
+
+// traceFileAccesses runs cmd and its children under ptrace, and returns the absolute paths of the
+// files they looked up, as seen by the processes, along with the error returned by cmd.
+func traceFileAccesses(cmd *exec.Cmd) ([]string, error) {
+	// All ptrace requests have to be made from the thread that started the process.
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Ptrace = true
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	pid := cmd.Process.Pid
+
+	// The process stops with a SIGTRAP after the exec.
+	var ws syscall.WaitStatus
+	if _, err := syscall.Wait4(pid, &ws, syscall.WALL, nil); err != nil {
+		return nil, err
+	}
+	if err := syscall.PtraceSetOptions(pid, ptraceOptions); err != nil {
+		cmd.Process.Kill()
+		cmd.Wait()
+		return nil, fmt.Errorf("failed to trace command: %w", err)
+	}
+
+	seen := make(map[string]bool)
+	var accessed []string
+	// Whether each traced thread is between the entry and the exit of a syscall.
+	inSyscall := make(map[int]bool)
+	started := map[int]bool{pid: true}
+
+	syscall.PtraceSyscall(pid, 0)
+	for {
+		tid, err := syscall.Wait4(-1, &ws, syscall.WALL, nil)
+		if err == syscall.EINTR {
+			continue
+		} else if err != nil {
+			cmd.Process.Kill()
+			cmd.Wait()
+			return accessed, fmt.Errorf("failed to trace command: %w", err)
+		}
+
+		if ws.Exited() || ws.Signaled() {
+			delete(inSyscall, tid)
+			continue
+		} else if !ws.Stopped() {
+			continue
+		}
+
+		switch sig := ws.StopSignal(); {
+		case !started[tid]:
+			// New threads and processes start with a SIGSTOP that must not be delivered.
+			started[tid] = true
+			syscall.PtraceSyscall(tid, 0)
+		case sig == syscallStop:
+			if !inSyscall[tid] {
+				if path := syscallPath(tid); path != "" && !seen[path] {
+					seen[path] = true
+					accessed = append(accessed, path)
+				}
+			}
+			inSyscall[tid] = !inSyscall[tid]
+			syscall.PtraceSyscall(tid, 0)
+		case sig == syscall.SIGTRAP && ws.TrapCause() == syscall.PTRACE_EVENT_EXIT && tid == pid:
+			// The command is exiting.  Leave it to be reaped by cmd.Wait, which also finishes
+			// copying its output.
+			syscall.PtraceDetach(pid)
+			return accessed, cmd.Wait()
+		case sig == syscall.SIGTRAP:
+			// A fork, clone, exec or exit event.
+			syscall.PtraceSyscall(tid, 0)
+		default:
+			syscall.PtraceSyscall(tid, int(sig))
+		}
+	}
+}
+
+// syscallPath returns the absolute path looked up by the syscall that the stopped thread is
+// entering, or an empty string if it is not a traced syscall.
+func syscallPath(tid int) string {
+	var regs syscall.PtraceRegs
+	if err := syscall.PtraceGetRegs(tid, &regs); err != nil {
+		return ""
+	}
+	args, ok := tracedSyscalls[regs.Orig_rax]
+	if !ok {
+		return ""
+	}
+
+	path := readTraceeString(tid, uintptr(syscallArg(&regs, args.path)))
+	if path == "" {
+		return ""
+	}
+	if filepath.IsAbs(path) {
+		return filepath.Clean(path)
+	}
+
+	var dir string
+	var err error
+	if dirfd := int32(syscallArg(&regs, args.dirfd)); args.dirfd < 0 || dirfd == atFdCwd {
+		dir, err = os.Readlink(fmt.Sprintf("/proc/%d/cwd", tid))
+	} else {
+		dir, err = os.Readlink(fmt.Sprintf("/proc/%d/fd/%d", tid, dirfd))
+	}
+	if err != nil || !filepath.IsAbs(dir) {
+		return ""
+	}
+	return filepath.Join(dir, path)
+}
+
+// readTraceeString reads a NUL terminated string from the memory of a stopped thread.
+func readTraceeString(tid int, addr uintptr) string {
+	if addr == 0 {
+		return ""
+	}
+	mem, err := os.Open("/proc/" + strconv.Itoa(tid) + "/mem")
+	if err != nil {
+		return ""
+	}
+	defer mem.Close()
+
+	const pageSize = 4096
+	var ret []byte
+	for len(ret) < syscall.PathMax {
+		// Read up to the end of the page, the next page may not be mapped.
+		buf := make([]byte, pageSize-int(addr%pageSize))
+		n, err := mem.ReadAt(buf, int64(addr))
+		if n == 0 {
+			if err != nil {
+				return ""
+			}
+			break
+		}
+		if i := bytes.IndexByte(buf[:n], 0); i >= 0 {
+			return string(append(ret, buf[:i]...))
+		}
+		ret = append(ret, buf[:n]...)
+		addr += uintptr(n)
+	}
+	return ""
+}
@@ -44,6 +44,10 @@ var (
 	docFile           string
 	bazelQueryViewDir string
 	bp2buildMarker    string
+
+	moduleGraphQuery       string
+	moduleGraphQueryOutput string
+	moduleGraphQueryFile   string
 )
 
 func init() {
@@ -61,6 +65,11 @@ func init() {
 	flag.StringVar(&docFile, "soong_docs", "", "build documentation file to output")
 	flag.StringVar(&bazelQueryViewDir, "bazel_queryview_dir", "", "path to the bazel queryview directory relative to --top")
 	flag.StringVar(&bp2buildMarker, "bp2build_marker", "", "If set, run bp2build, touch the specified marker file then exit")
+	flag.StringVar(&moduleGraphQuery, "query", "", "If set, evaluate the query over the module graph after the mutators have run, e.g. 'somepath(foo, bar)'")
+
+	// Flags that only make sense with --query
+	flag.StringVar(&moduleGraphQueryOutput, "query_output", "text", "Output format of --query: text, json or dot")
+	flag.StringVar(&moduleGraphQueryFile, "query_file", "", "File to write the result of --query to, defaults to stdout")
 }
 
 func newNameResolver(config android.Config) *android.NameResolver {
@@ -174,14 +183,45 @@ func writeJsonModuleGraph(configuration android.Config, ctx *android.Context, pa
 	writeFakeNinjaFile(extraNinjaDeps, configuration.BuildDir())
 }
 
+// Evaluate a query over the module graph and write the result to --query_file or stdout.
+func runModuleGraphQuery(configuration android.Config, ctx *android.Context, extraNinjaDeps []string) {
+	graph := android.NewModuleGraph(ctx, configuration)
+	result, err := graph.Query(moduleGraphQuery)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		os.Exit(1)
+	}
+
+	w := os.Stdout
+	if moduleGraphQueryFile != "" {
+		f, err := os.Create(shared.JoinPath(topDir, moduleGraphQueryFile))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	if err := result.Write(w, moduleGraphQueryOutput); err != nil {
+		fmt.Fprintf(os.Stderr, "error writing query result: %s\n", err)
+		os.Exit(1)
+	}
+	writeFakeNinjaFile(extraNinjaDeps, configuration.BuildDir())
+}
+
 func doChosenActivity(configuration android.Config, extraNinjaDeps []string) string {
 	bazelConversionRequested := bp2buildMarker != ""
 	mixedModeBuild := configuration.BazelContext.BazelEnabled()
 	generateQueryView := bazelQueryViewDir != ""
 	jsonModuleFile := configuration.Getenv("SOONG_DUMP_JSON_MODULE_GRAPH")
+	queryModuleGraph := moduleGraphQuery != ""
 
 	blueprintArgs := bootstrap.CmdlineArgs
-	prepareBuildActions := !generateQueryView && jsonModuleFile == ""
+	prepareBuildActions := !generateQueryView && jsonModuleFile == "" && !queryModuleGraph
+	if queryModuleGraph {
+		configuration.SetCollectModuleGraph()
+	}
 	if bazelConversionRequested {
 		// Run the alternate pipeline of bp2build mutators and singleton to convert
 		// Blueprint to BUILD files before everything else.
@@ -217,6 +257,11 @@ func doChosenActivity(configuration android.Config, extraNinjaDeps []string) str
 		return bootstrap.CmdlineArgs.OutFile // TODO: This is a lie
 	}
 
+	if queryModuleGraph {
+		runModuleGraphQuery(configuration, ctx, extraNinjaDeps)
+		return bootstrap.CmdlineArgs.OutFile // TODO: This is a lie
+	}
+
 	writeMetrics(configuration)
 	return bootstrap.CmdlineArgs.OutFile
 }
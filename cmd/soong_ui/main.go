@@ -235,7 +235,11 @@ func main() {
 	stat.AddOutput(status.NewVerboseLog(log, filepath.Join(logsDir, c.logsPrefix+"verbose.log")))
 	stat.AddOutput(status.NewErrorLog(log, filepath.Join(logsDir, c.logsPrefix+"error.log")))
 	stat.AddOutput(status.NewProtoErrorLog(log, buildErrorFile))
-	stat.AddOutput(status.NewCriticalPath(log))
+	stat.AddOutput(status.NewDiagnosticsLog(log, filepath.Join(logsDir, c.logsPrefix+"build_diagnostics.jsonl")))
+	stat.AddOutput(status.NewSarifLog(log, filepath.Join(logsDir, c.logsPrefix+"build_diagnostics.sarif")))
+	stat.AddOutput(status.NewCriticalPathWithReport(log,
+		filepath.Join(logsDir, c.logsPrefix+"critical_path.json"),
+		filepath.Join(logsDir, c.logsPrefix+"critical_path.html")))
 	stat.AddOutput(status.NewBuildProgressLog(log, filepath.Join(logsDir, c.logsPrefix+"build_progress.pb")))
 
 	buildCtx.Verbosef("Detected %.3v GB total RAM", float32(config.TotalRAM())/(1024*1024*1024))
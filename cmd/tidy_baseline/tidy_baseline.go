@@ -0,0 +1,199 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// tidy_baseline compares the warnings printed by clang-tidy for the sources of a module with the
+// baseline file of the module, and fails if there are warnings that are not in the baseline.  A
+// baseline has one line of the form "<file>: <message> [<check>]" per warning, without the line
+// and column, so that it doesn't change when unrelated lines are added to the file.  A warning
+// that is printed at several locations of a file is repeated once per location, and the check
+// fails if it is printed at more locations than there are lines in the baseline.
+//
+// With --update, it writes the warnings to a new baseline instead of checking them, and with
+// --merge it combines the baselines written for the different variants of a module.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	baseline = flag.String("baseline", "", "baseline file to check the warnings against")
+	module   = flag.String("module", "", "name of the module that is checked")
+	output   = flag.String("o", "", "stamp file to write when the check passes, or baseline to write")
+	update   = flag.Bool("update", false, "write the warnings to a new baseline instead of checking them")
+	merge    = flag.Bool("merge", false, "merge the baselines passed as arguments")
+)
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: tidy_baseline --baseline <file> --module <name> -o <stamp> <clang-tidy output>...\n")
+	fmt.Fprintf(os.Stderr, "       tidy_baseline --update -o <baseline> <clang-tidy output>...\n")
+	fmt.Fprintf(os.Stderr, "       tidy_baseline --merge -o <baseline> <baseline>...\n")
+	flag.PrintDefaults()
+	os.Exit(2)
+}
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+
+	if *output == "" || (!*update && !*merge && (*baseline == "" || *module == "")) {
+		usage()
+	}
+
+	if err := run(); err != nil {
+		fmt.Fprintln(os.Stderr, "tidy_baseline:", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	warnings := make(map[string]int)
+	if *merge {
+		// The variants of a module print the same warnings, keep the largest count of each.
+		for _, file := range flag.Args() {
+			counts := make(map[string]int)
+			if err := readFile(file, func(r io.Reader) error { return readBaseline(r, counts) }); err != nil {
+				return err
+			}
+			for w, n := range counts {
+				if n > warnings[w] {
+					warnings[w] = n
+				}
+			}
+		}
+	} else {
+		locations := make(map[string]map[string]bool)
+		for _, file := range flag.Args() {
+			if err := readFile(file, func(r io.Reader) error { return readWarnings(r, locations) }); err != nil {
+				return err
+			}
+		}
+		warnings = countWarnings(locations)
+	}
+
+	if *update || *merge {
+		return ioutil.WriteFile(*output, []byte(formatBaseline(warnings)), 0666)
+	}
+
+	known := make(map[string]int)
+	if err := readFile(*baseline, func(r io.Reader) error { return readBaseline(r, known) }); err != nil {
+		return err
+	}
+	if added := newWarnings(warnings, known); len(added) > 0 {
+		return fmt.Errorf("clang-tidy found warnings in %s that are not in %s:\n  %s\n"+
+			"Fix them, or run `TIDY_UPDATE_BASELINE=true m tidy-baselines` and extract "+
+			"out/soong/tidy_baselines.zip to update the baseline.",
+			*module, *baseline, strings.Join(added, "\n  "))
+	}
+
+	return ioutil.WriteFile(*output, nil, 0666)
+}
+
+func readFile(file string, read func(io.Reader) error) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := read(f); err != nil {
+		return fmt.Errorf("error reading %s: %w", file, err)
+	}
+	return nil
+}
+
+// warningRegexp matches a warning printed by clang-tidy, e.g.
+// "foo/bar.cpp:12:3: warning: use nullptr [modernize-use-nullptr]".
+var warningRegexp = regexp.MustCompile(`^(.+):([0-9]+:[0-9]+): warning: (.*) \[([^\]]+)\]$`)
+
+// readWarnings adds the locations of every warning in the output of clang-tidy, keyed by the
+// baseline line of the warning.  Warnings in headers are printed for every source that includes
+// them, and are only added once per location.
+func readWarnings(r io.Reader, locations map[string]map[string]bool) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		m := warningRegexp.FindStringSubmatch(scanner.Text())
+		if m == nil {
+			continue
+		}
+		w := fmt.Sprintf("%s: %s [%s]", filepath.Clean(m[1]), m[3], m[4])
+		if locations[w] == nil {
+			locations[w] = make(map[string]bool)
+		}
+		locations[w][m[2]] = true
+	}
+	return scanner.Err()
+}
+
+// countWarnings returns the number of locations of every warning.
+func countWarnings(locations map[string]map[string]bool) map[string]int {
+	ret := make(map[string]int, len(locations))
+	for w, l := range locations {
+		ret[w] = len(l)
+	}
+	return ret
+}
+
+// readBaseline counts the lines of a baseline, ignoring empty lines and comments.
+func readBaseline(r io.Reader, warnings map[string]int) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		warnings[line]++
+	}
+	return scanner.Err()
+}
+
+// newWarnings returns the sorted warnings that are printed more times than they are in the
+// baseline.
+func newWarnings(warnings, known map[string]int) []string {
+	var ret []string
+	for w, n := range warnings {
+		if k := known[w]; n > k {
+			if k > 0 {
+				w = fmt.Sprintf("%s (%d times, %d in the baseline)", w, n, k)
+			}
+			ret = append(ret, w)
+		}
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+func formatBaseline(warnings map[string]int) string {
+	var lines []string
+	for w, n := range warnings {
+		for i := 0; i < n; i++ {
+			lines = append(lines, w)
+		}
+	}
+	sort.Strings(lines)
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
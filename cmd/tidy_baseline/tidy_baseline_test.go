@@ -0,0 +1,94 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testTidyOutput = `
+foo/foo.cpp:12:3: warning: use nullptr [modernize-use-nullptr]
+  return NULL;
+         ^~~~
+         nullptr
+foo/include/foo.h:4:1: warning: single-argument constructors must be marked explicit [google-explicit-constructor]
+foo/./include/foo.h:4:1: warning: single-argument constructors must be marked explicit [google-explicit-constructor]
+foo/foo.cpp:20:5: note: this is not a warning [some-check]
+3 warnings generated.
+`
+
+func readTestWarnings(t *testing.T, outputs ...string) map[string]int {
+	t.Helper()
+	locations := make(map[string]map[string]bool)
+	for _, output := range outputs {
+		if err := readWarnings(strings.NewReader(output), locations); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return countWarnings(locations)
+}
+
+func TestReadWarnings(t *testing.T) {
+	// The header warning is printed again for another source that includes it.
+	warnings := readTestWarnings(t, testTidyOutput,
+		"foo/include/foo.h:4:1: warning: single-argument constructors must be marked explicit [google-explicit-constructor]\n"+
+			"foo/foo.cpp:30:7: warning: use nullptr [modernize-use-nullptr]\n")
+
+	want := "foo/foo.cpp: use nullptr [modernize-use-nullptr]\n" +
+		"foo/foo.cpp: use nullptr [modernize-use-nullptr]\n" +
+		"foo/include/foo.h: single-argument constructors must be marked explicit [google-explicit-constructor]\n"
+	if got := formatBaseline(warnings); got != want {
+		t.Errorf("expected baseline:\n%s\ngot:\n%s", want, got)
+	}
+}
+
+func TestNewWarnings(t *testing.T) {
+	warnings := readTestWarnings(t, testTidyOutput)
+
+	known := make(map[string]int)
+	baseline := `
+# Existing warnings of libfoo.
+foo/include/foo.h: single-argument constructors must be marked explicit [google-explicit-constructor]
+foo/foo.cpp: fixed warning [readability-braces-around-statements]
+`
+	if err := readBaseline(strings.NewReader(baseline), known); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"foo/foo.cpp: use nullptr [modernize-use-nullptr]"}
+	if got := newWarnings(warnings, known); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected new warnings %q, got %q", want, got)
+	}
+
+	known["foo/foo.cpp: use nullptr [modernize-use-nullptr]"] = 1
+	if got := newWarnings(warnings, known); len(got) != 0 {
+		t.Errorf("expected no new warnings, got %q", got)
+	}
+
+	// Another instance of a warning that is in the baseline is new.
+	warnings = readTestWarnings(t, testTidyOutput, "foo/foo.cpp:30:7: warning: use nullptr [modernize-use-nullptr]\n")
+	want = []string{"foo/foo.cpp: use nullptr [modernize-use-nullptr] (2 times, 1 in the baseline)"}
+	if got := newWarnings(warnings, known); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected new warnings %q, got %q", want, got)
+	}
+}
+
+func TestFormatBaselineEmpty(t *testing.T) {
+	if got := formatBaseline(nil); got != "" {
+		t.Errorf("expected an empty baseline, got %q", got)
+	}
+}
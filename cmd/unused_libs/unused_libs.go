@@ -0,0 +1,273 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// unused_libs finds the shared_libs and static_libs of a linked binary or shared library that
+// none of its symbols come from.  A shared library is used if it defines one of the undefined
+// dynamic symbols of the linked output, and a static library is used if one of the symbols it
+// defines was linked into the output.  The unused libraries are printed as warnings and written to
+// a JSON report.
+//
+// With --merge, it combines the reports of every module into a single JSON list of the modules
+// that have unused libraries.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+)
+
+// libFlags collects repeated --shared and --static flags of the form <module>=<path>.
+type libFlags []lib
+
+type lib struct {
+	name string
+	path string
+}
+
+func (l *libFlags) String() string {
+	return ""
+}
+
+func (l *libFlags) Set(s string) error {
+	i := strings.IndexByte(s, '=')
+	if i <= 0 || i == len(s)-1 {
+		return fmt.Errorf("expected <module>=<path>, got %q", s)
+	}
+	*l = append(*l, lib{name: s[:i], path: s[i+1:]})
+	return nil
+}
+
+var (
+	nm         = flag.String("nm", "llvm-nm", "path to llvm-nm")
+	module     = flag.String("module", "", "name of the module that is checked")
+	blueprint  = flag.String("bp", "", "path to the Android.bp file that defines the module")
+	output     = flag.String("o", "", "JSON report to write")
+	merge      = flag.Bool("merge", false, "merge the JSON reports passed as arguments")
+	sharedLibs libFlags
+	staticLibs libFlags
+)
+
+func init() {
+	flag.Var(&sharedLibs, "shared", "a shared library dependency as <module>=<path>, may be repeated")
+	flag.Var(&staticLibs, "static", "a static library dependency as <module>=<path>, may be repeated")
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: unused_libs --module <name> [--shared <module>=<lib.so>]... "+
+		"[--static <module>=<lib.a>]... -o <report.json> <linked output>\n")
+	fmt.Fprintf(os.Stderr, "       unused_libs --merge -o <report.json> [@<file list>] <report.json>...\n")
+	flag.PrintDefaults()
+	os.Exit(2)
+}
+
+// report lists the unused libraries of a linked output.
+type report struct {
+	Module           string   `json:"module"`
+	Blueprint        string   `json:"blueprint,omitempty"`
+	Output           string   `json:"output"`
+	UnusedSharedLibs []string `json:"unused_shared_libs,omitempty"`
+	UnusedStaticLibs []string `json:"unused_static_libs,omitempty"`
+}
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+
+	if *output == "" {
+		usage()
+	}
+
+	var err error
+	if *merge {
+		err = mergeReports(*output, flag.Args())
+	} else {
+		if *module == "" || flag.NArg() != 1 {
+			usage()
+		}
+		err = check(flag.Arg(0))
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "unused_libs:", err)
+		os.Exit(1)
+	}
+}
+
+func check(linked string) error {
+	r := report{
+		Module:    *module,
+		Blueprint: *blueprint,
+		Output:    linked,
+	}
+
+	if len(sharedLibs) > 0 {
+		undefined, err := symbols(linked, "-D", "--undefined-only")
+		if err != nil {
+			return err
+		}
+		r.UnusedSharedLibs, err = unusedLibs(sharedLibs, undefined, "-D", "--defined-only")
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(staticLibs) > 0 {
+		// Symbols with hidden visibility are local in the linked output, so all of its defined
+		// symbols are considered.
+		defined, err := symbols(linked, "--defined-only")
+		if err != nil {
+			return err
+		}
+		r.UnusedStaticLibs, err = unusedLibs(staticLibs, defined, "--extern-only", "--defined-only")
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, l := range r.UnusedSharedLibs {
+		warn(&r, "shared_libs", l)
+	}
+	for _, l := range r.UnusedStaticLibs {
+		warn(&r, "static_libs", l)
+	}
+
+	return writeJSON(*output, r)
+}
+
+func warn(r *report, prop, lib string) {
+	location := r.Blueprint
+	if location == "" {
+		location = r.Output
+	}
+	fmt.Fprintf(os.Stderr, "%s: warning: module %q: %s: %q is unused, none of its symbols are used by %s\n",
+		location, r.Module, prop, lib, r.Output)
+}
+
+// unusedLibs returns the names of the libraries that define none of the given symbols, using the
+// given llvm-nm flags to list the symbols they define.
+func unusedLibs(libs []lib, used map[string]bool, nmFlags ...string) ([]string, error) {
+	var unused []string
+	for _, l := range libs {
+		defined, err := symbols(l.path, nmFlags...)
+		if err != nil {
+			return nil, err
+		}
+		if !intersects(defined, used) {
+			unused = append(unused, l.name)
+		}
+	}
+	sort.Strings(unused)
+	return unused, nil
+}
+
+func intersects(a, b map[string]bool) bool {
+	if len(b) < len(a) {
+		a, b = b, a
+	}
+	for s := range a {
+		if b[s] {
+			return true
+		}
+	}
+	return false
+}
+
+// symbols runs llvm-nm on a file and returns the names of the symbols it lists.
+func symbols(file string, nmFlags ...string) (map[string]bool, error) {
+	args := append([]string{"--portability"}, nmFlags...)
+	args = append(args, file)
+	cmd := exec.Command(*nm, args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("%s %s failed: %w\n%s", *nm, strings.Join(args, " "), err, stderr.String())
+	}
+	return parseNm(bytes.NewReader(out))
+}
+
+// parseNm parses the output of llvm-nm --portability, where every symbol is on a line starting with
+// its name, and every member of an archive starts with a "lib.a[member.o]:" line.  Symbol versions
+// are removed from the names.
+func parseNm(r io.Reader) (map[string]bool, error) {
+	ret := make(map[string]bool)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || strings.HasSuffix(fields[0], ":") {
+			continue
+		}
+		name := fields[0]
+		if i := strings.IndexByte(name, '@'); i > 0 {
+			name = name[:i]
+		}
+		ret[name] = true
+	}
+	return ret, scanner.Err()
+}
+
+// mergeReports writes the reports that list at least one unused library to a single JSON list.
+// Arguments starting with @ name files containing a whitespace separated list of reports.
+func mergeReports(out string, args []string) error {
+	var files []string
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "@") {
+			data, err := ioutil.ReadFile(arg[1:])
+			if err != nil {
+				return err
+			}
+			files = append(files, strings.Fields(string(data))...)
+		} else {
+			files = append(files, arg)
+		}
+	}
+
+	reports := []report{}
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		var r report
+		if err := json.Unmarshal(data, &r); err != nil {
+			return fmt.Errorf("error parsing %s: %w", file, err)
+		}
+		if len(r.UnusedSharedLibs) > 0 || len(r.UnusedStaticLibs) > 0 {
+			reports = append(reports, r)
+		}
+	}
+	sort.SliceStable(reports, func(i, j int) bool {
+		if reports[i].Module != reports[j].Module {
+			return reports[i].Module < reports[j].Module
+		}
+		return reports[i].Output < reports[j].Output
+	})
+	return writeJSON(out, reports)
+}
+
+func writeJSON(file string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, append(data, '\n'), 0666)
+}
@@ -0,0 +1,130 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseNm(t *testing.T) {
+	out := `
+libfoo.a[foo.o]:
+foo T 0000000000000000 0000000000000010
+foo_helper t 0000000000000010 0000000000000008
+
+libfoo.a[bar.o]:
+bar T 0000000000000000 0000000000000010
+memcpy@LIBC U
+`
+	got, err := parseNm(strings.NewReader(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]bool{"foo": true, "foo_helper": true, "bar": true, "memcpy": true}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+// fakeNm writes a script that prints the contents of <file>.nm instead of running llvm-nm.
+func fakeNm(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	script := filepath.Join(dir, "nm")
+	if err := ioutil.WriteFile(script, []byte("#!/bin/sh\nfor f; do :; done\ncat \"$f.nm\"\n"), 0777); err != nil {
+		t.Fatal(err)
+	}
+	*nm = script
+	for file, symbols := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, file+".nm"), []byte(symbols), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestUnusedLibs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "unused_libs_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	fakeNm(t, dir, map[string]string{
+		"libfoo.so": "foo T 0 10\nfoo2 T 10 10\n",
+		"libbar.so": "bar T 0 10\n",
+		"libbaz.so": "baz T 0 10\n",
+	})
+
+	used := map[string]bool{"foo": true, "baz": true, "malloc": true}
+	libs := []lib{
+		{"libfoo", filepath.Join(dir, "libfoo.so")},
+		{"libbar", filepath.Join(dir, "libbar.so")},
+		{"libbaz", filepath.Join(dir, "libbaz.so")},
+	}
+	got, err := unusedLibs(libs, used, "-D", "--defined-only")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"libbar"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestMergeReports(t *testing.T) {
+	dir, err := ioutil.TempDir("", "unused_libs_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	reports := []report{
+		{Module: "libz", Output: "libz.so", UnusedSharedLibs: []string{"liblog"}},
+		{Module: "liba", Output: "liba.so"},
+		{Module: "bin", Output: "bin", UnusedStaticLibs: []string{"libbase"}},
+	}
+	var files []string
+	for _, r := range reports {
+		file := filepath.Join(dir, r.Module+".json")
+		if err := writeJSON(file, r); err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, file)
+	}
+	list := filepath.Join(dir, "list")
+	if err := ioutil.WriteFile(list, []byte(strings.Join(files[1:], " ")), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	out := filepath.Join(dir, "merged.json")
+	if err := mergeReports(out, []string{files[0], "@" + list}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []report
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if want := []report{reports[2], reports[0]}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+}
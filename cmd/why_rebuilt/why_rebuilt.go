@@ -0,0 +1,271 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// why_rebuilt explains why the actions run by the last build were rerun.  It reads the .ninja_log
+// and .ninja_deps files written by ninja, and the out/soong/module_outputs.json file written by
+// soong_build when SOONG_WRITE_MODULE_OUTPUTS=true, and reports the module, variant and rule that
+// produced each rebuilt output together with the cause of the rebuild.
+//
+// The actions run by the last build are the entries added to the .ninja_log since the copy of it
+// that soong_ui takes before running ninja when SOONG_WRITE_MODULE_OUTPUTS=true,
+// .ninja_log.before_build.  Without the copy, e.g. when ninja was run directly, the last build is
+// guessed from the end times in the log, see status.SplitNinjaLogBuilds.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"android/soong/ui/status"
+)
+
+var (
+	outDir      = flag.String("out_dir", "out", "the build output directory")
+	ninjaLog    = flag.String("ninja_log", "", "the .ninja_log file (defaults to <out_dir>/.ninja_log)")
+	previousLog = flag.String("previous_ninja_log", "", "a copy of the .ninja_log taken before the last "+
+		"build (defaults to <ninja_log>.before_build)")
+	ninjaDeps     = flag.String("ninja_deps", "", "the .ninja_deps file (defaults to <out_dir>/.ninja_deps)")
+	moduleOutputs = flag.String("module_outputs", "", "the module outputs file written by soong_build "+
+		"(defaults to <out_dir>/soong/module_outputs.json)")
+	jsonOutput = flag.Bool("json", false, "write the report as JSON")
+)
+
+// moduleOutput matches android.ModuleOutput.
+type moduleOutput struct {
+	Module  string   `json:"module"`
+	Variant string   `json:"variant"`
+	Type    string   `json:"type"`
+	Dir     string   `json:"dir"`
+	Rule    string   `json:"rule"`
+	Inputs  []string `json:"inputs"`
+}
+
+// rebuiltOutput describes an output rebuilt by the last build.
+type rebuiltOutput struct {
+	Output string `json:"output"`
+
+	// The module that produced the output, empty if it was not produced by a Soong module.
+	Module  string `json:"module,omitempty"`
+	Variant string `json:"variant,omitempty"`
+	Dir     string `json:"dir,omitempty"`
+	Rule    string `json:"rule,omitempty"`
+
+	// The reason the output was rebuilt and the input that caused it, if known.
+	Cause string `json:"cause"`
+	Input string `json:"input,omitempty"`
+}
+
+const (
+	causeNew            = "no previous build of the output"
+	causeCommandChanged = "command line changed"
+	causeInputRebuilt   = "input was rebuilt"
+	causeInputChanged   = "input changed"
+	causeUnknown        = "unknown, the output may have been deleted or an input removed"
+)
+
+// mtimeFunc returns the modification time of a file in ninja's timestamp units, or false if the
+// file does not exist.
+type mtimeFunc func(path string) (int64, bool)
+
+func statMtime(path string) (int64, bool) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, false
+	}
+	return info.ModTime().UnixNano(), true
+}
+
+// lastBuild returns the ninja log entries written by the earlier builds and by the last build.
+// The entries of the last build are the ones added since the snapshot of the log taken before it
+// if there is one, and are guessed from the end times otherwise.
+func lastBuild(entries, snapshot []status.NinjaLogEntry,
+	hasSnapshot bool) (previous, last []status.NinjaLogEntry) {
+
+	if hasSnapshot {
+		return snapshot, status.NinjaLogEntriesSince(snapshot, entries)
+	}
+	builds := status.SplitNinjaLogBuilds(entries)
+	if len(builds) == 0 {
+		return nil, nil
+	}
+	for _, build := range builds[:len(builds)-1] {
+		previous = append(previous, build...)
+	}
+	return previous, builds[len(builds)-1]
+}
+
+// explain returns the outputs rebuilt by the last build, and why they were rebuilt.
+func explain(previousEntries, last []status.NinjaLogEntry, deps *status.NinjaDeps,
+	outputs map[string]moduleOutput, mtime mtimeFunc) []rebuiltOutput {
+
+	previous := make(map[string]status.NinjaLogEntry)
+	for _, entry := range previousEntries {
+		previous[entry.Output] = entry
+	}
+
+	rebuilt := make(map[string]bool)
+	for _, entry := range last {
+		rebuilt[entry.Output] = true
+	}
+
+	var ret []rebuiltOutput
+	for _, entry := range last {
+		r := rebuiltOutput{Output: entry.Output}
+		out, hasModule := outputs[entry.Output]
+		if hasModule {
+			r.Module = out.Module
+			r.Variant = out.Variant
+			r.Dir = out.Dir
+			r.Rule = out.Rule
+		}
+
+		prev, ok := previous[entry.Output]
+		switch {
+		case !ok:
+			r.Cause = causeNew
+		case prev.CommandHash != entry.CommandHash:
+			r.Cause = causeCommandChanged
+		default:
+			r.Cause = causeUnknown
+			inputs := out.Inputs
+			if deps != nil {
+				inputs = append(append([]string(nil), inputs...), deps.Deps[entry.Output]...)
+			}
+			for _, input := range inputs {
+				if rebuilt[input] {
+					r.Cause, r.Input = causeInputRebuilt, input
+					break
+				}
+				if t, exists := mtime(input); !exists || t > prev.Mtime {
+					r.Cause, r.Input = causeInputChanged, input
+					break
+				}
+			}
+		}
+		ret = append(ret, r)
+	}
+
+	sort.SliceStable(ret, func(i, j int) bool { return ret[i].Output < ret[j].Output })
+	return ret
+}
+
+func writeText(w io.Writer, rebuilt []rebuiltOutput) {
+	for _, r := range rebuilt {
+		fmt.Fprintln(w, r.Output)
+		if r.Module != "" {
+			module := r.Module
+			if r.Variant != "" {
+				module += " (" + r.Variant + ")"
+			}
+			fmt.Fprintf(w, "  module: %s in //%s\n", module, r.Dir)
+			fmt.Fprintf(w, "  rule:   %s\n", r.Rule)
+		} else {
+			fmt.Fprintln(w, "  module: not produced by a Soong module")
+		}
+		if r.Input != "" {
+			fmt.Fprintf(w, "  cause:  %s: %s\n", r.Cause, r.Input)
+		} else {
+			fmt.Fprintf(w, "  cause:  %s\n", r.Cause)
+		}
+	}
+}
+
+func orDefault(value, def string) string {
+	if value == "" {
+		return def
+	}
+	return value
+}
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [--out_dir <out>] [--json]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	logPath := orDefault(*ninjaLog, filepath.Join(*outDir, ".ninja_log"))
+	logFile, err := os.Open(logPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	entries, err := status.ReadNinjaLog(logFile)
+	logFile.Close()
+	if err != nil {
+		log.Fatalf("Failed to read %s: %s", logFile.Name(), err)
+	}
+
+	var snapshot []status.NinjaLogEntry
+	hasSnapshot := false
+	snapshotPath := orDefault(*previousLog, logPath+status.NinjaLogSnapshotSuffix)
+	snapshotFile, err := os.Open(snapshotPath)
+	if err == nil {
+		snapshot, err = status.ReadNinjaLog(snapshotFile)
+		snapshotFile.Close()
+		if err != nil {
+			log.Fatalf("Failed to read %s: %s", snapshotPath, err)
+		}
+		hasSnapshot = true
+	} else if os.IsNotExist(err) {
+		fmt.Fprintf(os.Stderr, "%s does not exist, guessing the actions of the last build from "+
+			"the end times in the ninja log\n", snapshotPath)
+	} else {
+		log.Fatal(err)
+	}
+
+	var deps *status.NinjaDeps
+	depsFile, err := os.Open(orDefault(*ninjaDeps, filepath.Join(*outDir, ".ninja_deps")))
+	if err == nil {
+		deps, err = status.ReadNinjaDeps(depsFile)
+		depsFile.Close()
+		if err != nil {
+			log.Fatalf("Failed to read %s: %s", depsFile.Name(), err)
+		}
+	} else if !os.IsNotExist(err) {
+		log.Fatal(err)
+	}
+
+	outputs := make(map[string]moduleOutput)
+	outputsFile := orDefault(*moduleOutputs, filepath.Join(*outDir, "soong", "module_outputs.json"))
+	if data, err := ioutil.ReadFile(outputsFile); err == nil {
+		if err := json.Unmarshal(data, &outputs); err != nil {
+			log.Fatalf("Failed to read %s: %s", outputsFile, err)
+		}
+	} else if os.IsNotExist(err) {
+		fmt.Fprintf(os.Stderr, "%s does not exist, rebuild with SOONG_WRITE_MODULE_OUTPUTS=true "+
+			"to map outputs back to modules\n", outputsFile)
+	} else {
+		log.Fatal(err)
+	}
+
+	previous, last := lastBuild(entries, snapshot, hasSnapshot)
+	rebuilt := explain(previous, last, deps, outputs, statMtime)
+
+	if *jsonOutput {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(rebuilt); err != nil {
+			log.Fatal(err)
+		}
+	} else {
+		writeText(os.Stdout, rebuilt)
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"android/soong/ui/status"
+)
+
+func TestExplain(t *testing.T) {
+	entries := []status.NinjaLogEntry{
+		// The previous build.
+		{StartMs: 0, EndMs: 10, Mtime: 100, Output: "out/a.o", CommandHash: "a"},
+		{StartMs: 0, EndMs: 11, Mtime: 100, Output: "out/b.o", CommandHash: "b"},
+		{StartMs: 10, EndMs: 20, Mtime: 100, Output: "out/lib.so", CommandHash: "lib"},
+		{StartMs: 10, EndMs: 21, Mtime: 100, Output: "out/c.o", CommandHash: "c"},
+		// The last build.
+		{StartMs: 0, EndMs: 5, Mtime: 200, Output: "out/a.o", CommandHash: "a"},
+		{StartMs: 0, EndMs: 6, Mtime: 200, Output: "out/b.o", CommandHash: "b2"},
+		{StartMs: 5, EndMs: 10, Mtime: 200, Output: "out/lib.so", CommandHash: "lib"},
+		{StartMs: 5, EndMs: 11, Mtime: 200, Output: "out/c.o", CommandHash: "c"},
+		{StartMs: 5, EndMs: 12, Mtime: 200, Output: "out/new", CommandHash: "new"},
+	}
+
+	deps := &status.NinjaDeps{
+		Deps: map[string][]string{
+			"out/a.o": {"a.c", "a.h"},
+		},
+	}
+
+	outputs := map[string]moduleOutput{
+		"out/a.o":    {Module: "lib", Variant: "android_arm64", Dir: "foo", Rule: "cc", Inputs: []string{"a.c"}},
+		"out/b.o":    {Module: "lib", Variant: "android_arm64", Dir: "foo", Rule: "cc", Inputs: []string{"b.c"}},
+		"out/lib.so": {Module: "lib", Variant: "android_arm64", Dir: "foo", Rule: "ld", Inputs: []string{"out/a.o", "out/b.o"}},
+		"out/c.o":    {Module: "libc", Dir: "bar", Rule: "cc", Inputs: []string{"c.c"}},
+	}
+
+	mtimes := map[string]int64{
+		"a.c": 50,
+		"a.h": 150,
+		"b.c": 50,
+	}
+	mtime := func(path string) (int64, bool) {
+		t, ok := mtimes[path]
+		return t, ok
+	}
+
+	previous, last := lastBuild(entries, nil, false)
+	rebuilt := explain(previous, last, deps, outputs, mtime)
+
+	expected := []rebuiltOutput{
+		{Output: "out/a.o", Module: "lib", Variant: "android_arm64", Dir: "foo", Rule: "cc", Cause: causeInputChanged, Input: "a.h"},
+		{Output: "out/b.o", Module: "lib", Variant: "android_arm64", Dir: "foo", Rule: "cc", Cause: causeCommandChanged},
+		{Output: "out/c.o", Module: "libc", Dir: "bar", Rule: "cc", Cause: causeInputChanged, Input: "c.c"},
+		{Output: "out/lib.so", Module: "lib", Variant: "android_arm64", Dir: "foo", Rule: "ld", Cause: causeInputRebuilt, Input: "out/a.o"},
+		{Output: "out/new", Cause: causeNew},
+	}
+	if !reflect.DeepEqual(rebuilt, expected) {
+		t.Errorf("expected:\n%#v\ngot:\n%#v", expected, rebuilt)
+	}
+
+	text := &strings.Builder{}
+	writeText(text, rebuilt[3:])
+	if g, w := text.String(), `out/lib.so
+  module: lib (android_arm64) in //foo
+  rule:   ld
+  cause:  input was rebuilt: out/a.o
+out/new
+  module: not produced by a Soong module
+  cause:  no previous build of the output
+`; g != w {
+		t.Errorf("expected:\n%s\ngot:\n%s", w, g)
+	}
+}
+
+func TestLastBuildSnapshot(t *testing.T) {
+	snapshot := []status.NinjaLogEntry{
+		{StartMs: 0, EndMs: 3, Mtime: 100, Output: "out/a.o", CommandHash: "a"},
+	}
+	// The first action of the last build ended after the last action of the previous build, which
+	// the end times can't tell apart from a single build.
+	entries := append(snapshot,
+		status.NinjaLogEntry{StartMs: 0, EndMs: 50, Mtime: 200, Output: "out/a.o", CommandHash: "a2"},
+		status.NinjaLogEntry{StartMs: 50, EndMs: 60, Mtime: 200, Output: "out/lib.so", CommandHash: "lib"})
+
+	previous, last := lastBuild(entries, snapshot, true)
+	if !reflect.DeepEqual(previous, snapshot) {
+		t.Errorf("expected previous entries %v, got %v", snapshot, previous)
+	}
+	if !reflect.DeepEqual(last, entries[1:]) {
+		t.Errorf("expected last entries %v, got %v", entries[1:], last)
+	}
+
+	rebuilt := explain(previous, last, nil, nil, func(string) (int64, bool) { return 0, true })
+	expected := []rebuiltOutput{
+		{Output: "out/a.o", Cause: causeCommandChanged},
+		{Output: "out/lib.so", Cause: causeNew},
+	}
+	if !reflect.DeepEqual(rebuilt, expected) {
+		t.Errorf("expected:\n%#v\ngot:\n%#v", expected, rebuilt)
+	}
+}
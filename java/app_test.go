@@ -2823,3 +2823,44 @@ func TestExportedProguardFlagFiles(t *testing.T) {
 		t.Errorf("App does not use library proguard config")
 	}
 }
+
+func TestR8Mappings(t *testing.T) {
+	result := PrepareForTestWithJavaDefaultModules.RunTestWithBp(t, `
+		android_app {
+			name: "foo",
+			sdk_version: "current",
+		}
+
+		android_app {
+			name: "bar",
+			sdk_version: "current",
+			optimize: {
+				enabled: false,
+			},
+		}
+	`)
+
+	foo := result.ModuleForTests("foo", "android_common")
+	r8 := foo.Rule("java.r8")
+	android.AssertStringEquals(t, "usage txt", "out/soong/.intermediates/foo/android_common/proguard_usage.txt",
+		r8.Args["outUsageTxt"])
+	android.AssertStringEquals(t, "configuration", "out/soong/.intermediates/foo/android_common/proguard_configuration.txt",
+		r8.Args["outConfig"])
+
+	mappingZip := foo.Output("r8_mapping.zip")
+	android.AssertStringEquals(t, "module", "foo", mappingZip.Args["module"])
+	android.AssertStringEquals(t, "mapping", r8.Args["outDict"], mappingZip.Args["mapping"])
+
+	info := result.ModuleProvider(foo.Module(), R8OutputInfoProvider).(R8OutputInfo)
+	android.AssertPathRelativeToTopEquals(t, "provider mapping zip",
+		"out/soong/.intermediates/foo/android_common/r8_mapping.zip", info.MappingZip)
+
+	bar := result.ModuleForTests("bar", "android_common")
+	if bar.MaybeOutput("r8_mapping.zip").Rule != nil {
+		t.Errorf("expected no r8_mapping.zip for bar, which is not optimized")
+	}
+
+	mappings := result.SingletonForTests("r8_mappings").Output("r8_mappings.zip")
+	android.AssertPathsRelativeToTopEquals(t, "r8_mappings.zip inputs",
+		[]string{"out/soong/.intermediates/foo/android_common/r8_mapping.zip"}, mappings.Implicits)
+}
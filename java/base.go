@@ -108,6 +108,13 @@ type CommonProperties struct {
 	// more recompilation.
 	Exported_plugins []string
 
+	// List of java_plugin modules to run as Kotlin Symbol Processing (KSP) processors over the
+	// Kotlin and Java sources of the module.  KSP processors are found through the
+	// SymbolProcessorProvider services they declare, and run through kotlinc without generating
+	// Java stubs.  KSP runs before the annotation processors in plugins, which also see the
+	// sources it generates.
+	Ksp_plugins []string
+
 	// The number of Java source entries each Javac instance can process
 	Javac_shard_size *int64
 
@@ -607,6 +614,7 @@ func (j *Module) deps(ctx android.BottomUpMutatorContext) {
 	ctx.AddFarVariationDependencies(ctx.Config().BuildOSCommonTarget.Variations(), pluginTag, j.properties.Plugins...)
 	ctx.AddFarVariationDependencies(ctx.Config().BuildOSCommonTarget.Variations(), errorpronePluginTag, j.properties.Errorprone.Extra_check_modules...)
 	ctx.AddFarVariationDependencies(ctx.Config().BuildOSCommonTarget.Variations(), exportedPluginTag, j.properties.Exported_plugins...)
+	ctx.AddFarVariationDependencies(ctx.Config().BuildOSCommonTarget.Variations(), kspPluginTag, j.properties.Ksp_plugins...)
 
 	android.ProtoDeps(ctx, &j.protoProperties)
 	if j.hasSrcExt(".proto") {
@@ -722,6 +730,7 @@ func (j *Module) collectBuilderFlags(ctx android.ModuleContext, deps deps) javaB
 	flags.classpath = append(flags.classpath, deps.classpath...)
 	flags.java9Classpath = append(flags.java9Classpath, deps.java9Classpath...)
 	flags.processorPath = append(flags.processorPath, deps.processorPath...)
+	flags.kspProcessorPath = append(flags.kspProcessorPath, deps.kspProcessorPath...)
 	flags.errorProneProcessorPath = append(flags.errorProneProcessorPath, deps.errorProneProcessorPath...)
 
 	flags.processors = append(flags.processors, deps.processorClasses...)
@@ -883,6 +892,7 @@ func (j *Module) compile(ctx android.ModuleContext, aaptSrcJar android.Path) {
 	j.expandIDEInfoCompiledSrcs = append(j.expandIDEInfoCompiledSrcs, uniqueSrcFiles.Strings()...)
 
 	var kotlinJars android.Paths
+	var kotlinHeaderJars android.Paths
 
 	if srcFiles.HasExt(".kt") {
 		// user defined kotlin flags.
@@ -919,6 +929,19 @@ func (j *Module) compile(ctx android.ModuleContext, aaptSrcJar android.Path) {
 		flags.kotlincClasspath = append(flags.kotlincClasspath, flags.bootClasspath...)
 		flags.kotlincClasspath = append(flags.kotlincClasspath, flags.classpath...)
 
+		var kotlinSrcJars android.Paths
+		if len(flags.kspProcessorPath) > 0 {
+			// Run the KSP processors, and compile the sources they generate with the module.  KSP
+			// generates both .kt and .java sources, kotlinc compiles the .kt ones.
+			kspSrcJar := android.PathForModuleOut(ctx, "ksp", "ksp-sources.jar")
+			kspResJar := android.PathForModuleOut(ctx, "ksp", "ksp-res.jar")
+			kotlinKsp(ctx, kspSrcJar, kspResJar, kotlinSrcFiles, kotlinCommonSrcFiles, srcJars, flags)
+			srcJars = append(srcJars, kspSrcJar)
+			kotlinSrcJars = append(kotlinSrcJars, kspSrcJar)
+			kotlinJars = append(kotlinJars, kspResJar)
+			kotlinHeaderJars = append(kotlinHeaderJars, kspResJar)
+		}
+
 		if len(flags.processorPath) > 0 {
 			// Use kapt for annotation processing
 			kaptSrcJar := android.PathForModuleOut(ctx, "kapt", "kapt-sources.jar")
@@ -926,13 +949,16 @@ func (j *Module) compile(ctx android.ModuleContext, aaptSrcJar android.Path) {
 			kotlinKapt(ctx, kaptSrcJar, kaptResJar, kotlinSrcFiles, kotlinCommonSrcFiles, srcJars, flags)
 			srcJars = append(srcJars, kaptSrcJar)
 			kotlinJars = append(kotlinJars, kaptResJar)
+			kotlinHeaderJars = append(kotlinHeaderJars, kaptResJar)
 			// Disable annotation processing in javac, it's already been handled by kapt
 			flags.processorPath = nil
 			flags.processors = nil
 		}
 
 		kotlinJar := android.PathForModuleOut(ctx, "kotlin", jarName)
-		kotlinCompile(ctx, kotlinJar, kotlinSrcFiles, kotlinCommonSrcFiles, srcJars, flags)
+		kotlinHeaderJar := android.PathForModuleOut(ctx, "kotlin_headers", jarName)
+		kotlinCompile(ctx, kotlinJar, kotlinHeaderJar, kotlinSrcFiles, kotlinCommonSrcFiles, srcJars, kotlinSrcJars,
+			flags)
 		if ctx.Failed() {
 			return
 		}
@@ -941,10 +967,16 @@ func (j *Module) compile(ctx android.ModuleContext, aaptSrcJar android.Path) {
 		flags.classpath = append(flags.classpath, kotlinJar)
 
 		kotlinJars = append(kotlinJars, kotlinJar)
+		// Dependents compile against the ABI of the kotlin classes, so that they are not
+		// recompiled when only the bodies of functions change.
+		kotlinHeaderJars = append(kotlinHeaderJars, kotlinHeaderJar)
 		// Jar kotlin classes into the final jar after javac
 		if BoolDefault(j.properties.Static_kotlin_stdlib, true) {
 			kotlinJars = append(kotlinJars, deps.kotlinStdlib...)
+			kotlinHeaderJars = append(kotlinHeaderJars, deps.kotlinStdlib...)
 		}
+	} else if len(flags.kspProcessorPath) > 0 {
+		ctx.PropertyErrorf("ksp_plugins", "KSP processors run through kotlinc, and require Kotlin sources")
 	}
 
 	jars := append(android.Paths(nil), kotlinJars...)
@@ -965,7 +997,7 @@ func (j *Module) compile(ctx android.ModuleContext, aaptSrcJar android.Path) {
 			// with sharding enabled. See: b/77284273.
 		}
 		headerJarFileWithoutJarjar, j.headerJarFile =
-			j.compileJavaHeader(ctx, uniqueSrcFiles, srcJars, deps, flags, jarName, kotlinJars)
+			j.compileJavaHeader(ctx, uniqueSrcFiles, srcJars, deps, flags, jarName, kotlinHeaderJars)
 		if ctx.Failed() {
 			return
 		}
@@ -1700,6 +1732,12 @@ func (j *Module) collectDeps(ctx android.ModuleContext) deps {
 				} else {
 					ctx.PropertyErrorf("plugins", "%q is not a java_plugin module", otherName)
 				}
+			case kspPluginTag:
+				if _, ok := module.(*Plugin); ok {
+					deps.kspProcessorPath = append(deps.kspProcessorPath, dep.ImplementationAndResourcesJars...)
+				} else {
+					ctx.PropertyErrorf("ksp_plugins", "%q is not a java_plugin module", otherName)
+				}
 			case errorpronePluginTag:
 				if _, ok := module.(*Plugin); ok {
 					deps.errorProneProcessorPath = append(deps.errorProneProcessorPath, dep.ImplementationAndResourcesJars...)
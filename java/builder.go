@@ -263,6 +263,7 @@ type javaBuilderFlags struct {
 
 	kotlincFlags     string
 	kotlincClasspath classpath
+	kspProcessorPath classpath
 
 	proto android.ProtoFlags
 }
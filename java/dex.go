@@ -80,6 +80,25 @@ type dexer struct {
 	proguardUsageZip       android.OptionalPath
 }
 
+// R8OutputInfo contains the outputs of R8 for a module optimized with R8, which are needed to
+// deobfuscate the stack traces of the module.
+type R8OutputInfo struct {
+	// Mapping from the obfuscated names to the original names.  Its header contains the R8 map id
+	// that identifies the build of the module.
+	Mapping android.Path
+
+	// The classes, fields and methods removed by R8.
+	Usage android.Path
+
+	// The configuration used by R8, with all the flag files merged.
+	Configuration android.Path
+
+	// Zip of the mapping, usage and configuration in a directory named after the R8 map id.
+	MappingZip android.Path
+}
+
+var R8OutputInfoProvider = blueprint.NewProvider(R8OutputInfo{})
+
 func (d *dexer) effectiveOptimizeEnabled() bool {
 	return BoolDefault(d.dexProperties.Optimize.Enabled, d.dexProperties.Optimize.EnabledByDefault)
 }
@@ -129,9 +148,11 @@ var r8, r8RE = pctx.MultiCommandRemoteStaticRules("r8",
 			`--no-data-resources ` +
 			`-printmapping ${outDict} ` +
 			`-printusage ${outUsage} ` +
+			`-printconfiguration ${outConfig} ` +
 			`$r8Flags && ` +
-			`touch "${outDict}" "${outUsage}" && ` +
+			`touch "${outDict}" "${outUsage}" "${outConfig}" && ` +
 			`${config.SoongZipCmd} -o ${outUsageZip} -C ${outUsageDir} -f ${outUsage} && ` +
+			`cp ${outUsage} ${outUsageTxt} && ` +
 			`rm -rf ${outUsageDir} && ` +
 			`$zipTemplate${config.SoongZipCmd} $zipFlags -o $outDir/classes.dex.jar -C $outDir -f "$outDir/classes*.dex" && ` +
 			`${config.MergeZipsCmd} -D -stripFile "**/*.class" $out $outDir/classes.dex.jar $in`,
@@ -145,7 +166,7 @@ var r8, r8RE = pctx.MultiCommandRemoteStaticRules("r8",
 		"$r8Template": &remoteexec.REParams{
 			Labels:          map[string]string{"type": "compile", "compiler": "r8"},
 			Inputs:          []string{"$implicits", "${config.R8Jar}"},
-			OutputFiles:     []string{"${outUsage}"},
+			OutputFiles:     []string{"${outUsage}", "${outConfig}"},
 			ExecStrategy:    "${config.RER8ExecStrategy}",
 			ToolchainInputs: []string{"${config.JavaCmd}"},
 			Platform:        map[string]string{remoteexec.PoolKey: "${config.REJavaPool}"},
@@ -164,8 +185,24 @@ var r8, r8RE = pctx.MultiCommandRemoteStaticRules("r8",
 			ExecStrategy: "${config.RER8ExecStrategy}",
 			Platform:     map[string]string{remoteexec.PoolKey: "${config.REJavaPool}"},
 		},
-	}, []string{"outDir", "outDict", "outUsage", "outUsageZip", "outUsageDir",
-		"r8Flags", "zipFlags"}, []string{"implicits"})
+	}, []string{"outDir", "outDict", "outUsage", "outUsageZip", "outUsageDir", "outUsageTxt",
+		"outConfig", "r8Flags", "zipFlags"}, []string{"implicits"})
+
+// r8MappingZip packages the outputs of R8 in a directory named after the R8 map id from the header
+// of the mapping, or after the hash of the mapping if it has no map id.
+var r8MappingZip = pctx.AndroidStaticRule("r8MappingZip",
+	blueprint.RuleParams{
+		Command: `rm -rf "$out.tmp" && ` +
+			`id=$$( (sed -n 's/^# pg_map_id: //p' $mapping; sha256sum $mapping | cut -d ' ' -f 1) | head -n 1) && ` +
+			`mkdir -p "$out.tmp/$$id" && ` +
+			`cp $mapping "$out.tmp/$$id/mapping.txt" && ` +
+			`cp $usage "$out.tmp/$$id/usage.txt" && ` +
+			`cp $configuration "$out.tmp/$$id/configuration.txt" && ` +
+			`echo "$module" > "$out.tmp/$$id/module.txt" && ` +
+			`${config.SoongZipCmd} -o $out -C "$out.tmp" -D "$out.tmp" && ` +
+			`rm -rf "$out.tmp"`,
+		CommandDeps: []string{"${config.SoongZipCmd}"},
+	}, "mapping", "usage", "configuration", "module")
 
 func (d *dexer) dexCommonFlags(ctx android.ModuleContext, minSdkVersion android.SdkSpec) []string {
 	flags := d.dexProperties.Dxflags
@@ -299,6 +336,8 @@ func (d *dexer) compileDex(ctx android.ModuleContext, flags javaBuilderFlags, mi
 			android.ModuleNameWithPossibleOverride(ctx), "unused.txt")
 		proguardUsageZip := android.PathForModuleOut(ctx, "proguard_usage.zip")
 		d.proguardUsageZip = android.OptionalPathForPath(proguardUsageZip)
+		proguardUsageTxt := android.PathForModuleOut(ctx, "proguard_usage.txt")
+		proguardConfiguration := android.PathForModuleOut(ctx, "proguard_configuration.txt")
 		r8Flags, r8Deps := d.r8Flags(ctx, flags)
 		rule := r8
 		args := map[string]string{
@@ -308,6 +347,8 @@ func (d *dexer) compileDex(ctx android.ModuleContext, flags javaBuilderFlags, mi
 			"outUsageDir": proguardUsageDir.String(),
 			"outUsage":    proguardUsage.String(),
 			"outUsageZip": proguardUsageZip.String(),
+			"outUsageTxt": proguardUsageTxt.String(),
+			"outConfig":   proguardConfiguration.String(),
 			"outDir":      outDir.String(),
 		}
 		if ctx.Config().UseRBE() && ctx.Config().IsEnvTrue("RBE_R8") {
@@ -318,11 +359,31 @@ func (d *dexer) compileDex(ctx android.ModuleContext, flags javaBuilderFlags, mi
 			Rule:            rule,
 			Description:     "r8",
 			Output:          javalibJar,
-			ImplicitOutputs: android.WritablePaths{proguardDictionary, proguardUsageZip},
+			ImplicitOutputs: android.WritablePaths{proguardDictionary, proguardUsageZip, proguardUsageTxt, proguardConfiguration},
 			Input:           classesJar,
 			Implicits:       r8Deps,
 			Args:            args,
 		})
+
+		mappingZip := android.PathForModuleOut(ctx, "r8_mapping.zip")
+		ctx.Build(pctx, android.BuildParams{
+			Rule:        r8MappingZip,
+			Description: "r8 mapping zip",
+			Output:      mappingZip,
+			Implicits:   android.Paths{proguardDictionary, proguardUsageTxt, proguardConfiguration},
+			Args: map[string]string{
+				"mapping":       proguardDictionary.String(),
+				"usage":         proguardUsageTxt.String(),
+				"configuration": proguardConfiguration.String(),
+				"module":        android.ModuleNameWithPossibleOverride(ctx),
+			},
+		})
+		ctx.SetProvider(R8OutputInfoProvider, R8OutputInfo{
+			Mapping:       proguardDictionary,
+			Usage:         proguardUsageTxt,
+			Configuration: proguardConfiguration,
+			MappingZip:    mappingZip,
+		})
 	} else {
 		d8Flags, d8Deps := d8Flags(flags)
 		rule := d8
@@ -350,3 +411,41 @@ func (d *dexer) compileDex(ctx android.ModuleContext, flags javaBuilderFlags, mi
 
 	return javalibJar
 }
+
+func r8MappingsSingletonFactory() android.Singleton {
+	return &r8MappingsSingleton{}
+}
+
+type r8MappingsSingleton struct {
+	mappings android.Path
+}
+
+// GenerateBuildActions merges the R8 outputs of all modules optimized with R8 into
+// r8_mappings.zip, which contains a directory per R8 map id, so that stack traces can be
+// deobfuscated with the map id they report.
+func (s *r8MappingsSingleton) GenerateBuildActions(ctx android.SingletonContext) {
+	var zips android.Paths
+	ctx.VisitAllModules(func(module android.Module) {
+		if ctx.ModuleHasProvider(module, R8OutputInfoProvider) {
+			zips = append(zips, ctx.ModuleProvider(module, R8OutputInfoProvider).(R8OutputInfo).MappingZip)
+		}
+	})
+	if len(zips) == 0 {
+		return
+	}
+
+	s.mappings = android.PathForOutput(ctx, "r8_mappings.zip")
+	rule := android.NewRuleBuilder(pctx, ctx)
+	rule.Command().BuiltTool("merge_zips").
+		Flag("-ignore-duplicates").
+		Output(s.mappings).
+		Inputs(android.SortedUniquePaths(zips))
+	rule.Build("r8_mappings", "merge r8 mappings")
+	ctx.Phony("r8-mappings", s.mappings)
+}
+
+func (s *r8MappingsSingleton) MakeVars(ctx android.MakeVarsContext) {
+	if s.mappings != nil {
+		ctx.DistForGoal("r8-mappings", s.mappings)
+	}
+}
@@ -0,0 +1,181 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package java
+
+// Rules for generating coverage reports with jacoco
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/blueprint"
+
+	"android/soong/android"
+)
+
+func init() {
+	registerJacocoReportBuildComponents(android.InitRegistrationContext)
+}
+
+func registerJacocoReportBuildComponents(ctx android.RegistrationContext) {
+	ctx.RegisterModuleType("java_coverage_report", JacocoReportFactory)
+}
+
+var (
+	jacocoReportLibTag = dependencyTag{name: "jacocoReportLib"}
+
+	// jacocoReport generates the HTML and XML reports of the coverage recorded in the .exec files
+	// for the classes in the jacoco-report-classes jars, and converts the XML report to LCOV.
+	jacocoReport = pctx.AndroidStaticRule("jacocoReport", blueprint.RuleParams{
+		Command: `rm -rf $tmpDir && mkdir -p $tmpDir/sources $tmpDir/html && ` +
+			`${config.ZipSyncCmd} -d $tmpDir/sources $sourcesJar && ` +
+			`${config.JavaCmd} ${config.JavaVmFlags} -jar ${config.JacocoCLIJar} report --quiet ` +
+			`--name $name $in $classFiles --sourcefiles $tmpDir/sources ` +
+			`--html $tmpDir/html --xml $xml && ` +
+			`${config.SoongZipCmd} -o $out -C $tmpDir/html -D $tmpDir/html && ` +
+			`${config.JacocoLcovCmd} --sources $sourcesList -o $lcov $xml && ` +
+			`rm -rf $tmpDir`,
+		CommandDeps: []string{
+			"${config.ZipSyncCmd}",
+			"${config.JavaCmd}",
+			"${config.JacocoCLIJar}",
+			"${config.SoongZipCmd}",
+			"${config.JacocoLcovCmd}",
+		},
+	},
+		"name", "classFiles", "sourcesJar", "sourcesList", "tmpDir", "xml", "lcov")
+)
+
+type jacocoReportProperties struct {
+	// List of .exec files written by jacoco while running the tests.
+	Exec_files []string `android:"path"`
+
+	// List of java modules to report the coverage of.  The modules must be instrumented by
+	// building with EMMA_INSTRUMENT=true, and the reports only contain the classes selected by
+	// their jacoco.include_filter and jacoco.exclude_filter properties.
+	Libs []string
+}
+
+type JacocoReport struct {
+	android.ModuleBase
+
+	properties jacocoReportProperties
+
+	htmlReport android.Path
+	xmlReport  android.Path
+	lcovReport android.Path
+}
+
+func (r *JacocoReport) DepsMutator(ctx android.BottomUpMutatorContext) {
+	ctx.AddVariationDependencies(nil, jacocoReportLibTag, r.properties.Libs...)
+}
+
+func (r *JacocoReport) GenerateAndroidBuildActions(ctx android.ModuleContext) {
+	execFiles := android.PathsForModuleSrc(ctx, r.properties.Exec_files)
+	if len(execFiles) == 0 {
+		ctx.PropertyErrorf("exec_files", "at least one .exec file is required")
+	}
+
+	var classesJars, sources android.Paths
+	var srcJarArgs []string
+	var notInstrumented []string
+	ctx.VisitDirectDepsWithTag(jacocoReportLibTag, func(m android.Module) {
+		if !ctx.OtherModuleHasProvider(m, JavaInfoProvider) {
+			ctx.PropertyErrorf("libs", "%q is not a java module", ctx.OtherModuleName(m))
+			return
+		}
+		dep := ctx.OtherModuleProvider(m, JavaInfoProvider).(JavaInfo)
+		if dep.JacocoReportClassesFile == nil {
+			notInstrumented = append(notInstrumented, ctx.OtherModuleName(m))
+			return
+		}
+		classesJars = append(classesJars, dep.JacocoReportClassesFile)
+		srcJarArgs = append(srcJarArgs, dep.SrcJarArgs...)
+		sources = append(sources, dep.SrcJarDeps...)
+	})
+
+	htmlReport := android.PathForModuleOut(ctx, ctx.ModuleName()+"-html.zip")
+	xmlReport := android.PathForModuleOut(ctx, ctx.ModuleName()+".xml")
+	lcovReport := android.PathForModuleOut(ctx, ctx.ModuleName()+".lcov")
+	r.htmlReport, r.xmlReport, r.lcovReport = htmlReport, xmlReport, lcovReport
+
+	// The libraries are only instrumented in coverage builds, fail when the reports are built in
+	// other builds instead of failing the analysis of every build.
+	if len(notInstrumented) > 0 {
+		ctx.Build(pctx, android.BuildParams{
+			Rule:            android.ErrorRule,
+			Output:          htmlReport,
+			ImplicitOutputs: android.WritablePaths{xmlReport, lcovReport},
+			Args: map[string]string{
+				"error": fmt.Sprintf("%s are not instrumented by jacoco, build with EMMA_INSTRUMENT=true",
+					strings.Join(notInstrumented, ", ")),
+			},
+		})
+		return
+	}
+
+	sourcesJar := android.PathForModuleOut(ctx, "jacoco_report", "sources.jar")
+	TransformResourcesToJar(ctx, sourcesJar, srcJarArgs, sources)
+
+	sourcesList := android.PathForModuleOut(ctx, "jacoco_report", "sources.txt")
+	android.WriteFileRule(ctx, sourcesList, strings.Join(sources.Strings(), " "))
+
+	ctx.Build(pctx, android.BuildParams{
+		Rule:            jacocoReport,
+		Description:     "jacoco report",
+		Output:          htmlReport,
+		ImplicitOutputs: android.WritablePaths{xmlReport, lcovReport},
+		Inputs:          execFiles,
+		Implicits:       append(android.Paths{sourcesJar, sourcesList}, classesJars...),
+		Args: map[string]string{
+			"name":        ctx.ModuleName(),
+			"classFiles":  android.JoinWithPrefix(classesJars.Strings(), "--classfiles "),
+			"sourcesJar":  sourcesJar.String(),
+			"sourcesList": sourcesList.String(),
+			"tmpDir":      android.PathForModuleOut(ctx, "jacoco_report", "tmp").String(),
+			"xml":         xmlReport.String(),
+			"lcov":        lcovReport.String(),
+		},
+	})
+
+	ctx.CheckbuildFile(htmlReport)
+	ctx.CheckbuildFile(xmlReport)
+	ctx.CheckbuildFile(lcovReport)
+}
+
+func (r *JacocoReport) OutputFiles(tag string) (android.Paths, error) {
+	switch tag {
+	case "", ".html":
+		return android.Paths{r.htmlReport}, nil
+	case ".xml":
+		return android.Paths{r.xmlReport}, nil
+	case ".lcov":
+		return android.Paths{r.lcovReport}, nil
+	default:
+		return nil, fmt.Errorf("unsupported module reference tag %q", tag)
+	}
+}
+
+var _ android.OutputFileProducer = (*JacocoReport)(nil)
+
+// java_coverage_report generates the coverage reports of java modules from the .exec files written
+// by jacoco while running their tests.  It writes a zip of the HTML report, and XML and LCOV
+// reports, which can be referenced with the .html, .xml and .lcov tags.
+func JacocoReportFactory() android.Module {
+	module := &JacocoReport{}
+	module.AddProperties(&module.properties)
+	android.InitAndroidArchModule(module, android.DeviceSupported, android.MultilibCommon)
+	return module
+}
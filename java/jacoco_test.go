@@ -14,7 +14,11 @@
 
 package java
 
-import "testing"
+import (
+	"testing"
+
+	"android/soong/android"
+)
 
 func TestJacocoFilterToSpecs(t *testing.T) {
 	testCases := []struct {
@@ -103,3 +107,56 @@ func TestJacocoFiltersToZipCommand(t *testing.T) {
 		})
 	}
 }
+
+const jacocoReportBp = `
+	android_app {
+		name: "foo",
+		srcs: ["a.java", "b.java"],
+		sdk_version: "current",
+		jacoco: {
+			include_filter: ["com.android.foo.**"],
+		},
+	}
+
+	java_coverage_report {
+		name: "foo_coverage",
+		exec_files: ["foo.exec"],
+		libs: ["foo"],
+	}
+`
+
+func TestJacocoReport(t *testing.T) {
+	result := android.GroupFixturePreparers(
+		PrepareForTestWithJavaDefaultModules,
+		android.FixtureMergeEnv(map[string]string{"EMMA_INSTRUMENT": "true"}),
+		android.FixtureAddFile("foo.exec", nil),
+	).RunTestWithBp(t, jacocoReportBp)
+
+	module := result.ModuleForTests("foo_coverage", "android_common")
+	report := module.Rule("jacocoReport")
+
+	android.AssertPathsRelativeToTopEquals(t, "exec files", []string{"foo.exec"}, report.Inputs)
+	android.AssertStringEquals(t, "class files",
+		"--classfiles out/soong/.intermediates/foo/android_common/jacoco-report-classes/foo.jar",
+		report.Args["classFiles"])
+	android.AssertPathRelativeToTopEquals(t, "html report",
+		"out/soong/.intermediates/foo_coverage/android_common/foo_coverage-html.zip", report.Output)
+	android.AssertPathsRelativeToTopEquals(t, "xml and lcov reports", []string{
+		"out/soong/.intermediates/foo_coverage/android_common/foo_coverage.xml",
+		"out/soong/.intermediates/foo_coverage/android_common/foo_coverage.lcov",
+	}, report.ImplicitOutputs.Paths())
+
+	sources := android.ContentFromFileRuleForTests(t, module.Output("jacoco_report/sources.txt"))
+	android.AssertStringEquals(t, "sources", "a.java b.java\n", sources)
+}
+
+func TestJacocoReportNotInstrumented(t *testing.T) {
+	result := android.GroupFixturePreparers(
+		PrepareForTestWithJavaDefaultModules,
+		android.FixtureAddFile("foo.exec", nil),
+	).RunTestWithBp(t, jacocoReportBp)
+
+	html := result.ModuleForTests("foo_coverage", "android_common").Output("foo_coverage-html.zip")
+	android.AssertSame(t, "rule", android.ErrorRule, html.Rule)
+	android.AssertStringDoesContain(t, "error", html.Args["error"], "foo are not instrumented by jacoco")
+}
@@ -67,6 +67,7 @@ func registerJavaBuildComponents(ctx android.RegistrationContext) {
 
 	ctx.RegisterSingletonType("logtags", LogtagsSingleton)
 	ctx.RegisterSingletonType("kythe_java_extract", kytheExtractJavaFactory)
+	ctx.RegisterSingletonType("r8_mappings", r8MappingsSingletonFactory)
 }
 
 func RegisterJavaSdkMemberTypes() {
@@ -263,6 +264,7 @@ var (
 	pluginTag               = dependencyTag{name: "plugin"}
 	errorpronePluginTag     = dependencyTag{name: "errorprone-plugin"}
 	exportedPluginTag       = dependencyTag{name: "exported-plugin"}
+	kspPluginTag            = dependencyTag{name: "ksp-plugin"}
 	bootClasspathTag        = dependencyTag{name: "bootclasspath"}
 	systemModulesTag        = dependencyTag{name: "system modules"}
 	frameworkResTag         = dependencyTag{name: "framework-res"}
@@ -354,6 +356,7 @@ type deps struct {
 	java9Classpath          classpath
 	bootClasspath           classpath
 	processorPath           classpath
+	kspProcessorPath        classpath
 	errorProneProcessorPath classpath
 	processorClasses        []string
 	staticJars              android.Paths
@@ -28,17 +28,24 @@ import (
 
 var kotlinc = pctx.AndroidRemoteStaticRule("kotlinc", android.RemoteRuleSupports{Goma: true},
 	blueprint.RuleParams{
-		Command: `rm -rf "$classesDir" "$srcJarDir" "$kotlinBuildFile" "$emptyDir" && ` +
-			`mkdir -p "$classesDir" "$srcJarDir" "$emptyDir" && ` +
+		Command: `rm -rf "$classesDir" "$headerClassesDir" "$srcJarDir" "$kotlinSrcJarDir" "$kotlinBuildFile" "$emptyDir" && ` +
+			`mkdir -p "$classesDir" "$headerClassesDir" "$srcJarDir" "$kotlinSrcJarDir" "$emptyDir" && ` +
 			`${config.ZipSyncCmd} -d $srcJarDir -l $srcJarDir/list -f "*.java" $srcJars && ` +
+			`${config.ZipSyncCmd} -d $kotlinSrcJarDir -l $kotlinSrcJarDir/list -f "*.kt" $kotlinSrcJars && ` +
 			`${config.GenKotlinBuildFileCmd} --classpath "$classpath" --name "$name"` +
 			` --out_dir "$classesDir" --srcs "$out.rsp" --srcs "$srcJarDir/list"` +
+			` --srcs "$kotlinSrcJarDir/list"` +
 			` $commonSrcFilesArg --out "$kotlinBuildFile" && ` +
 			`${config.KotlincCmd} ${config.KotlincSuppressJDK9Warnings} ${config.JavacHeapFlags} ` +
 			`$kotlincFlags -jvm-target $kotlinJvmTarget -Xbuild-file=$kotlinBuildFile ` +
-			`-kotlin-home $emptyDir && ` +
+			`-kotlin-home $emptyDir ` +
+			`-Xplugin=${config.KotlinAbiGenPluginJar} ` +
+			`-P plugin:org.jetbrains.kotlin.jvm.abi:outputDir=$headerClassesDir && ` +
 			`${config.SoongZipCmd} -jar -o $out -C $classesDir -D $classesDir && ` +
-			`rm -rf "$srcJarDir"`,
+			`${config.SoongZipCmd} -jar -o $headerJar.tmp -C $headerClassesDir -D $headerClassesDir && ` +
+			`${config.Ziptime} $headerJar.tmp && ` +
+			`(if cmp -s $headerJar.tmp $headerJar ; then rm $headerJar.tmp ; else mv $headerJar.tmp $headerJar ; fi ) && ` +
+			`rm -rf "$srcJarDir" "$kotlinSrcJarDir"`,
 		CommandDeps: []string{
 			"${config.KotlincCmd}",
 			"${config.KotlinCompilerJar}",
@@ -48,15 +55,21 @@ var kotlinc = pctx.AndroidRemoteStaticRule("kotlinc", android.RemoteRuleSupports
 			"${config.KotlinStdlibJar}",
 			"${config.KotlinTrove4jJar}",
 			"${config.KotlinAnnotationJar}",
+			"${config.KotlinAbiGenPluginJar}",
 			"${config.GenKotlinBuildFileCmd}",
 			"${config.SoongZipCmd}",
 			"${config.ZipSyncCmd}",
+			"${config.Ziptime}",
 		},
 		Rspfile:        "$out.rsp",
 		RspfileContent: `$in`,
+		// The header jar is only replaced when the ABI changes, so that dependents that only
+		// compile against it are not rebuilt.
+		Restat: true,
 	},
-	"kotlincFlags", "classpath", "srcJars", "commonSrcFilesArg", "srcJarDir", "classesDir",
-	"kotlinJvmTarget", "kotlinBuildFile", "emptyDir", "name")
+	"kotlincFlags", "classpath", "srcJars", "kotlinSrcJars", "commonSrcFilesArg", "srcJarDir",
+	"kotlinSrcJarDir", "classesDir", "headerClassesDir", "headerJar", "kotlinJvmTarget", "kotlinBuildFile",
+	"emptyDir", "name")
 
 func kotlinCommonSrcsList(ctx android.ModuleContext, commonSrcFiles android.Paths) android.OptionalPath {
 	if len(commonSrcFiles) > 0 {
@@ -75,13 +88,18 @@ func kotlinCommonSrcsList(ctx android.ModuleContext, commonSrcFiles android.Path
 }
 
 // kotlinCompile takes .java and .kt sources and srcJars, and compiles the .kt sources into a classes jar in outputFile.
-func kotlinCompile(ctx android.ModuleContext, outputFile android.WritablePath,
-	srcFiles, commonSrcFiles, srcJars android.Paths,
+// The jvm-abi-gen plugin also writes the ABI of the classes, without private declarations or the bodies of non-inline
+// functions, into a header jar in headerOutputFile, so that dependents are only recompiled when the ABI changes.
+// Only the .java sources of srcJars are extracted, the .kt sources of kotlinSrcJars, like the sources generated by
+// KSP, are also compiled.
+func kotlinCompile(ctx android.ModuleContext, outputFile, headerOutputFile android.WritablePath,
+	srcFiles, commonSrcFiles, srcJars, kotlinSrcJars android.Paths,
 	flags javaBuilderFlags) {
 
 	var deps android.Paths
 	deps = append(deps, flags.kotlincClasspath...)
 	deps = append(deps, srcJars...)
+	deps = append(deps, kotlinSrcJars...)
 	deps = append(deps, commonSrcFiles...)
 
 	kotlinName := filepath.Join(ctx.ModuleDir(), ctx.ModuleSubDir(), ctx.ModuleName())
@@ -95,18 +113,23 @@ func kotlinCompile(ctx android.ModuleContext, outputFile android.WritablePath,
 	}
 
 	ctx.Build(pctx, android.BuildParams{
-		Rule:        kotlinc,
-		Description: "kotlinc",
-		Output:      outputFile,
-		Inputs:      srcFiles,
-		Implicits:   deps,
+		Rule:           kotlinc,
+		Description:    "kotlinc",
+		Output:         outputFile,
+		ImplicitOutput: headerOutputFile,
+		Inputs:         srcFiles,
+		Implicits:      deps,
 		Args: map[string]string{
 			"classpath":         flags.kotlincClasspath.FormJavaClassPath(""),
 			"kotlincFlags":      flags.kotlincFlags,
 			"commonSrcFilesArg": commonSrcFilesArg,
 			"srcJars":           strings.Join(srcJars.Strings(), " "),
+			"kotlinSrcJars":     strings.Join(kotlinSrcJars.Strings(), " "),
 			"classesDir":        android.PathForModuleOut(ctx, "kotlinc", "classes").String(),
+			"headerClassesDir":  android.PathForModuleOut(ctx, "kotlinc", "header_classes").String(),
+			"headerJar":         headerOutputFile.String(),
 			"srcJarDir":         android.PathForModuleOut(ctx, "kotlinc", "srcJars").String(),
+			"kotlinSrcJarDir":   android.PathForModuleOut(ctx, "kotlinc", "kotlinSrcJars").String(),
 			"kotlinBuildFile":   android.PathForModuleOut(ctx, "kotlinc-build.xml").String(),
 			"emptyDir":          android.PathForModuleOut(ctx, "kotlinc", "empty").String(),
 			// http://b/69160377 kotlinc only supports -jvm-target 1.6 and 1.8
@@ -218,6 +241,93 @@ func kotlinKapt(ctx android.ModuleContext, srcJarOutputFile, resJarOutputFile an
 	})
 }
 
+var ksp = pctx.AndroidRemoteStaticRule("ksp", android.RemoteRuleSupports{Goma: true},
+	blueprint.RuleParams{
+		Command: `rm -rf "$srcJarDir" "$kotlinBuildFile" "$kspDir" && ` +
+			`mkdir -p "$srcJarDir" "$kspDir/sources" "$kspDir/classes" && ` +
+			`${config.ZipSyncCmd} -d $srcJarDir -l $srcJarDir/list -f "*.java" $srcJars && ` +
+			`${config.GenKotlinBuildFileCmd} --classpath "$classpath" --name "$name"` +
+			` --srcs "$out.rsp" --srcs "$srcJarDir/list"` +
+			` $commonSrcFilesArg --out "$kotlinBuildFile" && ` +
+			`${config.KotlincCmd} ${config.KotlincSuppressJDK9Warnings} ` +
+			`${config.JavacHeapFlags} $kotlincFlags ` +
+			`-Xplugin=${config.KotlinKspJar} -Xplugin=${config.KotlinKspApiJar} ` +
+			`-P plugin:com.google.devtools.ksp.symbol-processing:projectBaseDir=$kspDir ` +
+			`-P plugin:com.google.devtools.ksp.symbol-processing:kotlinOutputDir=$kspDir/sources ` +
+			`-P plugin:com.google.devtools.ksp.symbol-processing:javaOutputDir=$kspDir/sources ` +
+			`-P plugin:com.google.devtools.ksp.symbol-processing:classOutputDir=$kspDir/classes ` +
+			`-P plugin:com.google.devtools.ksp.symbol-processing:resourceOutputDir=$kspDir/classes ` +
+			`-P plugin:com.google.devtools.ksp.symbol-processing:kspOutputDir=$kspDir ` +
+			`-P plugin:com.google.devtools.ksp.symbol-processing:cachesDir=$kspDir/caches ` +
+			`-P plugin:com.google.devtools.ksp.symbol-processing:incremental=false ` +
+			`$kspProcessorPath ` +
+			`-Xbuild-file=$kotlinBuildFile && ` +
+			`${config.SoongZipCmd} -jar -o $out -C $kspDir/sources -D $kspDir/sources && ` +
+			`${config.SoongZipCmd} -jar -o $classesJarOut -C $kspDir/classes -D $kspDir/classes && ` +
+			`rm -rf "$srcJarDir"`,
+		CommandDeps: []string{
+			"${config.KotlincCmd}",
+			"${config.KotlinCompilerJar}",
+			"${config.KotlinKspJar}",
+			"${config.KotlinKspApiJar}",
+			"${config.GenKotlinBuildFileCmd}",
+			"${config.SoongZipCmd}",
+			"${config.ZipSyncCmd}",
+		},
+		Rspfile:        "$out.rsp",
+		RspfileContent: `$in`,
+	},
+	"kotlincFlags", "kspProcessorPath", "classpath", "srcJars", "commonSrcFilesArg", "srcJarDir",
+	"kspDir", "kotlinBuildFile", "name", "classesJarOut")
+
+// kotlinKsp runs Kotlin Symbol Processing processors.  It takes .kt and .java sources and srcjars, and runs the KSP
+// processors over all of them, producing a srcjar of generated code in srcJarOutputFile and a jar of generated
+// classes and resources in resJarOutputFile.  Unlike kapt, KSP doesn't generate Java stubs, and doesn't affect the
+// annotation processing of the javac rule.
+func kotlinKsp(ctx android.ModuleContext, srcJarOutputFile, resJarOutputFile android.WritablePath,
+	srcFiles, commonSrcFiles, srcJars android.Paths,
+	flags javaBuilderFlags) {
+
+	var deps android.Paths
+	deps = append(deps, flags.kotlincClasspath...)
+	deps = append(deps, srcJars...)
+	deps = append(deps, flags.kspProcessorPath...)
+	deps = append(deps, commonSrcFiles...)
+
+	commonSrcsList := kotlinCommonSrcsList(ctx, commonSrcFiles)
+	commonSrcFilesArg := ""
+	if commonSrcsList.Valid() {
+		deps = append(deps, commonSrcsList.Path())
+		commonSrcFilesArg = "--common_srcs " + commonSrcsList.String()
+	}
+
+	kspProcessorPath := flags.kspProcessorPath.FormRepeatedClassPath("-P plugin:com.google.devtools.ksp.symbol-processing:apclasspath=")
+
+	kotlinName := filepath.Join(ctx.ModuleDir(), ctx.ModuleSubDir(), ctx.ModuleName())
+	kotlinName = strings.ReplaceAll(kotlinName, "/", "__")
+
+	ctx.Build(pctx, android.BuildParams{
+		Rule:           ksp,
+		Description:    "ksp",
+		Output:         srcJarOutputFile,
+		ImplicitOutput: resJarOutputFile,
+		Inputs:         srcFiles,
+		Implicits:      deps,
+		Args: map[string]string{
+			"classpath":         flags.kotlincClasspath.FormJavaClassPath(""),
+			"kotlincFlags":      flags.kotlincFlags,
+			"commonSrcFilesArg": commonSrcFilesArg,
+			"srcJars":           strings.Join(srcJars.Strings(), " "),
+			"srcJarDir":         android.PathForModuleOut(ctx, "ksp", "srcJars").String(),
+			"kotlinBuildFile":   android.PathForModuleOut(ctx, "ksp", "build.xml").String(),
+			"kspProcessorPath":  strings.Join(kspProcessorPath, " "),
+			"kspDir":            android.PathForModuleOut(ctx, "ksp/gen").String(),
+			"name":              kotlinName,
+			"classesJarOut":     resJarOutputFile.String(),
+		},
+	})
+}
+
 // kapt converts a list of key, value pairs into a base64 encoded Java serialization, which is what kapt expects.
 func kaptEncodeFlags(options [][2]string) string {
 	buf := &bytes.Buffer{}
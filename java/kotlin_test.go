@@ -65,6 +65,30 @@ func TestKotlin(t *testing.T) {
 	}
 
 	fooHeaderJar := ctx.ModuleForTests("foo", "android_common").Output("turbine-combined/foo.jar")
+
+	// Test that dependents compile against the kotlin header jar instead of the kotlin classes
+	fooKotlinHeaderJar := fooKotlinc.ImplicitOutput.String()
+	if fooKotlinc.Args["headerJar"] != fooKotlinHeaderJar {
+		t.Errorf("expected kotlinc header jar %q, got %q", fooKotlinHeaderJar, fooKotlinc.Args["headerJar"])
+	}
+	if !inList(fooKotlinHeaderJar, fooHeaderJar.Inputs.Strings()) {
+		t.Errorf("foo header jar inputs %v does not contain %q",
+			fooHeaderJar.Inputs.Strings(), fooKotlinHeaderJar)
+	}
+	if inList(fooKotlinc.Output.String(), fooHeaderJar.Inputs.Strings()) {
+		t.Errorf("foo header jar inputs %v unexpectedly contains %q",
+			fooHeaderJar.Inputs.Strings(), fooKotlinc.Output.String())
+	}
+
+	// Test that the kotlin header jar is only replaced when the ABI changes
+	if !fooKotlinc.RuleParams.Restat {
+		t.Errorf("expected kotlinc rule to restat")
+	}
+	expectedHeaderJarCmd := `(if cmp -s $headerJar.tmp $headerJar ; then rm $headerJar.tmp ; else mv $headerJar.tmp $headerJar ; fi )`
+	if !strings.Contains(fooKotlinc.RuleParams.Command, expectedHeaderJarCmd) {
+		t.Errorf("expected %q in kotlinc command %q", expectedHeaderJarCmd, fooKotlinc.RuleParams.Command)
+	}
+
 	bazHeaderJar := ctx.ModuleForTests("baz", "android_common").Output("turbine-combined/baz.jar")
 	barKotlinc := ctx.ModuleForTests("bar", "android_common").Rule("kotlinc")
 
@@ -223,6 +247,131 @@ func TestKapt(t *testing.T) {
 	})
 }
 
+func TestKsp(t *testing.T) {
+	ctx, _ := testJava(t, `
+		java_library {
+			name: "foo",
+			srcs: ["a.java", "b.kt"],
+			ksp_plugins: ["bar"],
+			plugins: ["baz"],
+		}
+
+		java_plugin {
+			name: "bar",
+			srcs: ["b.java"],
+		}
+
+		java_plugin {
+			name: "baz",
+			processor_class: "com.baz",
+			srcs: ["b.java"],
+		}
+	`)
+
+	buildOS := android.BuildOs.String()
+
+	foo := ctx.ModuleForTests("foo", "android_common")
+	ksp := foo.Rule("ksp")
+	kapt := foo.Rule("kapt")
+	kotlinc := foo.Rule("kotlinc")
+	javac := foo.Rule("javac")
+
+	bar := ctx.ModuleForTests("bar", buildOS+"_common").Rule("javac").Output.String()
+	baz := ctx.ModuleForTests("baz", buildOS+"_common").Rule("javac").Output.String()
+
+	// Test that the kotlin and java sources are passed to ksp
+	if len(ksp.Inputs) != 2 || ksp.Inputs[0].String() != "a.java" || ksp.Inputs[1].String() != "b.kt" {
+		t.Errorf(`foo ksp inputs %v != ["a.java", "b.kt"]`, ksp.Inputs)
+	}
+
+	// Test that only the ksp plugins are passed to ksp, and only the other plugins to kapt
+	expectedKspProcessorPath := "-P plugin:com.google.devtools.ksp.symbol-processing:apclasspath=" + bar
+	if ksp.Args["kspProcessorPath"] != expectedKspProcessorPath {
+		t.Errorf("expected kspProcessorPath %q, got %q", expectedKspProcessorPath, ksp.Args["kspProcessorPath"])
+	}
+	expectedKaptProcessorPath := "-P plugin:org.jetbrains.kotlin.kapt3:apclasspath=" + baz
+	if kapt.Args["kaptProcessorPath"] != expectedKaptProcessorPath {
+		t.Errorf("expected kaptProcessorPath %q, got %q", expectedKaptProcessorPath, kapt.Args["kaptProcessorPath"])
+	}
+
+	// Test that the ksp srcjar is extracted by the kapt, kotlinc and javac rules
+	kspSrcJar := ksp.Output.String()
+	if kapt.Args["srcJars"] != kspSrcJar {
+		t.Errorf("expected %q in kapt srcjars %v", kspSrcJar, kapt.Args["srcJars"])
+	}
+	expectedSrcJars := kspSrcJar + " " + kapt.Output.String()
+	if kotlinc.Args["srcJars"] != expectedSrcJars {
+		t.Errorf("expected kotlinc srcjars %q, got %q", expectedSrcJars, kotlinc.Args["srcJars"])
+	}
+	if javac.Args["srcJars"] != expectedSrcJars {
+		t.Errorf("expected javac srcjars %q, got %q", expectedSrcJars, javac.Args["srcJars"])
+	}
+	if !inList(kspSrcJar, javac.Implicits.Strings()) {
+		t.Errorf("expected %q in javac implicits %v", kspSrcJar, javac.Implicits.Strings())
+	}
+}
+
+func TestKspKotlinSources(t *testing.T) {
+	ctx, _ := testJava(t, `
+		java_library {
+			name: "foo",
+			srcs: ["a.kt"],
+			ksp_plugins: ["bar"],
+		}
+
+		java_library {
+			name: "baz",
+			srcs: ["b.kt"],
+		}
+
+		java_plugin {
+			name: "bar",
+			srcs: ["b.java"],
+		}
+	`)
+
+	foo := ctx.ModuleForTests("foo", "android_common")
+	kspSrcJar := foo.Rule("ksp").Output.String()
+	kotlinc := foo.Rule("kotlinc")
+
+	// Test that the .kt sources generated by ksp are extracted and compiled by kotlinc
+	if kotlinc.Args["kotlinSrcJars"] != kspSrcJar {
+		t.Errorf("expected kotlinc kotlinSrcJars %q, got %q", kspSrcJar, kotlinc.Args["kotlinSrcJars"])
+	}
+	if !inList(kspSrcJar, kotlinc.Implicits.Strings()) {
+		t.Errorf("expected %q in kotlinc implicits %v", kspSrcJar, kotlinc.Implicits.Strings())
+	}
+	for _, s := range []string{
+		`-l $kotlinSrcJarDir/list -f "*.kt" $kotlinSrcJars`,
+		`--srcs "$kotlinSrcJarDir/list"`,
+	} {
+		if !strings.Contains(kotlinc.RuleParams.Command, s) {
+			t.Errorf("expected %q in kotlinc command %q", s, kotlinc.RuleParams.Command)
+		}
+	}
+
+	// Test that no .kt sources are extracted from the srcjars of modules without ksp
+	baz := ctx.ModuleForTests("baz", "android_common").Rule("kotlinc")
+	if baz.Args["kotlinSrcJars"] != "" {
+		t.Errorf("expected no kotlinc kotlinSrcJars, got %q", baz.Args["kotlinSrcJars"])
+	}
+}
+
+func TestKspWithoutKotlin(t *testing.T) {
+	testJavaError(t, `ksp_plugins: KSP processors run through kotlinc, and require Kotlin sources`, `
+		java_library {
+			name: "foo",
+			srcs: ["a.java"],
+			ksp_plugins: ["bar"],
+		}
+
+		java_plugin {
+			name: "bar",
+			srcs: ["b.java"],
+		}
+	`)
+}
+
 func TestKaptEncodeFlags(t *testing.T) {
 	// Compares the kaptEncodeFlags against the results of the example implementation at
 	// https://kotlinlang.org/docs/reference/kapt.html#apjavac-options-encoding
@@ -16,18 +16,26 @@ package java
 
 import (
 	"fmt"
+	"hash/fnv"
 	"io"
+	"path/filepath"
 	"strconv"
 	"strings"
 
+	"github.com/google/blueprint"
+
 	"android/soong/android"
 	"android/soong/java/config"
 	"android/soong/tradefed"
 )
 
 func init() {
-	android.RegisterModuleType("android_robolectric_test", RobolectricTestFactory)
-	android.RegisterModuleType("android_robolectric_runtimes", robolectricRuntimesFactory)
+	registerRobolectricBuildComponents(android.InitRegistrationContext)
+}
+
+func registerRobolectricBuildComponents(ctx android.RegistrationContext) {
+	ctx.RegisterModuleType("android_robolectric_test", RobolectricTestFactory)
+	ctx.RegisterModuleType("android_robolectric_runtimes", robolectricRuntimesFactory)
 }
 
 var robolectricDefaultLibs = []string{
@@ -45,6 +53,16 @@ var (
 	roboRuntimesTag     = dependencyTag{name: "roboRuntimes"}
 )
 
+// robolectricRun runs a shard of the tests on the host JVM, writing the results as JUnit XML.
+var robolectricRun = pctx.AndroidStaticRule("robolectricRun",
+	blueprint.RuleParams{
+		Command: `rm -f $out && ` +
+			`XML_OUTPUT_FILE=$out $timeout${config.JavaCmd} ${config.JavaVmFlags} -cp $classpath ` +
+			`-Drobolectric.offline=true $dependencyDirFlag ` +
+			`com.android.junitxml.JUnitXmlRunner $tests`,
+		CommandDeps: []string{"${config.JavaCmd}"},
+	}, "classpath", "dependencyDirFlag", "tests", "timeout")
+
 type robolectricProperties struct {
 	// The name of the android_app module that the tests will run against.
 	Instrumentation_for *string
@@ -56,7 +74,9 @@ type robolectricProperties struct {
 		// Timeout in seconds when running the tests.
 		Timeout *int64
 
-		// Number of shards to use when running the tests.
+		// Number of shards to use when running the tests.  The test classes are assigned to the
+		// shards by the hash of their name, so that adding a test class doesn't move the other
+		// test classes to other shards.
 		Shards *int64
 	}
 
@@ -74,6 +94,9 @@ type robolectricTest struct {
 	libs  []string
 	tests []string
 
+	// testShards contains the test classes of each shard, some shards may be empty.
+	testShards [][]string
+
 	manifest    android.Path
 	resourceApk android.Path
 
@@ -187,8 +210,25 @@ func (r *robolectricTest) GenerateAndroidBuildActions(ctx android.ModuleContext)
 
 	r.data = append(r.data, r.manifest, r.resourceApk)
 
+	numShards := 1
+	if s := r.robolectricProperties.Test_options.Shards; s != nil && *s > 1 {
+		numShards = int(*s)
+	}
+	r.testShards = shardTestsByHash(r.tests, numShards)
+
 	runtimes := ctx.GetDirectDepWithTag("robolectric-android-all-prebuilts", roboRuntimesTag)
 
+	// The classpath of the tests run in the build uses the test config from r.extraResources,
+	// whose paths are relative to the top of the tree instead of the installed test directory.
+	classpath := android.Paths{r.outputFile, instrumentedApp.implementationAndResourcesJar}
+	for _, dep := range ctx.GetDirectDepsWithTag(libTag) {
+		m := ctx.OtherModuleProvider(dep, JavaInfoProvider).(JavaInfo)
+		if !android.InList(ctx.OtherModuleName(dep), config.FrameworkLibraries) {
+			classpath = append(classpath, m.ImplementationAndResourcesJars...)
+		}
+	}
+	r.generateRunRules(ctx, classpath, runtimes.(*robolectricRuntimes).runtimes)
+
 	installPath := android.PathForModuleInstall(ctx, r.BaseModuleName())
 
 	installedResourceApk := ctx.InstallFile(installPath, ctx.ModuleName()+".apk", r.resourceApk)
@@ -209,6 +249,78 @@ func (r *robolectricTest) GenerateAndroidBuildActions(ctx android.ModuleContext)
 	ctx.InstallFile(installPath, ctx.ModuleName()+".jar", r.combinedJar, installDeps...)
 }
 
+// shardTestsByHash assigns each test to one of numShards shards by the hash of its name.
+func shardTestsByHash(tests []string, numShards int) [][]string {
+	shards := make([][]string, numShards)
+	for _, test := range tests {
+		h := fnv.New32a()
+		h.Write([]byte(test))
+		shard := int(h.Sum32() % uint32(numShards))
+		shards[shard] = append(shards[shard], test)
+	}
+	return shards
+}
+
+// testClassName converts the path of a test source file relative to its source root to the name of
+// its class.
+func testClassName(test string) string {
+	return strings.Replace(strings.TrimSuffix(test, ".java"), "/", ".", -1)
+}
+
+// generateRunRules creates a rule for each shard that runs its tests on the host JVM and writes
+// the results to a JUnit XML file, and the <name>-robo-run phony target that runs all the shards.
+func (r *robolectricTest) generateRunRules(ctx android.ModuleContext, classpath android.Paths,
+	runtimes []android.InstallPath) {
+
+	// The test config in the classpath points to the manifest and resources of the instrumented app.
+	implicits := append(android.Paths{r.manifest, r.resourceApk}, classpath...)
+	dependencyDirFlag := ""
+	for _, runtime := range runtimes {
+		dependencyDirFlag = "-Drobolectric.dependency.dir=" + filepath.Dir(runtime.String())
+		implicits = append(implicits, runtime)
+	}
+
+	timeout := ""
+	if t := r.robolectricProperties.Test_options.Timeout; t != nil {
+		timeout = fmt.Sprintf("timeout %d ", *t)
+	}
+
+	name := ctx.ModuleName() + "-robo-run"
+	var results android.Paths
+	for i, shard := range r.testShards {
+		if len(shard) == 0 {
+			continue
+		}
+
+		var classes []string
+		for _, test := range shard {
+			classes = append(classes, testClassName(test))
+		}
+
+		result := android.PathForModuleOut(ctx, "robo-run", "shard"+strconv.Itoa(i), "test_results.xml")
+		ctx.Build(pctx, android.BuildParams{
+			Rule:        robolectricRun,
+			Description: fmt.Sprintf("robolectric %s shard %d", ctx.ModuleName(), i),
+			Output:      result,
+			Implicits:   implicits,
+			Args: map[string]string{
+				"classpath":         strings.Join(classpath.Strings(), ":"),
+				"dependencyDirFlag": dependencyDirFlag,
+				"tests":             strings.Join(classes, " "),
+				"timeout":           timeout,
+			},
+		})
+		if len(r.testShards) > 1 {
+			ctx.Phony(name+"-"+strconv.Itoa(i), result)
+		}
+		results = append(results, result)
+	}
+
+	if len(results) > 0 {
+		ctx.Phony(name, results...)
+	}
+}
+
 func generateRoboTestConfig(ctx android.ModuleContext, outputFile android.WritablePath,
 	instrumentedApp *AndroidApp) {
 	rule := android.NewRuleBuilder(pctx, ctx)
@@ -273,20 +385,23 @@ func (r *robolectricTest) AndroidMkEntries() []android.AndroidMkEntries {
 
 	entries.ExtraFooters = []android.AndroidMkExtraFootersFunc{
 		func(w io.Writer, name, prefix, moduleDir string) {
-			if s := r.robolectricProperties.Test_options.Shards; s != nil && *s > 1 {
-				numShards := int(*s)
-				shardSize := (len(r.tests) + numShards - 1) / numShards
-				shards := android.ShardStrings(r.tests, shardSize)
-				for i, shard := range shards {
-					r.writeTestRunner(w, name, "Run"+name+strconv.Itoa(i), shard)
+			if len(r.testShards) > 1 {
+				var runners []string
+				for i, shard := range r.testShards {
+					if len(shard) == 0 {
+						continue
+					}
+					runner := "Run" + name + strconv.Itoa(i)
+					r.writeTestRunner(w, name, runner, shard)
+					runners = append(runners, runner)
 				}
 
 				// TODO: add rules to dist the outputs of the individual tests, or combine them together?
 				fmt.Fprintln(w, "")
 				fmt.Fprintln(w, ".PHONY:", "Run"+name)
 				fmt.Fprintln(w, "Run"+name, ": \\")
-				for i := range shards {
-					fmt.Fprintln(w, "   ", "Run"+name+strconv.Itoa(i), "\\")
+				for _, runner := range runners {
+					fmt.Fprintln(w, "   ", runner, "\\")
 				}
 				fmt.Fprintln(w, "")
 			} else {
@@ -321,6 +436,9 @@ func (r *robolectricTest) writeTestRunner(w io.Writer, module, name string, test
 // instead of on a device.  It also generates a rule with the name of the module prefixed with "Run" that can be
 // used to run the tests.  Running the tests with build rule will eventually be deprecated and replaced with atest.
 //
+// The <name>-robo-run target runs the tests on the host JVM without Make or tradefed, and writes the results of
+// each shard to a JUnit XML file.  When test_options.shards is set, <name>-robo-run-<N> runs only shard N.
+//
 // The test runner considers any file listed in srcs whose name ends with Test.java to be a test class, unless
 // it is named BaseRobolectricTest.java.  The path to the each source file must exactly match the package
 // name, or match the package name when the prefix "src/" is removed.
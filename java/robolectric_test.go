@@ -0,0 +1,121 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package java
+
+import (
+	"testing"
+
+	"android/soong/android"
+)
+
+func TestShardTestsByHash(t *testing.T) {
+	tests := []string{
+		"com/android/FooTest.java",
+		"com/android/BarTest.java",
+		"com/android/BazTest.java",
+		"com/android/QuxTest.java",
+	}
+
+	android.AssertDeepEquals(t, "2 shards", [][]string{
+		{"com/android/BarTest.java", "com/android/BazTest.java"},
+		{"com/android/FooTest.java", "com/android/QuxTest.java"},
+	}, shardTestsByHash(tests, 2))
+
+	// Removing a test doesn't move the other tests to other shards.
+	android.AssertDeepEquals(t, "2 shards without FooTest", [][]string{
+		{"com/android/BarTest.java", "com/android/BazTest.java"},
+		{"com/android/QuxTest.java"},
+	}, shardTestsByHash(tests[1:], 2))
+
+	android.AssertDeepEquals(t, "1 shard", [][]string{tests}, shardTestsByHash(tests, 1))
+}
+
+const robolectricTestBp = `
+	android_app {
+		name: "robo-app",
+		srcs: ["app/App.java"],
+		platform_apis: true,
+	}
+
+	java_library {
+		name: "Robolectric_all-target",
+		srcs: ["Robolectric.java"],
+	}
+
+	java_library {
+		name: "mockito-robolectric-prebuilt",
+		srcs: ["Mockito.java"],
+	}
+
+	java_library {
+		name: "truth-prebuilt",
+		srcs: ["Truth.java"],
+	}
+
+	java_library {
+		name: "junitxml",
+		srcs: ["JUnitXml.java"],
+	}
+
+	android_robolectric_runtimes {
+		name: "robolectric-android-all-prebuilts",
+		jars: ["android-all/android-all-R-robolectric-r0.jar"],
+	}
+
+	android_robolectric_test {
+		name: "RoboTests",
+		srcs: [
+			"src/com/android/FooTest.java",
+			"src/com/android/BarTest.java",
+			"src/com/android/BazTest.java",
+			"src/com/android/QuxTest.java",
+			"src/com/android/TestUtils.java",
+		],
+		instrumentation_for: "robo-app",
+		test_options: {
+			shards: 2,
+			timeout: 600,
+		},
+	}
+`
+
+func TestRobolectricRun(t *testing.T) {
+	result := android.GroupFixturePreparers(
+		PrepareForTestWithJavaDefaultModules,
+		android.FixtureAddFile("android-all/android-all-R-robolectric-r0.jar", nil),
+	).RunTestWithBp(t, robolectricTestBp)
+
+	module := result.ModuleForTests("RoboTests", "android_common")
+
+	shard0 := module.Output("robo-run/shard0/test_results.xml")
+	android.AssertStringEquals(t, "shard 0 tests", "com.android.BarTest com.android.BazTest",
+		shard0.Args["tests"])
+	android.AssertStringEquals(t, "timeout", "timeout 600 ", shard0.Args["timeout"])
+	android.AssertStringDoesContain(t, "dependency dir", shard0.Args["dependencyDirFlag"],
+		"-Drobolectric.dependency.dir=")
+	android.AssertStringDoesContain(t, "dependency dir", shard0.Args["dependencyDirFlag"],
+		"/android-all")
+
+	classpath := shard0.Args["classpath"]
+	android.AssertStringDoesContain(t, "classpath", classpath,
+		"out/soong/.intermediates/RoboTests/android_common/")
+	android.AssertStringDoesContain(t, "classpath", classpath,
+		"out/soong/.intermediates/junitxml/android_common/")
+	android.AssertStringDoesNotContain(t, "classpath", classpath, "samedir_config.jar")
+
+	shard1 := module.Output("robo-run/shard1/test_results.xml")
+	android.AssertStringEquals(t, "shard 1 tests", "com.android.FooTest com.android.QuxTest",
+		shard1.Args["tests"])
+}
@@ -245,9 +245,11 @@ func registerRequiredBuildComponentsForTest(ctx android.RegistrationContext) {
 	RegisterDexpreoptBootJarsComponents(ctx)
 	RegisterDocsBuildComponents(ctx)
 	RegisterGenRuleBuildComponents(ctx)
+	registerJacocoReportBuildComponents(ctx)
 	registerJavaBuildComponents(ctx)
 	registerPlatformBootclasspathBuildComponents(ctx)
 	RegisterPrebuiltApisBuildComponents(ctx)
+	registerRobolectricBuildComponents(ctx)
 	RegisterRuntimeResourceOverlayBuildComponents(ctx)
 	RegisterSdkLibraryBuildComponents(ctx)
 	RegisterStubsBuildComponents(ctx)
@@ -124,6 +124,12 @@ func (binary *binaryDecorator) compile(ctx ModuleContext, flags Flags, deps Path
 
 	TransformSrcToBinary(ctx, srcPath, deps, flags, outputFile)
 
+	if flags.SplitDwarf {
+		dwpFile := android.PathForModuleOut(ctx, fileName+".dwp")
+		binary.stripper.PackSplitDwarf(ctx, outputFile, dwpFile)
+		binary.splitDwarfPackage = android.OptionalPathForPath(dwpFile)
+	}
+
 	if binary.stripper.NeedsStrip(ctx) {
 		strippedOutputFile := android.PathForModuleOut(ctx, "stripped", fileName)
 		binary.stripper.StripExecutableOrSharedLib(ctx, outputFile, strippedOutputFile)
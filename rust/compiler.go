@@ -21,6 +21,7 @@ import (
 	"github.com/google/blueprint/proptools"
 
 	"android/soong/android"
+	"android/soong/cc"
 	"android/soong/rust/config"
 )
 
@@ -153,6 +154,10 @@ type BaseCompilerProperties struct {
 	// linkage if all dependencies of the root binary module do not link against libstd\
 	// the same way.
 	Prefer_rlib *bool `android:"arch_variant"`
+
+	// whether to emit split DWARF and pack it into a .dwp file next to the unstripped binary or
+	// shared library.  Defaults to the SplitDwarf product variable or SOONG_SPLIT_DWARF.
+	Split_dwarf *bool `android:"arch_variant"`
 }
 
 type baseCompiler struct {
@@ -171,6 +176,10 @@ type baseCompiler struct {
 	// Stripped output file. If Valid(), this file will be installed instead of outputFile.
 	strippedOutputFile android.OptionalPath
 
+	// Packed split DWARF of the unstripped output file, and its copy in the symbols directory.
+	splitDwarfPackage        android.OptionalPath
+	symbolsSplitDwarfPackage android.OptionalPath
+
 	// If a crate has a source-generated dependency, a copy of the source file
 	// will be available in cargoOutDir (equivalent to Cargo OUT_DIR).
 	cargoOutDir android.ModuleOutPath
@@ -268,6 +277,11 @@ func (compiler *baseCompiler) compilerFlags(ctx ModuleContext, flags Flags) Flag
 		flags.RustFlags = append(flags.RustFlags, "--cfg 'android_vndk'")
 	}
 
+	if BoolDefault(compiler.Properties.Split_dwarf, ctx.Config().SplitDwarf()) && !ctx.Darwin() && !ctx.Windows() {
+		flags.SplitDwarf = true
+		flags.RustFlags = append(flags.RustFlags, "-Z unstable-options", "-C split-debuginfo=unpacked")
+	}
+
 	return flags
 }
 
@@ -372,6 +386,8 @@ func (compiler *baseCompiler) nativeCoverage() bool {
 func (compiler *baseCompiler) install(ctx ModuleContext) {
 	path := ctx.RustModule().OutputFile()
 	compiler.path = ctx.InstallFile(compiler.installDir(ctx), path.Path().Base(), path.Path())
+	compiler.symbolsSplitDwarfPackage = cc.InstallSplitDwarfPackage(ctx, compiler.installDir(ctx),
+		path.Path().Base(), compiler.splitDwarfPackage)
 }
 
 func (compiler *baseCompiler) getStem(ctx ModuleContext) string {
@@ -208,3 +208,36 @@ func TestStdDeviceLinkage(t *testing.T) {
 		t.Errorf("libstd is not linked dynamically for dylibs")
 	}
 }
+
+// Test that split_dwarf emits unpacked debug info and packs it for dylibs and binaries, but not rlibs.
+func TestSplitDwarf(t *testing.T) {
+	ctx := testRust(t, `
+		rust_library {
+			name: "libfoo",
+			srcs: ["foo.rs"],
+			crate_name: "foo",
+			split_dwarf: true,
+		}
+		rust_binary {
+			name: "fizzbuzz",
+			srcs: ["foo.rs"],
+			split_dwarf: true,
+		}`)
+
+	libfooDylib := ctx.ModuleForTests("libfoo", "android_arm64_armv8-a_dylib")
+	android.AssertStringDoesContain(t, "libfoo dylib flags",
+		libfooDylib.Rule("rustc").Args["rustcFlags"], "-C split-debuginfo=unpacked")
+	libfooDylib.Output("libfoo.dylib.so.dwp")
+
+	libfooRlib := ctx.ModuleForTests("libfoo", "android_arm64_armv8-a_rlib_dylib-std")
+	if libfooRlib.MaybeOutput("libfoo.rlib.dwp").Rule != nil {
+		t.Errorf("unexpected .dwp file for rlib")
+	}
+
+	fizzbuzz := ctx.ModuleForTests("fizzbuzz", "android_arm64_armv8-a")
+	fizzbuzz.Output("fizzbuzz.dwp")
+	symbols := fizzbuzz.Module().(*Module).compiler.(*binaryDecorator).symbolsSplitDwarfPackage
+	if !symbols.Valid() || !strings.HasSuffix(symbols.String(), "symbols/system/bin/fizzbuzz.dwp") {
+		t.Errorf("unexpected symbols path for split DWARF package: %#v", symbols)
+	}
+}
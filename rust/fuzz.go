@@ -126,6 +126,10 @@ func (s *rustFuzzPackager) GenerateBuildActions(ctx android.SingletonContext) {
 	// Map between each architecture + host/device combination.
 	archDirs := make(map[archOs][]fileToZip)
 
+	// Map between each architecture + host/device combination, and the manifest entries of the
+	// fuzz targets in its package.
+	manifests := make(map[archOs][]cc.FuzzManifestEntry)
+
 	// List of individual fuzz targets.
 	s.fuzzTargets = make(map[string]bool)
 
@@ -232,6 +236,28 @@ func (s *rustFuzzPackager) GenerateBuildActions(ctx android.SingletonContext) {
 
 		s.fuzzTargets[module.Name()] = true
 		archDirs[archOs] = append(archDirs[archOs], fileToZip{fuzzZip, ""})
+
+		sanitizer := "none"
+		if rustModule.sanitize.isSanitizerEnabled(cc.Hwasan) {
+			sanitizer = "hwaddress"
+		} else if rustModule.sanitize.isSanitizerEnabled(cc.Asan) {
+			sanitizer = "address"
+		}
+		// Rust fuzz targets are always built for libFuzzer, through libfuzzer-sys.
+		entry := cc.FuzzManifestEntry{
+			Name:       module.Name(),
+			Zip:        fuzzZip.Base(),
+			Engine:     "libfuzzer",
+			Sanitizer:  sanitizer,
+			Arch:       archString,
+			Host:       rustModule.Host(),
+			CorpusSize: len(fuzzModule.corpus),
+			FuzzConfig: fuzzModule.Properties.Fuzz_config,
+		}
+		if fuzzModule.dictionary != nil {
+			entry.Dictionary = fuzzModule.dictionary.Base()
+		}
+		manifests[archOs] = append(manifests[archOs], entry)
 	})
 
 	var archOsList []archOs
@@ -248,6 +274,10 @@ func (s *rustFuzzPackager) GenerateBuildActions(ctx android.SingletonContext) {
 		outputFile := android.PathForOutput(ctx, "fuzz-rust-"+hostOrTarget+"-"+arch+".zip")
 		s.packages = append(s.packages, outputFile)
 
+		manifest := android.PathForOutput(ctx, "fuzz-rust-"+hostOrTarget+"-"+arch+".json")
+		cc.WriteFuzzManifest(ctx, manifest, manifests[archOs])
+		s.packages = append(s.packages, manifest)
+
 		command := builder.Command().BuiltTool("soong_zip").
 			Flag("-j").
 			FlagWithOutput("-o ", outputFile).
@@ -471,6 +471,12 @@ func (library *libraryDecorator) compile(ctx ModuleContext, flags Flags, deps Pa
 		TransformSrctoShared(ctx, srcPath, deps, flags, outputFile)
 	}
 
+	if !library.rlib() && !library.static() && flags.SplitDwarf {
+		dwpFile := android.PathForModuleOut(ctx, fileName+".dwp")
+		library.stripper.PackSplitDwarf(ctx, outputFile, dwpFile)
+		library.splitDwarfPackage = android.OptionalPathForPath(dwpFile)
+	}
+
 	if !library.rlib() && !library.static() && library.stripper.NeedsStrip(ctx) {
 		strippedOutputFile := android.PathForModuleOut(ctx, "stripped", fileName)
 		library.stripper.StripExecutableOrSharedLib(ctx, outputFile, strippedOutputFile)
@@ -62,6 +62,7 @@ type Flags struct {
 	Toolchain       config.Toolchain
 	Coverage        bool
 	Clippy          bool
+	SplitDwarf      bool
 }
 
 type BaseProperties struct {
@@ -825,6 +826,14 @@ func (mod *Module) GenerateAndroidBuildActions(actx android.ModuleContext) {
 		apexInfo := actx.Provider(android.ApexInfoProvider).(android.ApexInfo)
 		if mod.installable(apexInfo) {
 			mod.compiler.install(ctx)
+
+			if mod.Binary() || mod.Dylib() || mod.Shared() {
+				installedFile := mod.unstrippedOutputFile.Path()
+				if stripped := mod.compiler.strippedOutputFilePath(); stripped.Valid() {
+					installedFile = stripped.Path()
+				}
+				bloaty.ReportSizeForModule(ctx, installedFile, mod.unstrippedOutputFile.Path())
+			}
 		}
 	}
 }
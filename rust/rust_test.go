@@ -415,4 +415,14 @@ func TestLibrarySizes(t *testing.T) {
 	m := ctx.SingletonForTests("file_metrics")
 	m.Output("libwaldo.dylib.so.bloaty.csv")
 	m.Output("stripped/libwaldo.dylib.so.bloaty.csv")
+
+	libwaldo := ctx.ModuleForTests("libwaldo", "android_arm64_armv8-a_dylib")
+	report := libwaldo.Output("libwaldo.dylib.so.size_report.json")
+	android.AssertStringEquals(t, "size report module", "libwaldo", report.Args["module"])
+	android.AssertStringEquals(t, "size report variant", "android_arm64_armv8-a_dylib", report.Args["variant"])
+	android.AssertPathRelativeToTopEquals(t, "size report input",
+		"out/soong/.intermediates/libwaldo/android_arm64_armv8-a_dylib/stripped/libwaldo.dylib.so", report.Input)
+
+	merged := ctx.SingletonForTests("size_reports").Output("size_reports.json")
+	android.AssertStringListContains(t, "merged size reports", merged.Inputs.Strings(), report.Output.String())
 }
@@ -0,0 +1,103 @@
+#!/bin/bash -eu
+
+set -o pipefail
+
+# This test exercises fake_rewrapper, the stand-in for the remote execution
+# wrapper that checks that actions only read the inputs they declare.
+
+source "$(dirname "$0")/lib.sh"
+
+function build_fake_rewrapper() {
+  build/soong/soong_ui.bash --make-mode --skip-make --skip-soong-tests fake_rewrapper
+  FAKE_REWRAPPER="$MOCK_TOP/out/soong/host/linux-x86/bin/fake_rewrapper"
+  [[ -x "$FAKE_REWRAPPER" ]] || fail "fake_rewrapper was not built"
+}
+
+function create_inputs() {
+  mkdir -p a out/a
+  echo "foo" > a/foo.txt
+  echo "bar" > a/bar.txt
+}
+
+function test_declared_inputs() {
+  setup
+  build_fake_rewrapper
+  create_inputs
+
+  "$FAKE_REWRAPPER" --labels=type=tool --exec_strategy=remote \
+    --inputs=a/foo.txt,a/bar.txt --output_files=out/a/foobar.txt \
+    -- /bin/sh -c "cat a/foo.txt a/bar.txt > out/a/foobar.txt"
+
+  [[ "$(cat out/a/foobar.txt)" == "$(printf 'foo\nbar')" ]] || fail "Unexpected output"
+}
+
+function test_undeclared_input() {
+  setup
+  build_fake_rewrapper
+  create_inputs
+
+  if "$FAKE_REWRAPPER" --labels=type=tool --exec_strategy=remote \
+    --inputs=a/foo.txt --output_files=out/a/foobar.txt \
+    -- /bin/sh -c "cat a/foo.txt a/bar.txt > out/a/foobar.txt" >& "$MOCK_TOP/errors"; then
+    fail "Action with an undeclared input should have failed"
+  fi
+
+  grep -q "not declared as remote inputs" "$MOCK_TOP/errors" || fail "Undeclared input error not found"
+  grep -q "^  a/bar.txt$" "$MOCK_TOP/errors" || fail "Undeclared input a/bar.txt not reported"
+  [[ ! -e out/a/foobar.txt ]] || fail "Output of a failed action should not be copied"
+}
+
+function test_bootstrap() {
+  setup
+  build_fake_rewrapper
+
+  mkdir -p rbe
+  ln -s "$FAKE_REWRAPPER" rbe/bootstrap
+  RBE_output_dir=out/rbe rbe/bootstrap || fail "bootstrap failed"
+  [[ ! -e out/rbe/rbe_metrics.pb ]] || fail "Metrics written when starting the proxy"
+  RBE_output_dir=out/rbe rbe/bootstrap -shutdown || fail "bootstrap -shutdown failed"
+  [[ -e out/rbe/rbe_metrics.pb ]] || fail "Metrics not written when shutting down the proxy"
+}
+
+# Builds a java resource jar with the jar rule of the java package, whose remote
+# execution wrapper and input list are generated by remoteexec.REParams, through
+# fake_rewrapper with the remote exec strategy.
+function test_remoteexec_rule() {
+  setup
+  build_fake_rewrapper
+  symlink_directory prebuilts/jdk
+
+  mkdir -p rbe a
+  ln -s "$FAKE_REWRAPPER" rbe/bootstrap
+  cat > rbe/rewrapper <<EOF
+#!/bin/bash
+echo "\$@" >> "$MOCK_TOP/out/rewrapper.log"
+exec "$FAKE_REWRAPPER" "\$@"
+EOF
+  chmod +x rbe/rewrapper
+
+  echo "res" > a/res.txt
+  cat > a/Android.bp <<'EOF'
+java_library_host {
+  name: "foo",
+  java_resources: ["res.txt"],
+}
+EOF
+
+  USE_RBE=true RBE_DIR="$MOCK_TOP/rbe" RBE_WRAPPER="$MOCK_TOP/rbe/rewrapper" \
+    RBE_JAR=1 RBE_JAR_EXEC_STRATEGY=remote \
+    build/soong/soong_ui.bash --make-mode --skip-make --skip-soong-tests foo \
+    || fail "Build with remote actions failed"
+
+  grep -q -- "--exec_strategy=remote" out/rewrapper.log || fail "The jar rule was not run by fake_rewrapper"
+  [[ -e out/rbe_metrics.pb ]] || fail "RBE metrics were not dumped"
+
+  local jar="out/soong/host/linux-x86/framework/foo.jar"
+  [[ -e "$jar" ]] || fail "$jar was not built"
+  unzip -l "$jar" | grep -q "res.txt" || fail "res.txt is missing from $jar"
+}
+
+test_declared_inputs
+test_undeclared_input
+test_bootstrap
+test_remoteexec_rule
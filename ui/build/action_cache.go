@@ -0,0 +1,90 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package build
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"syscall"
+
+	"github.com/golang/protobuf/proto"
+
+	soong_metrics_proto "android/soong/ui/metrics/metrics_proto"
+)
+
+// The file in the sbox sandboxes directory that sbox appends a "hit" or "miss" line to for every
+// lookup in the local action cache, see cmd/sbox/cache.go.  It is kept out of the cache directory,
+// which may be shared with concurrent builds in other output directories.
+const actionCacheStatsFile = "sbox_cache_stats"
+
+func actionCacheStatsPath(config Config) string {
+	return filepath.Join(config.TempDir(), actionCacheStatsFile)
+}
+
+// resetActionCacheStats creates the local action cache directory if necessary, so that it can be
+// mounted into the build sandbox, and clears the results of the lookups of the previous build.
+func resetActionCacheStats(ctx Context, config Config) {
+	dir := config.SboxCacheDir()
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		ctx.Fatalf("Failed to create action cache directory %s: %s", dir, err)
+	}
+	if err := os.Remove(actionCacheStatsPath(config)); err != nil && !os.IsNotExist(err) {
+		ctx.Verbosef("Failed to remove action cache stats: %s", err)
+	}
+}
+
+// loadActionCacheMetrics adds the number of hits and misses in the local action cache during the
+// build to the metrics.
+func loadActionCacheMetrics(ctx Context, config Config) {
+	// The stats file does not exist if no cacheable sbox commands were run.
+	metrics := parseActionCacheStats(&bytes.Buffer{})
+
+	f, err := os.Open(actionCacheStatsPath(config))
+	if err == nil {
+		defer f.Close()
+		// Wait for any sbox process left over from an interrupted ninja to finish appending.
+		syscall.Flock(int(f.Fd()), syscall.LOCK_SH)
+		metrics = parseActionCacheStats(f)
+	} else if !os.IsNotExist(err) {
+		ctx.Verbosef("Failed to read action cache stats: %s", err)
+		return
+	}
+
+	ctx.Verbosef("Action cache: %d hits, %d misses", metrics.GetHits(), metrics.GetMisses())
+	if ctx.Metrics != nil {
+		ctx.Metrics.SetActionCacheMetrics(metrics)
+	}
+}
+
+// parseActionCacheStats counts the hits and misses in the stats file of the local action cache.
+func parseActionCacheStats(r io.Reader) *soong_metrics_proto.ActionCacheMetrics {
+	var hits, misses uint64
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		switch scanner.Text() {
+		case "hit":
+			hits++
+		case "miss":
+			misses++
+		}
+	}
+	return &soong_metrics_proto.ActionCacheMetrics{
+		Hits:   proto.Uint64(hits),
+		Misses: proto.Uint64(misses),
+	}
+}
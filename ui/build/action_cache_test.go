@@ -0,0 +1,32 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package build
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseActionCacheStats(t *testing.T) {
+	metrics := parseActionCacheStats(strings.NewReader("hit\nmiss\nhit\n\nunknown\nhit\n"))
+	if metrics.GetHits() != 3 || metrics.GetMisses() != 1 {
+		t.Errorf("expected 3 hits and 1 miss, got %d hits and %d misses", metrics.GetHits(), metrics.GetMisses())
+	}
+
+	metrics = parseActionCacheStats(strings.NewReader(""))
+	if metrics.Hits == nil || metrics.GetHits() != 0 || metrics.GetMisses() != 0 {
+		t.Errorf("expected zero hits and misses, got %v", metrics)
+	}
+}
@@ -920,6 +920,15 @@ func (c *configImpl) UseRBE() bool {
 	return false
 }
 
+// SboxCacheDir returns the directory of the local action cache used by sbox, or an empty string
+// if the cache is disabled.
+func (c *configImpl) SboxCacheDir() string {
+	if v, ok := c.environ.Get("SOONG_SBOX_CACHE_DIR"); ok {
+		return strings.TrimSpace(v)
+	}
+	return ""
+}
+
 func (c *configImpl) UseBazel() bool {
 	return c.useBazel
 }
@@ -202,10 +202,39 @@ func runNinjaForBuild(ctx Context, config Config) {
 		}
 	}()
 
+	if config.SboxCacheDir() != "" {
+		resetActionCacheStats(ctx, config)
+		defer loadActionCacheMetrics(ctx, config)
+	}
+
+	snapshotNinjaLog(ctx, config)
+
 	ctx.Status.Status("Starting ninja...")
 	cmd.RunAndStreamOrFatal()
 }
 
+// snapshotNinjaLog copies the Ninja log before running Ninja, so that tools like why_rebuilt can
+// tell the actions run by this build apart from those run by earlier builds.  The log has no
+// markers between builds, and Ninja may recompact it when it starts.  The log can be large, so it
+// is only copied when SOONG_WRITE_MODULE_OUTPUTS=true also asks for the other inputs of
+// why_rebuilt.
+func snapshotNinjaLog(ctx Context, config Config) {
+	logPath := filepath.Join(config.OutDir(), ".ninja_log")
+	snapshotPath := logPath + status.NinjaLogSnapshotSuffix
+	if !config.Environment().IsEnvTrue("SOONG_WRITE_MODULE_OUTPUTS") {
+		// Don't leave a snapshot from an earlier build behind for why_rebuilt to use.
+		os.Remove(snapshotPath)
+		return
+	}
+	if _, err := copyFile(logPath, snapshotPath); err != nil {
+		// Don't leave a stale or partial snapshot behind.
+		os.Remove(snapshotPath)
+		if !os.IsNotExist(err) {
+			ctx.Verbosef("Failed to snapshot the ninja log: %s", err)
+		}
+	}
+}
+
 // A simple struct for checking if Ninja gets stuck, using timestamps.
 type ninjaStucknessChecker struct {
 	logPath     string
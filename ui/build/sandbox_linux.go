@@ -203,6 +203,13 @@ func (c *Cmd) wrapSandbox() {
 		sandboxArgs = append(sandboxArgs, "-B", sandboxConfig.distDir)
 	}
 
+	if cacheDir := c.config.SboxCacheDir(); cacheDir != "" {
+		// Mount the local action cache of sbox as read-write if it exists
+		if _, err := os.Stat(cacheDir); err == nil {
+			sandboxArgs = append(sandboxArgs, "-B", absPath(c.ctx, cacheDir))
+		}
+	}
+
 	if c.Sandbox.AllowBuildBrokenUsesNetwork && c.config.BuildBrokenUsesNetwork() {
 		c.ctx.Printf("AllowBuildBrokenUsesNetwork: %v", c.Sandbox.AllowBuildBrokenUsesNetwork)
 		c.ctx.Printf("BuildBrokenUsesNetwork: %v", c.config.BuildBrokenUsesNetwork())
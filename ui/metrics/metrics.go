@@ -210,6 +210,12 @@ func (m *Metrics) SetSoongBuildMetrics(metrics *soong_metrics_proto.SoongBuildMe
 	m.metrics.SoongBuildMetrics = metrics
 }
 
+// SetActionCacheMetrics sets the results of the lookups in the local
+// action cache of sbox during the build.
+func (m *Metrics) SetActionCacheMetrics(metrics *soong_metrics_proto.ActionCacheMetrics) {
+	m.metrics.ActionCacheMetrics = metrics
+}
+
 // A CriticalUserJourneysMetrics is a struct that contains critical user journey
 // metrics. These critical user journeys are defined under cuj/cuj.go file.
 type CriticalUserJourneysMetrics struct {
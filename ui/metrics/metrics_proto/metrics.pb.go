@@ -207,10 +207,12 @@ type MetricsBase struct {
 	// The build command that the user entered to the build system.
 	BuildCommand *string `protobuf:"bytes,26,opt,name=build_command,json=buildCommand" json:"build_command,omitempty"`
 	// The metrics for calling Bazel.
-	BazelRuns            []*PerfInfo `protobuf:"bytes,27,rep,name=bazel_runs,json=bazelRuns" json:"bazel_runs,omitempty"`
-	XXX_NoUnkeyedLiteral struct{}    `json:"-"`
-	XXX_unrecognized     []byte      `json:"-"`
-	XXX_sizecache        int32       `json:"-"`
+	BazelRuns []*PerfInfo `protobuf:"bytes,27,rep,name=bazel_runs,json=bazelRuns" json:"bazel_runs,omitempty"`
+	// The results of the lookups in the local action cache of sbox.
+	ActionCacheMetrics   *ActionCacheMetrics `protobuf:"bytes,28,opt,name=action_cache_metrics,json=actionCacheMetrics" json:"action_cache_metrics,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}            `json:"-"`
+	XXX_unrecognized     []byte              `json:"-"`
+	XXX_sizecache        int32               `json:"-"`
 }
 
 func (m *MetricsBase) Reset()         { *m = MetricsBase{} }
@@ -432,6 +434,13 @@ func (m *MetricsBase) GetBazelRuns() []*PerfInfo {
 	return nil
 }
 
+func (m *MetricsBase) GetActionCacheMetrics() *ActionCacheMetrics {
+	if m != nil {
+		return m.ActionCacheMetrics
+	}
+	return nil
+}
+
 type BuildConfig struct {
 	UseGoma              *bool    `protobuf:"varint,1,opt,name=use_goma,json=useGoma" json:"use_goma,omitempty"`
 	UseRbe               *bool    `protobuf:"varint,2,opt,name=use_rbe,json=useRbe" json:"use_rbe,omitempty"`
@@ -970,6 +979,55 @@ func (m *SoongBuildMetrics) GetMaxHeapSize() uint64 {
 	return 0
 }
 
+type ActionCacheMetrics struct {
+	// The number of sbox commands whose outputs were restored from the cache.
+	Hits *uint64 `protobuf:"varint,1,opt,name=hits" json:"hits,omitempty"`
+	// The number of cacheable sbox commands that were not found in the cache.
+	Misses               *uint64  `protobuf:"varint,2,opt,name=misses" json:"misses,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ActionCacheMetrics) Reset()         { *m = ActionCacheMetrics{} }
+func (m *ActionCacheMetrics) String() string { return proto.CompactTextString(m) }
+func (*ActionCacheMetrics) ProtoMessage()    {}
+func (*ActionCacheMetrics) Descriptor() ([]byte, []int) {
+	return fileDescriptor_6039342a2ba47b72, []int{9}
+}
+
+func (m *ActionCacheMetrics) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ActionCacheMetrics.Unmarshal(m, b)
+}
+func (m *ActionCacheMetrics) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ActionCacheMetrics.Marshal(b, m, deterministic)
+}
+func (m *ActionCacheMetrics) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ActionCacheMetrics.Merge(m, src)
+}
+func (m *ActionCacheMetrics) XXX_Size() int {
+	return xxx_messageInfo_ActionCacheMetrics.Size(m)
+}
+func (m *ActionCacheMetrics) XXX_DiscardUnknown() {
+	xxx_messageInfo_ActionCacheMetrics.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ActionCacheMetrics proto.InternalMessageInfo
+
+func (m *ActionCacheMetrics) GetHits() uint64 {
+	if m != nil && m.Hits != nil {
+		return *m.Hits
+	}
+	return 0
+}
+
+func (m *ActionCacheMetrics) GetMisses() uint64 {
+	if m != nil && m.Misses != nil {
+		return *m.Misses
+	}
+	return 0
+}
+
 func init() {
 	proto.RegisterEnum("soong_build_metrics.MetricsBase_BuildVariant", MetricsBase_BuildVariant_name, MetricsBase_BuildVariant_value)
 	proto.RegisterEnum("soong_build_metrics.MetricsBase_Arch", MetricsBase_Arch_name, MetricsBase_Arch_value)
@@ -983,6 +1041,7 @@ func init() {
 	proto.RegisterType((*CriticalUserJourneyMetrics)(nil), "soong_build_metrics.CriticalUserJourneyMetrics")
 	proto.RegisterType((*CriticalUserJourneysMetrics)(nil), "soong_build_metrics.CriticalUserJourneysMetrics")
 	proto.RegisterType((*SoongBuildMetrics)(nil), "soong_build_metrics.SoongBuildMetrics")
+	proto.RegisterType((*ActionCacheMetrics)(nil), "soong_build_metrics.ActionCacheMetrics")
 }
 
 func init() {
@@ -990,92 +1049,94 @@ func init() {
 }
 
 var fileDescriptor_6039342a2ba47b72 = []byte{
-	// 1380 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x9c, 0x57, 0xef, 0x52, 0x1b, 0x37,
-	0x10, 0x8f, 0xc1, 0x60, 0x7b, 0xfd, 0x07, 0x23, 0xa0, 0x5c, 0x48, 0xd2, 0x52, 0xb7, 0x49, 0x99,
-	0x4e, 0x43, 0x32, 0x34, 0xc3, 0x64, 0x98, 0x4c, 0xa7, 0xe0, 0xd0, 0x34, 0x65, 0xc0, 0x8c, 0x08,
-	0x69, 0xda, 0x7e, 0x50, 0xe5, 0xb3, 0x0c, 0x97, 0xdc, 0x9d, 0x6e, 0x24, 0x1d, 0xc5, 0x79, 0xb3,
-	0x7e, 0xee, 0x4b, 0xf4, 0x05, 0xfa, 0x04, 0x7d, 0x81, 0x8e, 0x56, 0x77, 0xe6, 0x20, 0x6e, 0xc2,
-	0xe4, 0xdb, 0xe9, 0xb7, 0xbf, 0xdf, 0x6a, 0xb5, 0xd2, 0xee, 0xda, 0xd0, 0x8c, 0x84, 0x51, 0x81,
-	0xaf, 0xd7, 0x13, 0x25, 0x8d, 0x24, 0x0b, 0x5a, 0xca, 0xf8, 0x84, 0xf5, 0xd3, 0x20, 0x1c, 0xb0,
-	0xcc, 0xd4, 0xf9, 0xbb, 0x01, 0xf5, 0x7d, 0xf7, 0xbd, 0xc3, 0xb5, 0x20, 0x0f, 0x61, 0xd1, 0x11,
-	0x06, 0xdc, 0x08, 0x66, 0x82, 0x48, 0x68, 0xc3, 0xa3, 0xc4, 0x2b, 0xad, 0x96, 0xd6, 0xa6, 0x29,
-	0x41, 0xdb, 0x53, 0x6e, 0xc4, 0x8b, 0xdc, 0x42, 0x6e, 0x42, 0xd5, 0x29, 0x82, 0x81, 0x37, 0xb5,
-	0x5a, 0x5a, 0xab, 0xd1, 0x0a, 0xae, 0x9f, 0x0f, 0xc8, 0x16, 0xdc, 0x4c, 0x42, 0x6e, 0x86, 0x52,
-	0x45, 0xec, 0x4c, 0x28, 0x1d, 0xc8, 0x98, 0xf9, 0x72, 0x20, 0x62, 0x1e, 0x09, 0x6f, 0x1a, 0xb9,
-	0xcb, 0x39, 0xe1, 0xa5, 0xb3, 0x77, 0x33, 0x33, 0xb9, 0x0b, 0x2d, 0xc3, 0xd5, 0x89, 0x30, 0x2c,
-	0x51, 0x72, 0x90, 0xfa, 0xc6, 0x2b, 0xa3, 0xa0, 0xe9, 0xd0, 0x43, 0x07, 0x92, 0x01, 0x2c, 0x66,
-	0x34, 0x17, 0xc4, 0x19, 0x57, 0x01, 0x8f, 0x8d, 0x37, 0xb3, 0x5a, 0x5a, 0x6b, 0x6d, 0xdc, 0x5f,
-	0x9f, 0x70, 0xe6, 0xf5, 0xc2, 0x79, 0xd7, 0x77, 0xac, 0xe5, 0xa5, 0x13, 0x6d, 0x4d, 0xef, 0x1e,
-	0x3c, 0xa3, 0xc4, 0xf9, 0x2b, 0x1a, 0x48, 0x0f, 0xea, 0xd9, 0x2e, 0x5c, 0xf9, 0xa7, 0xde, 0x2c,
-	0x3a, 0xbf, 0xfb, 0x41, 0xe7, 0xdb, 0xca, 0x3f, 0xdd, 0xaa, 0x1c, 0x1f, 0xec, 0x1d, 0xf4, 0x7e,
-	0x3e, 0xa0, 0xe0, 0x5c, 0x58, 0x90, 0xac, 0xc3, 0x42, 0xc1, 0xe1, 0x38, 0xea, 0x0a, 0x1e, 0x71,
-	0xfe, 0x82, 0x98, 0x07, 0xf0, 0x0d, 0x64, 0x61, 0x31, 0x3f, 0x49, 0xc7, 0xf4, 0x2a, 0xd2, 0xdb,
-	0xce, 0xd2, 0x4d, 0xd2, 0x9c, 0xbd, 0x07, 0xb5, 0x53, 0xa9, 0xb3, 0x60, 0x6b, 0x1f, 0x15, 0x6c,
-	0xd5, 0x3a, 0xc0, 0x50, 0x29, 0x34, 0xd1, 0xd9, 0x46, 0x3c, 0x70, 0x0e, 0xe1, 0xa3, 0x1c, 0xd6,
-	0xad, 0x93, 0x8d, 0x78, 0x80, 0x3e, 0x97, 0xa1, 0x82, 0x3e, 0xa5, 0xf6, 0xea, 0x78, 0x86, 0x59,
-	0xbb, 0xec, 0x69, 0xd2, 0xc9, 0x36, 0x93, 0x9a, 0x89, 0x73, 0xa3, 0xb8, 0xd7, 0x40, 0x73, 0xdd,
-	0x99, 0x77, 0x2d, 0x34, 0xe6, 0xf8, 0x4a, 0x6a, 0x6d, 0x5d, 0x34, 0x2f, 0x38, 0x5d, 0x8b, 0xf5,
-	0x34, 0xb9, 0x07, 0x73, 0x05, 0x0e, 0x86, 0xdd, 0x72, 0xcf, 0x67, 0xcc, 0xc2, 0x40, 0xee, 0xc3,
-	0x42, 0x81, 0x37, 0x3e, 0xe2, 0x9c, 0x4b, 0xec, 0x98, 0x5b, 0x88, 0x5b, 0xa6, 0x86, 0x0d, 0x02,
-	0xe5, 0xb5, 0x5d, 0xdc, 0x32, 0x35, 0x4f, 0x03, 0x45, 0xbe, 0x83, 0xba, 0x16, 0x26, 0x4d, 0x98,
-	0x91, 0x32, 0xd4, 0xde, 0xfc, 0xea, 0xf4, 0x5a, 0x7d, 0xe3, 0xce, 0xc4, 0x14, 0x1d, 0x0a, 0x35,
-	0x7c, 0x1e, 0x0f, 0x25, 0x05, 0x54, 0xbc, 0xb0, 0x02, 0xb2, 0x05, 0xb5, 0x37, 0xdc, 0x04, 0x4c,
-	0xa5, 0xb1, 0xf6, 0xc8, 0x75, 0xd4, 0x55, 0xcb, 0xa7, 0x69, 0xac, 0xc9, 0x13, 0x00, 0xc7, 0x44,
-	0xf1, 0xc2, 0x75, 0xc4, 0x35, 0xb4, 0xe6, 0xea, 0x38, 0x88, 0x5f, 0x73, 0xa7, 0x5e, 0xbc, 0x96,
-	0x1a, 0x05, 0xa8, 0xfe, 0x16, 0x66, 0x8c, 0x34, 0x3c, 0xf4, 0x96, 0x56, 0x4b, 0x1f, 0x16, 0x3a,
-	0x2e, 0x79, 0x09, 0x93, 0x5a, 0x91, 0xf7, 0x09, 0xba, 0xb8, 0x37, 0xd1, 0xc5, 0x91, 0xc5, 0xb0,
-	0x24, 0xb3, 0x17, 0x46, 0xe7, 0xf5, 0x55, 0x88, 0x74, 0xa1, 0xe1, 0x54, 0xbe, 0x8c, 0x87, 0xc1,
-	0x89, 0xb7, 0x8c, 0x0e, 0x57, 0x27, 0x3a, 0x44, 0x61, 0x17, 0x79, 0xb4, 0xde, 0xbf, 0x58, 0x90,
-	0x15, 0xc0, 0xa7, 0x8f, 0x2d, 0xca, 0xc3, 0x3b, 0x1e, 0xaf, 0xc9, 0x2f, 0xb0, 0xa8, 0x47, 0xda,
-	0x88, 0x88, 0x29, 0xa1, 0x65, 0xaa, 0x7c, 0xc1, 0x82, 0x78, 0x28, 0xbd, 0x9b, 0xb8, 0xd1, 0x57,
-	0x93, 0x23, 0x47, 0x01, 0xcd, 0xf8, 0x98, 0x06, 0xa2, 0xdf, 0xc1, 0xc8, 0x17, 0xd0, 0xcc, 0x63,
-	0x8f, 0x22, 0x1e, 0x0f, 0xbc, 0x15, 0xdc, 0xbb, 0x91, 0x85, 0x86, 0x98, 0xbd, 0xab, 0x3e, 0x7f,
-	0x2b, 0x42, 0x77, 0x57, 0xb7, 0xae, 0x75, 0x57, 0x28, 0xb0, 0x77, 0xd5, 0x79, 0x08, 0x8d, 0x4b,
-	0x4d, 0xad, 0x0a, 0xe5, 0xe3, 0xa3, 0x5d, 0xda, 0xbe, 0x41, 0x9a, 0x50, 0xb3, 0x5f, 0x4f, 0x77,
-	0x77, 0x8e, 0x9f, 0xb5, 0x4b, 0xa4, 0x02, 0xb6, 0x11, 0xb6, 0xa7, 0x3a, 0x4f, 0xa0, 0x8c, 0xcf,
-	0xbe, 0x0e, 0x79, 0x19, 0xb7, 0x6f, 0x58, 0xeb, 0x36, 0xdd, 0x6f, 0x97, 0x48, 0x0d, 0x66, 0xb6,
-	0xe9, 0xfe, 0xe6, 0xa3, 0xf6, 0x94, 0xc5, 0x5e, 0x3d, 0xde, 0x6c, 0x4f, 0x13, 0x80, 0xd9, 0x57,
-	0x8f, 0x37, 0xd9, 0xe6, 0xa3, 0x76, 0xb9, 0x73, 0x02, 0xf5, 0x42, 0x96, 0xed, 0x9c, 0x48, 0xb5,
-	0x60, 0x27, 0x32, 0xe2, 0x38, 0x4d, 0xaa, 0xb4, 0x92, 0x6a, 0xf1, 0x4c, 0x46, 0xdc, 0x96, 0x95,
-	0x35, 0xa9, 0xbe, 0xc0, 0x09, 0x52, 0xa5, 0xb3, 0xa9, 0x16, 0xb4, 0x2f, 0xc8, 0x97, 0xd0, 0x1a,
-	0x4a, 0x9b, 0xe6, 0xb1, 0x72, 0x1a, 0xed, 0x0d, 0x44, 0x8f, 0x9d, 0xbc, 0x23, 0x81, 0xbc, 0x9b,
-	0x65, 0xb2, 0x01, 0x4b, 0xf8, 0xdc, 0x58, 0x72, 0x3a, 0xd2, 0x81, 0xcf, 0x43, 0x16, 0x89, 0x48,
-	0xaa, 0x11, 0x6e, 0x5e, 0xa6, 0x0b, 0x68, 0x3c, 0xcc, 0x6c, 0xfb, 0x68, 0xb2, 0x43, 0x87, 0x9f,
-	0xf1, 0x20, 0xe4, 0xfd, 0x50, 0xd8, 0x4e, 0xab, 0x31, 0x9e, 0x19, 0xda, 0x1c, 0xa3, 0xdd, 0x24,
-	0xd5, 0x9d, 0x7f, 0x4b, 0x50, 0xcd, 0x33, 0x4c, 0x08, 0x94, 0x07, 0x42, 0xfb, 0xe8, 0xb6, 0x46,
-	0xf1, 0xdb, 0x62, 0xf8, 0x80, 0xdc, 0x3c, 0xc4, 0x6f, 0x72, 0x07, 0x40, 0x1b, 0xae, 0x0c, 0x0e,
-	0x55, 0x3c, 0x47, 0x99, 0xd6, 0x10, 0xb1, 0xb3, 0x94, 0xdc, 0x82, 0x9a, 0x12, 0x3c, 0x74, 0xd6,
-	0x32, 0x5a, 0xab, 0x16, 0x40, 0xe3, 0xe7, 0x00, 0x2e, 0x78, 0x9b, 0x08, 0x9c, 0x6d, 0xe5, 0x9d,
-	0x29, 0xaf, 0x44, 0x6b, 0x0e, 0x3d, 0xd6, 0x82, 0xfc, 0x0e, 0xcb, 0x89, 0x92, 0xbe, 0xd0, 0x5a,
-	0xe8, 0x2b, 0xcf, 0x73, 0x16, 0x1f, 0xca, 0xda, 0xe4, 0x87, 0xe2, 0x34, 0x97, 0xde, 0xe7, 0xd2,
-	0xd8, 0x51, 0x11, 0xee, 0xfc, 0x39, 0x0d, 0x0b, 0x13, 0xe8, 0xe3, 0xc3, 0x96, 0x0a, 0x87, 0x5d,
-	0x83, 0x76, 0xaa, 0x85, 0xc2, 0xd3, 0xb0, 0x28, 0xb0, 0xed, 0x15, 0x93, 0x51, 0xa6, 0x2d, 0x8b,
-	0xdb, 0x43, 0xed, 0x23, 0x6a, 0x27, 0x5b, 0x56, 0x53, 0x45, 0xae, 0x4b, 0x4f, 0xdb, 0x59, 0x0a,
-	0xec, 0xdb, 0x00, 0x11, 0x3f, 0x67, 0x4a, 0x6b, 0xf6, 0xa6, 0x9f, 0xa7, 0x29, 0xe2, 0xe7, 0x54,
-	0xeb, 0xbd, 0x3e, 0xf9, 0x1a, 0xe6, 0xa3, 0x20, 0x96, 0x8a, 0x25, 0xfc, 0x44, 0xb0, 0x21, 0x4f,
-	0x43, 0xa3, 0x5d, 0xb6, 0xe8, 0x1c, 0x1a, 0x0e, 0xf9, 0x89, 0xf8, 0x01, 0x61, 0xe4, 0xf2, 0xd7,
-	0x57, 0xb8, 0xb3, 0x19, 0xd7, 0x1a, 0x0a, 0xdc, 0x4f, 0xa1, 0x1e, 0x48, 0x16, 0xc4, 0x49, 0x6a,
-	0xec, 0xb6, 0x15, 0x77, 0x77, 0x81, 0x7c, 0x6e, 0x91, 0xbd, 0x3e, 0x59, 0x85, 0x46, 0x20, 0x99,
-	0x4c, 0x4d, 0x46, 0xa8, 0x22, 0x01, 0x02, 0xd9, 0x43, 0x68, 0xaf, 0x4f, 0x9e, 0xc0, 0xca, 0x99,
-	0x0c, 0xd3, 0xd8, 0x70, 0x35, 0xb2, 0xed, 0xc9, 0x88, 0x73, 0xc3, 0xf4, 0x1f, 0x81, 0xf1, 0x4f,
-	0x85, 0xc6, 0x11, 0x5d, 0xa6, 0xde, 0x98, 0xd1, 0x75, 0x84, 0xa3, 0xcc, 0x4e, 0xbe, 0x87, 0xdb,
-	0x41, 0xfc, 0x1e, 0x3d, 0xa0, 0x7e, 0xa5, 0xc0, 0xb9, 0xe2, 0xa1, 0xf3, 0x4f, 0x09, 0x5a, 0xfb,
-	0x72, 0x90, 0x86, 0xe2, 0xc5, 0x28, 0x71, 0xd7, 0xf6, 0x5b, 0xde, 0x2d, 0x5d, 0x92, 0xf1, 0xfa,
-	0x5a, 0x1b, 0x0f, 0x26, 0x8f, 0xf5, 0x4b, 0x52, 0xd7, 0x3c, 0x5d, 0xc9, 0x15, 0x06, 0x7c, 0xff,
-	0x02, 0x25, 0x9f, 0x41, 0x3d, 0x42, 0x0d, 0x33, 0xa3, 0x24, 0xaf, 0x03, 0x88, 0xc6, 0x6e, 0x6c,
-	0x65, 0xc7, 0x69, 0xc4, 0xe4, 0x90, 0x39, 0xd0, 0x5d, 0x79, 0x93, 0x36, 0xe2, 0x34, 0xea, 0x0d,
-	0xdd, 0x7e, 0xba, 0xf3, 0x20, 0x6b, 0x21, 0x99, 0xd7, 0x4b, 0x7d, 0xa8, 0x06, 0x33, 0x47, 0xbd,
-	0xde, 0x81, 0x6d, 0x58, 0x55, 0x28, 0xef, 0x6f, 0xef, 0xed, 0xb6, 0xa7, 0x3a, 0x21, 0xac, 0x74,
-	0x55, 0x60, 0x6c, 0x49, 0x1f, 0x6b, 0xa1, 0x7e, 0x92, 0xa9, 0x8a, 0xc5, 0x28, 0x1f, 0x10, 0x93,
-	0x5e, 0xea, 0x16, 0x54, 0xf2, 0x01, 0x34, 0xf5, 0x9e, 0x79, 0x51, 0xf8, 0x61, 0x43, 0x73, 0x41,
-	0xa7, 0x0f, 0xb7, 0x26, 0xec, 0xa6, 0x2f, 0xe6, 0x51, 0xd9, 0x4f, 0x5f, 0x6b, 0xaf, 0x84, 0xf5,
-	0x37, 0x39, 0xb3, 0xff, 0x1f, 0x2d, 0x45, 0x71, 0xe7, 0xaf, 0x12, 0xcc, 0xbf, 0x33, 0xfd, 0x88,
-	0x07, 0x95, 0x3c, 0x6f, 0x25, 0xcc, 0x5b, 0xbe, 0xb4, 0xf3, 0x2b, 0xfb, 0x79, 0xe8, 0x0e, 0xd4,
-	0xa4, 0xe3, 0xb5, 0x7d, 0xf3, 0xae, 0x25, 0xf2, 0x30, 0x94, 0x3e, 0xf3, 0x65, 0x1a, 0x9b, 0xac,
-	0xd4, 0xe6, 0xd0, 0xb0, 0x6d, 0xf1, 0xae, 0x85, 0x6d, 0x05, 0x17, 0xb9, 0x3a, 0x78, 0x9b, 0xb7,
-	0xa5, 0xd6, 0x05, 0xf5, 0x28, 0x78, 0x2b, 0xec, 0xef, 0x31, 0x5b, 0x93, 0xa7, 0x82, 0x27, 0x8e,
-	0xe6, 0x2a, 0xae, 0x1e, 0xf1, 0xf3, 0x1f, 0x05, 0x4f, 0x2c, 0x67, 0x67, 0xe9, 0xd7, 0x6c, 0xe4,
-	0x67, 0xe7, 0x66, 0xf8, 0x97, 0xe4, 0xbf, 0x00, 0x00, 0x00, 0xff, 0xff, 0xcb, 0xfb, 0x8e, 0xf5,
-	0xa2, 0x0c, 0x00, 0x00,
+	// 1414 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x9d, 0x57, 0x6d, 0x53, 0xdb, 0x46,
+	0x10, 0xae, 0xb1, 0xf1, 0xcb, 0xfa, 0x05, 0x73, 0x40, 0x50, 0x48, 0xd2, 0x52, 0xb7, 0x49, 0x99,
+	0x4e, 0x43, 0x32, 0xb4, 0x93, 0xe9, 0x30, 0x99, 0x4e, 0xc0, 0xa1, 0x69, 0xca, 0x80, 0x99, 0x23,
+	0xa4, 0x6f, 0x1f, 0xd4, 0xb3, 0x7c, 0x06, 0x25, 0x96, 0xce, 0xa3, 0x93, 0x69, 0xc8, 0xdf, 0xea,
+	0xa7, 0x7e, 0xee, 0x6f, 0xe9, 0x2f, 0xe8, 0x1f, 0xe8, 0xde, 0x9e, 0x24, 0x04, 0xb8, 0x09, 0x93,
+	0x6f, 0x77, 0xcf, 0x3e, 0xbb, 0xb7, 0xb7, 0x7b, 0xbb, 0x2b, 0x41, 0x33, 0x90, 0x71, 0xe4, 0x7b,
+	0x7a, 0x7d, 0x1c, 0xa9, 0x58, 0xb1, 0x05, 0xad, 0x54, 0x78, 0xec, 0xf6, 0x27, 0xfe, 0x68, 0xe0,
+	0x26, 0xa2, 0xce, 0x9f, 0x4d, 0xa8, 0xef, 0xd9, 0xf5, 0xb6, 0xd0, 0x92, 0x3d, 0x84, 0x45, 0x4b,
+	0x18, 0x88, 0x58, 0xba, 0xb1, 0x1f, 0x48, 0x1d, 0x8b, 0x60, 0xec, 0x14, 0x56, 0x0b, 0x6b, 0x45,
+	0xce, 0x48, 0xf6, 0x14, 0x45, 0x2f, 0x52, 0x09, 0xbb, 0x09, 0x55, 0xab, 0xe1, 0x0f, 0x9c, 0x19,
+	0x64, 0xd5, 0x78, 0x85, 0xf6, 0xcf, 0x07, 0x6c, 0x13, 0x6e, 0x8e, 0x47, 0x22, 0x1e, 0xaa, 0x28,
+	0x70, 0x4f, 0x65, 0xa4, 0x7d, 0x15, 0xba, 0x9e, 0x1a, 0xc8, 0x50, 0x04, 0xd2, 0x29, 0x12, 0x77,
+	0x39, 0x25, 0xbc, 0xb4, 0xf2, 0x6e, 0x22, 0x66, 0x77, 0xa1, 0x15, 0x8b, 0xe8, 0x58, 0xc6, 0x2e,
+	0x7a, 0x3f, 0x98, 0x78, 0xb1, 0x53, 0x22, 0x85, 0xa6, 0x45, 0x0f, 0x2c, 0xc8, 0x06, 0xb0, 0x98,
+	0xd0, 0xac, 0x13, 0xa7, 0x22, 0xf2, 0x45, 0x18, 0x3b, 0xb3, 0x48, 0x6e, 0x6d, 0xdc, 0x5f, 0x9f,
+	0x72, 0xe7, 0xf5, 0xdc, 0x7d, 0xd7, 0xb7, 0x8d, 0xe4, 0xa5, 0x55, 0xda, 0x2c, 0xee, 0xec, 0x3f,
+	0xe3, 0xcc, 0xda, 0xcb, 0x0b, 0x58, 0x0f, 0xea, 0xc9, 0x29, 0x22, 0xf2, 0x4e, 0x9c, 0x32, 0x19,
+	0xbf, 0xfb, 0x5e, 0xe3, 0x5b, 0x48, 0xde, 0xac, 0x1c, 0xed, 0xef, 0xee, 0xf7, 0x7e, 0xda, 0xe7,
+	0x60, 0x4d, 0x18, 0x90, 0xad, 0xc3, 0x42, 0xce, 0x60, 0xe6, 0x75, 0x85, 0xae, 0x38, 0x7f, 0x4e,
+	0x4c, 0x1d, 0xf8, 0x0a, 0x12, 0xb7, 0x5c, 0x6f, 0x3c, 0xc9, 0xe8, 0x55, 0xa2, 0xb7, 0xad, 0xa4,
+	0x3b, 0x9e, 0xa4, 0xec, 0x5d, 0xa8, 0x9d, 0x28, 0x9d, 0x38, 0x5b, 0xfb, 0x20, 0x67, 0xab, 0xc6,
+	0x00, 0xb9, 0xca, 0xa1, 0x49, 0xc6, 0x36, 0xc2, 0x81, 0x35, 0x08, 0x1f, 0x64, 0xb0, 0x6e, 0x8c,
+	0xa0, 0x0d, 0xb2, 0xb9, 0x0c, 0x15, 0xb2, 0xa9, 0xb4, 0x53, 0xa7, 0x3b, 0x94, 0xcd, 0xb6, 0xa7,
+	0x59, 0x27, 0x39, 0x4c, 0x69, 0x57, 0xbe, 0x89, 0x23, 0xe1, 0x34, 0x48, 0x5c, 0xb7, 0xe2, 0x1d,
+	0x03, 0x65, 0x1c, 0x2f, 0x52, 0x5a, 0x1b, 0x13, 0xcd, 0x73, 0x4e, 0xd7, 0x60, 0x68, 0xe7, 0x1e,
+	0xcc, 0xe5, 0x38, 0xe4, 0x76, 0xcb, 0x3e, 0x9f, 0x8c, 0x45, 0x8e, 0xdc, 0x87, 0x85, 0x1c, 0x2f,
+	0xbb, 0xe2, 0x9c, 0x0d, 0x6c, 0xc6, 0xcd, 0xf9, 0xad, 0x26, 0xb1, 0x3b, 0xf0, 0x23, 0xa7, 0x6d,
+	0xfd, 0xc6, 0xed, 0x53, 0x3f, 0x62, 0xdf, 0x41, 0x5d, 0xcb, 0x78, 0x32, 0x76, 0x63, 0xa5, 0x46,
+	0xda, 0x99, 0x5f, 0x2d, 0xae, 0xd5, 0x37, 0xee, 0x4c, 0x0d, 0xd1, 0x81, 0x8c, 0x86, 0xcf, 0xc3,
+	0xa1, 0xe2, 0x40, 0x1a, 0x2f, 0x8c, 0x02, 0x56, 0x4a, 0xed, 0xb5, 0x88, 0x7d, 0x37, 0x9a, 0x84,
+	0xda, 0x61, 0xd7, 0xd1, 0xae, 0x1a, 0x3e, 0x47, 0x3a, 0x7b, 0x0c, 0x60, 0x99, 0xa4, 0xbc, 0x70,
+	0x1d, 0xe5, 0x1a, 0x49, 0x53, 0xed, 0xd0, 0x0f, 0x5f, 0x09, 0xab, 0xbd, 0x78, 0x2d, 0x6d, 0x52,
+	0x20, 0xed, 0xaf, 0x61, 0x36, 0x56, 0xb1, 0x18, 0x39, 0x4b, 0x18, 0x8e, 0xf7, 0x2a, 0x5a, 0x2e,
+	0x7b, 0x09, 0xd3, 0x5a, 0x91, 0x73, 0x83, 0x4c, 0xdc, 0x9b, 0x6a, 0xe2, 0xd0, 0x60, 0x54, 0x92,
+	0xc9, 0x0b, 0xe3, 0xf3, 0xfa, 0x32, 0xc4, 0xba, 0xd0, 0xb0, 0x5a, 0x9e, 0x0a, 0x87, 0xfe, 0xb1,
+	0xb3, 0x4c, 0x06, 0x57, 0xa7, 0x1a, 0x24, 0xc5, 0x2e, 0xf1, 0x78, 0xbd, 0x7f, 0xbe, 0x61, 0x2b,
+	0x40, 0x4f, 0x9f, 0x5a, 0x94, 0x43, 0x39, 0xce, 0xf6, 0xec, 0x17, 0x58, 0xd4, 0x67, 0x3a, 0x96,
+	0x81, 0x1b, 0x49, 0xad, 0x26, 0x91, 0x27, 0x5d, 0x1f, 0xef, 0xe5, 0xdc, 0xa4, 0x83, 0xbe, 0x98,
+	0xee, 0x39, 0x29, 0xf0, 0x84, 0x4f, 0x61, 0x60, 0xfa, 0x0a, 0xc6, 0x3e, 0x83, 0x66, 0xea, 0x7b,
+	0x10, 0x88, 0x70, 0xe0, 0xac, 0xd0, 0xd9, 0x8d, 0xc4, 0x35, 0xc2, 0x4c, 0xae, 0xfa, 0xe2, 0xad,
+	0x1c, 0xd9, 0x5c, 0xdd, 0xba, 0x56, 0xae, 0x48, 0x81, 0x72, 0x85, 0xde, 0x0b, 0x2f, 0xa6, 0x1e,
+	0x2c, 0xbc, 0x13, 0x99, 0xc5, 0xfd, 0xf6, 0x3b, 0xbc, 0xdf, 0x22, 0x85, 0xae, 0xe1, 0xa7, 0x81,
+	0x67, 0xe2, 0x0a, 0xd6, 0x79, 0x08, 0x8d, 0x0b, 0xfd, 0xb2, 0x0a, 0xa5, 0xa3, 0xc3, 0x1d, 0xde,
+	0xfe, 0x88, 0x35, 0xa1, 0x66, 0x56, 0x4f, 0x77, 0xb6, 0x8f, 0x9e, 0xb5, 0x0b, 0xac, 0x02, 0xa6,
+	0xc7, 0xb6, 0x67, 0x3a, 0x8f, 0xa1, 0x44, 0x15, 0x55, 0x87, 0xb4, 0x43, 0x20, 0x19, 0xa5, 0x5b,
+	0x7c, 0x0f, 0x69, 0x35, 0x98, 0xc5, 0xc5, 0xa3, 0x6f, 0xda, 0x33, 0x06, 0xfb, 0xf9, 0xdb, 0x47,
+	0xed, 0x22, 0x03, 0x28, 0xe3, 0xc2, 0x45, 0xb0, 0xd4, 0x39, 0x86, 0x7a, 0x2e, 0x81, 0x66, 0x04,
+	0x4d, 0xb4, 0x74, 0x8f, 0x55, 0x20, 0x68, 0x50, 0x55, 0x79, 0x05, 0xf7, 0xcf, 0x70, 0x6b, 0x2a,
+	0xd6, 0x88, 0xa2, 0xbe, 0xa4, 0xe1, 0x54, 0xe5, 0x65, 0xdc, 0xf2, 0xbe, 0x64, 0x9f, 0x43, 0x0b,
+	0xc7, 0x0e, 0x66, 0x30, 0xd3, 0x2c, 0x92, 0xbc, 0x41, 0xe8, 0x91, 0x55, 0xef, 0x28, 0x60, 0x57,
+	0x13, 0xc8, 0x36, 0x60, 0x89, 0x5e, 0xb2, 0x3b, 0x3e, 0x39, 0xd3, 0xbe, 0x87, 0x8b, 0x40, 0x06,
+	0x2a, 0x3a, 0xa3, 0xc3, 0x4b, 0x7c, 0x81, 0x84, 0x07, 0x89, 0x6c, 0x8f, 0x44, 0x66, 0x9e, 0x89,
+	0x53, 0xe1, 0x8f, 0x44, 0x7f, 0x24, 0x4d, 0x13, 0xd7, 0xe4, 0xcf, 0x2c, 0x6f, 0x66, 0x28, 0x36,
+	0x70, 0xdd, 0xf9, 0xb7, 0x00, 0xd5, 0x34, 0x79, 0x8c, 0x41, 0x69, 0x20, 0xb5, 0x47, 0x66, 0x6b,
+	0x9c, 0xd6, 0x06, 0xa3, 0xb7, 0x69, 0x47, 0x2d, 0xad, 0xd9, 0x1d, 0xec, 0x00, 0x38, 0x04, 0x62,
+	0x9a, 0xd7, 0x74, 0x8f, 0x12, 0x96, 0xb8, 0x41, 0xcc, 0x98, 0x66, 0xb7, 0xa0, 0x16, 0x49, 0x74,
+	0x92, 0xa4, 0x25, 0x92, 0x56, 0x0d, 0x40, 0xc2, 0x4f, 0x01, 0xac, 0xf3, 0x26, 0x10, 0x34, 0x36,
+	0x4b, 0xdb, 0x33, 0x4e, 0x81, 0xd7, 0x2c, 0x8a, 0x81, 0x60, 0xbf, 0xc3, 0x32, 0xce, 0x60, 0x4f,
+	0x6a, 0x2d, 0xf5, 0xa5, 0x97, 0x5f, 0xa6, 0x37, 0xb8, 0x36, 0xfd, 0x0d, 0x5a, 0x9d, 0x0b, 0x4f,
+	0x7f, 0x29, 0x33, 0x94, 0x87, 0x3b, 0x7f, 0x15, 0x61, 0x61, 0x0a, 0x3d, 0xbb, 0x6c, 0x21, 0x77,
+	0xd9, 0x35, 0x68, 0xa3, 0xa7, 0x11, 0xdd, 0xc6, 0x0d, 0x7c, 0xd3, 0xb9, 0x29, 0x18, 0x25, 0xde,
+	0x32, 0xb8, 0xb9, 0xd4, 0x1e, 0xa1, 0x66, 0x68, 0x26, 0xe5, 0x9a, 0xe7, 0xda, 0xf0, 0xb4, 0xad,
+	0x24, 0xc7, 0xbe, 0x8d, 0x81, 0x10, 0x6f, 0xdc, 0x08, 0xe7, 0xc0, 0xeb, 0x7e, 0x1a, 0x26, 0x44,
+	0xb8, 0xd6, 0xbb, 0x7d, 0xf6, 0x25, 0xcc, 0x07, 0x7e, 0xa8, 0x22, 0x77, 0x2c, 0x8e, 0xa5, 0x3b,
+	0x14, 0x93, 0x51, 0xac, 0x6d, 0xb4, 0xf8, 0x1c, 0x09, 0x0e, 0x10, 0xff, 0x9e, 0x60, 0xe2, 0x8a,
+	0x57, 0x97, 0xb8, 0xe5, 0x84, 0x6b, 0x04, 0x39, 0xee, 0xc7, 0x50, 0xf7, 0x15, 0xc6, 0x72, 0x8c,
+	0x63, 0x05, 0x8f, 0xad, 0xd8, 0xdc, 0xf9, 0xea, 0xb9, 0x41, 0xf0, 0xdc, 0x55, 0x68, 0xa0, 0x1c,
+	0xa7, 0x4c, 0x42, 0xa8, 0x12, 0x01, 0x7c, 0xd5, 0x23, 0x08, 0x19, 0x8f, 0x61, 0xe5, 0x54, 0x8d,
+	0x26, 0x21, 0xa6, 0xfb, 0xcc, 0x74, 0xbe, 0x18, 0x07, 0xa7, 0xab, 0xff, 0xf0, 0x63, 0xac, 0x4f,
+	0x4d, 0xd3, 0xbf, 0xc4, 0x9d, 0x8c, 0xd1, 0xb5, 0x84, 0xc3, 0x44, 0xce, 0x9e, 0xc0, 0x6d, 0x3f,
+	0x7c, 0x87, 0x3e, 0x90, 0xfe, 0x4a, 0x8e, 0x73, 0xc9, 0x42, 0xe7, 0x9f, 0x02, 0xb4, 0xf6, 0xf0,
+	0x63, 0x6c, 0x24, 0x5f, 0x9c, 0x8d, 0x6d, 0xda, 0x7e, 0x4b, 0x1b, 0xb1, 0x0d, 0x32, 0xa5, 0xaf,
+	0xb5, 0xf1, 0x60, 0xfa, 0x17, 0xc3, 0x05, 0x55, 0xdb, 0x97, 0x6d, 0xc9, 0xe5, 0xbe, 0x1d, 0xfa,
+	0xe7, 0x28, 0xfb, 0x04, 0xea, 0x01, 0xe9, 0xb8, 0x31, 0x2a, 0x25, 0x75, 0x00, 0x41, 0x66, 0xc6,
+	0x54, 0x76, 0x38, 0x09, 0x5c, 0x35, 0x74, 0x2d, 0x68, 0x53, 0xde, 0xe4, 0x0d, 0x44, 0x7b, 0x43,
+	0x7b, 0x9e, 0xee, 0x3c, 0x48, 0x5a, 0x48, 0x62, 0xf5, 0x42, 0x1f, 0xc2, 0xf6, 0x73, 0xd8, 0xeb,
+	0xed, 0x9b, 0x86, 0x85, 0x9d, 0x6c, 0x6f, 0x6b, 0x77, 0x07, 0x3b, 0xd6, 0x08, 0x56, 0xba, 0x91,
+	0x1f, 0x9b, 0x92, 0xc6, 0xa2, 0x88, 0x7e, 0xc4, 0x57, 0x1a, 0xca, 0xb3, 0x74, 0xf6, 0x4c, 0x7b,
+	0xa9, 0x9b, 0x50, 0x49, 0x7b, 0xec, 0xcc, 0x3b, 0x46, 0x51, 0xee, 0x9b, 0x89, 0xa7, 0x0a, 0x9d,
+	0x3e, 0xdc, 0x9a, 0x72, 0x9a, 0x3e, 0x1f, 0x75, 0x25, 0x6f, 0xf2, 0x4a, 0xe3, 0x71, 0xa6, 0xfe,
+	0xa6, 0x47, 0xf6, 0xff, 0xbd, 0xe5, 0xa4, 0xdc, 0xf9, 0xbb, 0x00, 0xf3, 0x57, 0x06, 0x2b, 0x73,
+	0xd0, 0xeb, 0x24, 0x6e, 0x05, 0x8a, 0x5b, 0xba, 0x35, 0xa3, 0x31, 0xf9, 0xf2, 0xb4, 0x17, 0x6a,
+	0xf2, 0x6c, 0x6f, 0xde, 0xbc, 0x6d, 0x89, 0x62, 0x34, 0x52, 0x1e, 0xbe, 0x23, 0x7c, 0x2c, 0x49,
+	0xa9, 0xcd, 0x91, 0x60, 0xcb, 0xe0, 0x5d, 0x03, 0x9b, 0x0a, 0xce, 0x73, 0xb5, 0xff, 0x36, 0x6d,
+	0x4b, 0xad, 0x73, 0xea, 0x21, 0xa2, 0xe6, 0x53, 0xcf, 0xd4, 0xe4, 0x89, 0x14, 0x63, 0x4b, 0xb3,
+	0x15, 0x57, 0x47, 0xf0, 0x07, 0xc4, 0x0c, 0xa7, 0xf3, 0x04, 0xd8, 0xd5, 0x29, 0x65, 0xf2, 0x71,
+	0xe2, 0xc7, 0x3a, 0xe9, 0xc8, 0xb4, 0x66, 0x37, 0xa0, 0x1c, 0xf8, 0xa6, 0xf7, 0x24, 0xfd, 0x22,
+	0xd9, 0x6d, 0x2f, 0xfd, 0x9a, 0x7c, 0x8f, 0x24, 0x91, 0x73, 0xe9, 0x7f, 0xe9, 0x3f, 0xd0, 0xd3,
+	0x0b, 0x8d, 0x3f, 0x0d, 0x00, 0x00,
 }
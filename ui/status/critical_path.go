@@ -29,15 +29,31 @@ func NewCriticalPath(log logger.Logger) StatusOutput {
 	}
 }
 
+// NewCriticalPathWithReport returns a StatusOutput that logs the critical path like
+// NewCriticalPath, and also writes a report of the critical path, the time spent in each module
+// and rule and the parallelism of the build to jsonFile and htmlFile.
+func NewCriticalPathWithReport(log logger.Logger, jsonFile, htmlFile string) StatusOutput {
+	cp := NewCriticalPath(log).(*criticalPath)
+	cp.reportJSONFile = jsonFile
+	cp.reportHTMLFile = htmlFile
+	return cp
+}
+
 type criticalPath struct {
 	log logger.Logger
 
 	nodes   map[string]*node
 	running map[*Action]time.Time
 
+	// All the finished actions in the order they finished.
+	finished []*node
+
 	start, end time.Time
 
 	clock clock
+
+	// The files to write the report to, if any.
+	reportJSONFile, reportHTMLFile string
 }
 
 type clock interface {
@@ -55,6 +71,8 @@ type node struct {
 	cumulativeDuration time.Duration
 	duration           time.Duration
 	input              *node
+
+	start, end time.Time
 }
 
 func (cp *criticalPath) StartAction(action *Action, counts Counts) {
@@ -92,7 +110,10 @@ func (cp *criticalPath) FinishAction(result ActionResult, counts Counts) {
 			cumulativeDuration: cumulativeDuration,
 			duration:           duration,
 			input:              criticalPathInput,
+			start:              start,
+			end:                end,
 		}
+		cp.finished = append(cp.finished, node)
 
 		for _, output := range result.Action.Outputs {
 			cp.nodes[output] = node
@@ -126,6 +147,10 @@ func (cp *criticalPath) Flush() {
 				seconds/60, seconds%60, criticalPath[i].action.Description)
 		}
 	}
+
+	if cp.reportJSONFile != "" || cp.reportHTMLFile != "" {
+		cp.writeReport(criticalPath)
+	}
 }
 
 func (cp *criticalPath) Message(level MsgLevel, msg string) {}
@@ -0,0 +1,286 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package status
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// The maximum number of samples in the parallelism timeline of the critical path report.
+const maxTimelineSamples = 500
+
+// criticalPathReport is the report written by a criticalPath created with
+// NewCriticalPathWithReport.
+type criticalPathReport struct {
+	ElapsedMs      int64 `json:"elapsed_ms"`
+	CriticalPathMs int64 `json:"critical_path_ms"`
+
+	// The actions on the critical path, in the order they ran.
+	CriticalPath []criticalPathReportAction `json:"critical_path"`
+
+	// The total time spent running the actions of each module and each rule, sorted by decreasing
+	// time.
+	Modules []criticalPathReportTotal `json:"modules"`
+	Rules   []criticalPathReportTotal `json:"rules"`
+
+	// The average number of actions running during each interval of the build.
+	TimelineIntervalMs int64     `json:"timeline_interval_ms"`
+	Timeline           []float64 `json:"timeline"`
+}
+
+type criticalPathReportAction struct {
+	Description string `json:"description"`
+	Module      string `json:"module,omitempty"`
+	Variant     string `json:"variant,omitempty"`
+	Rule        string `json:"rule"`
+	StartMs     int64  `json:"start_ms"`
+	DurationMs  int64  `json:"duration_ms"`
+}
+
+type criticalPathReportTotal struct {
+	Name       string `json:"name"`
+	Actions    int    `json:"actions"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+// parseActionDescription extracts the module, variant and rule from the description of an action.
+// Soong prefixes the descriptions of the actions generated by modules with the module label and
+// suffixes them with the variant, e.g. "//foo:libfoo clang++ foo.cpp [linux_glibc x86]".  For
+// other actions the first word of the description is used as the rule.
+func parseActionDescription(desc string) (module, variant, rule string) {
+	if strings.HasPrefix(desc, "//") {
+		if i := strings.IndexByte(desc, ' '); i > 0 {
+			module, desc = desc[:i], desc[i+1:]
+		}
+		if strings.HasSuffix(desc, "]") {
+			if i := strings.LastIndex(desc, " ["); i >= 0 {
+				variant = desc[i+2 : len(desc)-1]
+				desc = desc[:i]
+			}
+		}
+	}
+	if fields := strings.Fields(desc); len(fields) > 0 {
+		rule = fields[0]
+	}
+	return module, variant, rule
+}
+
+func durationMs(d time.Duration) int64 {
+	return int64(d / time.Millisecond)
+}
+
+func sortedTotals(totals map[string]*criticalPathReportTotal) []criticalPathReportTotal {
+	ret := make([]criticalPathReportTotal, 0, len(totals))
+	for _, total := range totals {
+		ret = append(ret, *total)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].DurationMs != ret[j].DurationMs {
+			return ret[i].DurationMs > ret[j].DurationMs
+		}
+		return ret[i].Name < ret[j].Name
+	})
+	return ret
+}
+
+func (cp *criticalPath) report(criticalPath []*node) *criticalPathReport {
+	elapsed := cp.end.Sub(cp.start)
+	report := &criticalPathReport{
+		ElapsedMs:    durationMs(elapsed),
+		CriticalPath: []criticalPathReportAction{},
+		Timeline:     []float64{},
+	}
+	if len(criticalPath) > 0 {
+		report.CriticalPathMs = durationMs(criticalPath[0].cumulativeDuration)
+	}
+
+	for i := len(criticalPath) - 1; i >= 0; i-- {
+		n := criticalPath[i]
+		module, variant, rule := parseActionDescription(n.action.Description)
+		report.CriticalPath = append(report.CriticalPath, criticalPathReportAction{
+			Description: n.action.Description,
+			Module:      module,
+			Variant:     variant,
+			Rule:        rule,
+			StartMs:     durationMs(n.start.Sub(cp.start)),
+			DurationMs:  durationMs(n.duration),
+		})
+	}
+
+	modules := make(map[string]*criticalPathReportTotal)
+	rules := make(map[string]*criticalPathReportTotal)
+	add := func(totals map[string]*criticalPathReportTotal, name string, duration time.Duration) {
+		total := totals[name]
+		if total == nil {
+			total = &criticalPathReportTotal{Name: name}
+			totals[name] = total
+		}
+		total.Actions++
+		total.DurationMs += durationMs(duration)
+	}
+
+	interval := time.Second
+	if elapsed > maxTimelineSamples*interval {
+		interval = (elapsed + maxTimelineSamples - 1) / maxTimelineSamples
+	}
+	report.TimelineIntervalMs = durationMs(interval)
+	var busy []time.Duration
+	if elapsed > 0 {
+		busy = make([]time.Duration, (elapsed+interval-1)/interval)
+	}
+
+	for _, n := range cp.finished {
+		module, _, rule := parseActionDescription(n.action.Description)
+		if module != "" {
+			add(modules, module, n.duration)
+		}
+		add(rules, rule, n.duration)
+
+		// Add the time the action was running during each interval of the timeline.
+		start, end := n.start.Sub(cp.start), n.end.Sub(cp.start)
+		for i := int(start / interval); i < len(busy) && time.Duration(i)*interval < end; i++ {
+			bucketStart, bucketEnd := time.Duration(i)*interval, time.Duration(i+1)*interval
+			if start > bucketStart {
+				bucketStart = start
+			}
+			if end < bucketEnd {
+				bucketEnd = end
+			}
+			busy[i] += bucketEnd - bucketStart
+		}
+	}
+
+	report.Modules = sortedTotals(modules)
+	report.Rules = sortedTotals(rules)
+	for _, b := range busy {
+		report.Timeline = append(report.Timeline, float64(b)/float64(interval))
+	}
+
+	return report
+}
+
+func (cp *criticalPath) writeReport(criticalPath []*node) {
+	report := cp.report(criticalPath)
+
+	if cp.reportJSONFile != "" {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err == nil {
+			err = writeFileAtomically(cp.reportJSONFile, data)
+		}
+		if err != nil {
+			cp.log.Printf("Failed to write critical path report %s: %v\n", cp.reportJSONFile, err)
+		}
+	}
+
+	if cp.reportHTMLFile != "" {
+		buf := &bytes.Buffer{}
+		err := criticalPathReportTemplate.Execute(buf, report)
+		if err == nil {
+			err = writeFileAtomically(cp.reportHTMLFile, buf.Bytes())
+		}
+		if err != nil {
+			cp.log.Printf("Failed to write critical path report %s: %v\n", cp.reportHTMLFile, err)
+		}
+	}
+}
+
+func writeFileAtomically(filename string, data []byte) error {
+	tempPath := filename + ".tmp"
+	if err := ioutil.WriteFile(tempPath, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tempPath, filename)
+}
+
+// The maximum number of modules and rules listed in the HTML report.
+const maxReportTotals = 100
+
+var criticalPathReportTemplate = template.Must(template.New("critical_path").Funcs(template.FuncMap{
+	"duration": func(ms int64) string {
+		return (time.Duration(ms) * time.Millisecond).Round(100 * time.Millisecond).String()
+	},
+	"limit": func(totals []criticalPathReportTotal) []criticalPathReportTotal {
+		if len(totals) > maxReportTotals {
+			return totals[:maxReportTotals]
+		}
+		return totals
+	},
+	"timeline": func(r *criticalPathReport) template.HTML {
+		// An SVG polyline of the number of running actions over time.
+		max := 1.0
+		for _, v := range r.Timeline {
+			if v > max {
+				max = v
+			}
+		}
+		const width, height = 1000.0, 200.0
+		var points []string
+		for i, v := range r.Timeline {
+			x := width * float64(i) / float64(len(r.Timeline))
+			points = append(points, fmt.Sprintf("%.1f,%.1f", x, height-height*v/max))
+		}
+		return template.HTML(fmt.Sprintf(`<svg width="%.0f" height="%.0f" style="border:1px solid #ccc">`+
+			`<polyline fill="none" stroke="#1a73e8" points="%s"/>`+
+			`<text x="4" y="14" font-size="12">%.0f</text></svg>`,
+			width, height, strings.Join(points, " "), max))
+	},
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Build critical path</title>
+<style>
+body { font-family: sans-serif; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+td, th { border: 1px solid #ccc; padding: 2px 8px; text-align: left; }
+td.num { text-align: right; }
+</style>
+</head>
+<body>
+<h1>Build critical path</h1>
+<p>Elapsed time {{duration .ElapsedMs}}, critical path {{duration .CriticalPathMs}}.</p>
+
+<h2>Parallelism</h2>
+<p>Average number of running actions for every {{duration .TimelineIntervalMs}} of the build.</p>
+{{timeline .}}
+
+<h2>Critical path</h2>
+<table>
+<tr><th>Start</th><th>Duration</th><th>Module</th><th>Variant</th><th>Rule</th><th>Description</th></tr>
+{{range .CriticalPath}}<tr><td class="num">{{duration .StartMs}}</td><td class="num">{{duration .DurationMs}}</td><td>{{.Module}}</td><td>{{.Variant}}</td><td>{{.Rule}}</td><td>{{.Description}}</td></tr>
+{{end}}</table>
+
+<h2>Modules</h2>
+<table>
+<tr><th>Module</th><th>Actions</th><th>Total time</th></tr>
+{{range limit .Modules}}<tr><td>{{.Name}}</td><td class="num">{{.Actions}}</td><td class="num">{{duration .DurationMs}}</td></tr>
+{{end}}</table>
+
+<h2>Rules</h2>
+<table>
+<tr><th>Rule</th><th>Actions</th><th>Total time</th></tr>
+{{range limit .Rules}}<tr><td>{{.Name}}</td><td class="num">{{.Actions}}</td><td class="num">{{duration .DurationMs}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
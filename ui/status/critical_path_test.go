@@ -16,6 +16,7 @@ package status
 
 import (
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 )
@@ -164,3 +165,84 @@ func TestCriticalPath(t *testing.T) {
 		})
 	}
 }
+
+func TestParseActionDescription(t *testing.T) {
+	tests := []struct {
+		desc                  string
+		module, variant, rule string
+	}{
+		{"//foo:libfoo clang++ foo.cpp", "//foo:libfoo", "", "clang++"},
+		{"//foo:libfoo clang++ foo.cpp [linux_glibc x86]", "//foo:libfoo", "linux_glibc x86", "clang++"},
+		{"//:root javac", "//:root", "", "javac"},
+		{"Kati build", "", "", "Kati"},
+		{"", "", "", ""},
+	}
+	for _, tt := range tests {
+		module, variant, rule := parseActionDescription(tt.desc)
+		if module != tt.module || variant != tt.variant || rule != tt.rule {
+			t.Errorf("parseActionDescription(%q) = %q, %q, %q, want %q, %q, %q",
+				tt.desc, module, variant, rule, tt.module, tt.variant, tt.rule)
+		}
+	}
+}
+
+func TestCriticalPathReport(t *testing.T) {
+	cp := &testCriticalPath{
+		criticalPath: NewCriticalPath(nil).(*criticalPath),
+		actions:      make(map[int]*Action),
+	}
+
+	start := func(id int, startTime time.Duration, desc, output string, inputs ...string) {
+		cp.start(id, startTime, []string{output}, inputs)
+		cp.actions[id].Description = desc
+	}
+
+	//  a   c
+	//  |
+	//  b
+	start(0, 0, "//foo:liba clang++ a.cpp", "a.o")
+	start(1, 0, "//bar:libc javac [common]", "c.jar")
+	cp.finish(0, 2*time.Second)
+	start(2, 2*time.Second, "//foo:liba ld a.so", "a.so", "a.o")
+	cp.finish(1, 3*time.Second)
+	cp.finish(2, 4*time.Second)
+
+	report := cp.criticalPath.report(cp.criticalPath.criticalPath())
+
+	wantPath := []criticalPathReportAction{
+		{Description: "//foo:liba clang++ a.cpp", Module: "//foo:liba", Rule: "clang++", StartMs: 0, DurationMs: 2000},
+		{Description: "//foo:liba ld a.so", Module: "//foo:liba", Rule: "ld", StartMs: 2000, DurationMs: 2000},
+	}
+	if !reflect.DeepEqual(report.CriticalPath, wantPath) {
+		t.Errorf("critical path = %#v, want %#v", report.CriticalPath, wantPath)
+	}
+
+	wantModules := []criticalPathReportTotal{
+		{Name: "//foo:liba", Actions: 2, DurationMs: 4000},
+		{Name: "//bar:libc", Actions: 1, DurationMs: 3000},
+	}
+	if !reflect.DeepEqual(report.Modules, wantModules) {
+		t.Errorf("modules = %#v, want %#v", report.Modules, wantModules)
+	}
+
+	wantRules := []criticalPathReportTotal{
+		{Name: "javac", Actions: 1, DurationMs: 3000},
+		{Name: "clang++", Actions: 1, DurationMs: 2000},
+		{Name: "ld", Actions: 1, DurationMs: 2000},
+	}
+	if !reflect.DeepEqual(report.Rules, wantRules) {
+		t.Errorf("rules = %#v, want %#v", report.Rules, wantRules)
+	}
+
+	if g, w := report.Timeline, []float64{2, 2, 2, 1}; !reflect.DeepEqual(g, w) {
+		t.Errorf("timeline = %v, want %v", g, w)
+	}
+
+	html := &strings.Builder{}
+	if err := criticalPathReportTemplate.Execute(html, report); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(html.String(), "<td>//foo:liba</td><td class=\"num\">2</td><td class=\"num\">4s</td>") {
+		t.Errorf("unexpected html report:\n%s", html.String())
+	}
+}
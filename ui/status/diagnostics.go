@@ -0,0 +1,350 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package status
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"android/soong/ui/logger"
+)
+
+// Diagnostic is a single error or warning reported by a compiler or other tool in the output of a
+// failed action.
+type Diagnostic struct {
+	File   string `json:"file,omitempty"`
+	Line   int    `json:"line,omitempty"`
+	Column int    `json:"column,omitempty"`
+
+	// One of "error", "warning" or "note".
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+
+	// The identifier of the check that reported the diagnostic if the tool provides one, e.g. the
+	// rustc error code or the metalava issue id.
+	Rule string `json:"rule,omitempty"`
+}
+
+var (
+	ansiEscapeRegexp = regexp.MustCompile("\x1b\\[[0-9;]*[a-zA-Z]")
+
+	// clang, kotlinc before 1.5 and the column-less form used by javac, aapt2 and metalava:
+	//   foo.cpp:10:5: error: use of undeclared identifier 'x'
+	//   Foo.java:10: error: cannot find symbol
+	//   Foo.java:10: error: Missing nullability on method `foo` [MissingNullability]
+	//   res/values/strings.xml:3: error: unescaped apostrophe.
+	fileLineDiagnosticRegexp = regexp.MustCompile(
+		`^([^\s:][^:]*):(\d+):(?:(\d+):)? (fatal error|error|warning|note): (.*)$`)
+
+	// aapt2 diagnostics about a file without a line number:
+	//   res/values/strings.xml: error: file failed to compile.
+	fileDiagnosticRegexp = regexp.MustCompile(`^([^\s:][^:]*): (error|warning): (.*)$`)
+
+	// kotlinc 1.5 and later:
+	//   e: foo/Foo.kt: (10, 5): Unresolved reference: x
+	//   e: file:///foo/Foo.kt:10:5 Unresolved reference: x
+	kotlincDiagnosticRegexp = regexp.MustCompile(`^([ew]): (?:file://)?([^:]+?)(?:: \((\d+), (\d+)\):|:(\d+):(\d+)) (.*)$`)
+
+	// rustc prints the location on the line following the message:
+	//   error[E0425]: cannot find value `x` in this scope
+	//    --> src/main.rs:2:5
+	rustcDiagnosticRegexp = regexp.MustCompile(`^(error|warning)(?:\[(\w+)\])?: (.*)$`)
+	rustcLocationRegexp   = regexp.MustCompile(`^\s*--> (.+):(\d+):(\d+)$`)
+
+	// The warning flag or issue id at the end of clang, metalava and errorprone diagnostics.
+	ruleSuffixRegexp = regexp.MustCompile(`^(.*) \[([\w,=-]+)\]$`)
+)
+
+func diagnosticSeverity(s string) string {
+	switch s {
+	case "fatal error", "error", "e":
+		return "error"
+	case "warning", "w":
+		return "warning"
+	}
+	return "note"
+}
+
+func atoi(s string) int {
+	i, _ := strconv.Atoi(s)
+	return i
+}
+
+// ParseDiagnostics extracts the diagnostics reported by clang, javac, kotlinc, rustc, aapt2 and
+// metalava from the output of an action.  Lines that are not recognized as diagnostics, such as
+// source snippets and caret lines, are ignored.
+func ParseDiagnostics(output string) []Diagnostic {
+	var diagnostics []Diagnostic
+	lines := strings.Split(ansiEscapeRegexp.ReplaceAllString(output, ""), "\n")
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimRight(lines[i], "\r")
+
+		if m := fileLineDiagnosticRegexp.FindStringSubmatch(line); m != nil {
+			d := Diagnostic{
+				File:     m[1],
+				Line:     atoi(m[2]),
+				Column:   atoi(m[3]),
+				Severity: diagnosticSeverity(m[4]),
+				Message:  m[5],
+			}
+			if r := ruleSuffixRegexp.FindStringSubmatch(d.Message); r != nil {
+				d.Message, d.Rule = r[1], r[2]
+			}
+			diagnostics = append(diagnostics, d)
+		} else if m := kotlincDiagnosticRegexp.FindStringSubmatch(line); m != nil {
+			d := Diagnostic{
+				File:     m[2],
+				Severity: diagnosticSeverity(m[1]),
+				Message:  m[7],
+			}
+			if m[3] != "" {
+				d.Line, d.Column = atoi(m[3]), atoi(m[4])
+			} else {
+				d.Line, d.Column = atoi(m[5]), atoi(m[6])
+			}
+			diagnostics = append(diagnostics, d)
+		} else if m := rustcDiagnosticRegexp.FindStringSubmatch(line); m != nil {
+			d := Diagnostic{
+				Severity: diagnosticSeverity(m[1]),
+				Message:  m[3],
+				Rule:     m[2],
+			}
+			if i+1 < len(lines) {
+				if l := rustcLocationRegexp.FindStringSubmatch(lines[i+1]); l != nil {
+					d.File, d.Line, d.Column = l[1], atoi(l[2]), atoi(l[3])
+					i++
+				}
+			}
+			diagnostics = append(diagnostics, d)
+		} else if m := fileDiagnosticRegexp.FindStringSubmatch(line); m != nil {
+			diagnostics = append(diagnostics, Diagnostic{
+				File:     m[1],
+				Severity: diagnosticSeverity(m[2]),
+				Message:  m[3],
+			})
+		}
+	}
+	return diagnostics
+}
+
+// actionDiagnostic is a Diagnostic together with the action that reported it, written as a line
+// of the JSON lines diagnostics log.
+type actionDiagnostic struct {
+	Diagnostic
+	Action  string   `json:"action"`
+	Outputs []string `json:"outputs,omitempty"`
+}
+
+type diagnosticsLog struct {
+	f   *os.File
+	log logger.Logger
+}
+
+// NewDiagnosticsLog returns a StatusOutput that appends a JSON object to the file for every
+// diagnostic in the output of a failed action as soon as the action finishes.
+func NewDiagnosticsLog(log logger.Logger, filename string) StatusOutput {
+	f, err := logger.CreateFileWithRotation(filename, 5)
+	if err != nil {
+		log.Println("Failed to create diagnostics log file:", err)
+		return nil
+	}
+
+	return &diagnosticsLog{
+		f:   f,
+		log: log,
+	}
+}
+
+func (d *diagnosticsLog) StartAction(action *Action, counts Counts) {}
+
+func (d *diagnosticsLog) FinishAction(result ActionResult, counts Counts) {
+	if result.Error == nil {
+		return
+	}
+
+	var buf []byte
+	for _, diagnostic := range ParseDiagnostics(result.Output) {
+		data, err := json.Marshal(actionDiagnostic{
+			Diagnostic: diagnostic,
+			Action:     result.Description,
+			Outputs:    result.Outputs,
+		})
+		if err != nil {
+			d.log.Printf("Failed to marshal diagnostic: %v\n", err)
+			continue
+		}
+		buf = append(append(buf, data...), '\n')
+	}
+
+	// Write all the diagnostics of the action at once so that readers following the file never
+	// see a partial line.
+	if _, err := d.f.Write(buf); err != nil {
+		d.log.Printf("Failed to write file %s: %v\n", d.f.Name(), err)
+	}
+}
+
+func (d *diagnosticsLog) Flush() {
+	d.f.Close()
+}
+
+func (d *diagnosticsLog) Message(level MsgLevel, message string) {}
+
+func (d *diagnosticsLog) Write(p []byte) (int, error) {
+	return 0, errors.New("not supported")
+}
+
+// The subset of the SARIF 2.1.0 format written by the sarifLog.
+type sarifLogFile struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name string `json:"name"`
+}
+
+type sarifResult struct {
+	RuleID    string            `json:"ruleId,omitempty"`
+	Level     string            `json:"level"`
+	Message   sarifMessage      `json:"message"`
+	Locations []sarifLocation   `json:"locations,omitempty"`
+	Props     map[string]string `json:"properties,omitempty"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine,omitempty"`
+	StartColumn int `json:"startColumn,omitempty"`
+}
+
+func newSarifResult(d Diagnostic, action string) sarifResult {
+	r := sarifResult{
+		RuleID:  d.Rule,
+		Level:   d.Severity,
+		Message: sarifMessage{Text: d.Message},
+		Props:   map[string]string{"action": action},
+	}
+	if d.File != "" {
+		loc := sarifLocation{
+			PhysicalLocation: sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{URI: d.File},
+			},
+		}
+		if d.Line > 0 {
+			loc.PhysicalLocation.Region = &sarifRegion{StartLine: d.Line, StartColumn: d.Column}
+		}
+		r.Locations = []sarifLocation{loc}
+	}
+	return r
+}
+
+type sarifLog struct {
+	sarif    sarifLogFile
+	filename string
+	log      logger.Logger
+}
+
+// NewSarifLog returns a StatusOutput that writes the diagnostics in the output of failed actions
+// to the file in the SARIF format.  Like the build_error proto, the file is rewritten after every
+// failed action so that it is up to date while the build is running.
+func NewSarifLog(log logger.Logger, filename string) StatusOutput {
+	os.Remove(filename)
+	return &sarifLog{
+		sarif: sarifLogFile{
+			Version: "2.1.0",
+			Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+			Runs: []sarifRun{{
+				Tool:    sarifTool{Driver: sarifDriver{Name: "soong"}},
+				Results: []sarifResult{},
+			}},
+		},
+		filename: filename,
+		log:      log,
+	}
+}
+
+func (s *sarifLog) StartAction(action *Action, counts Counts) {}
+
+func (s *sarifLog) FinishAction(result ActionResult, counts Counts) {
+	if result.Error == nil {
+		return
+	}
+
+	diagnostics := ParseDiagnostics(result.Output)
+	if len(diagnostics) == 0 {
+		return
+	}
+	for _, d := range diagnostics {
+		s.sarif.Runs[0].Results = append(s.sarif.Runs[0].Results, newSarifResult(d, result.Description))
+	}
+
+	if err := s.write(); err != nil {
+		s.log.Printf("Failed to write file %s: %v\n", s.filename, err)
+	}
+}
+
+func (s *sarifLog) write() error {
+	data, err := json.MarshalIndent(s.sarif, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tempPath := s.filename + ".tmp"
+	if err := ioutil.WriteFile(tempPath, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tempPath, s.filename)
+}
+
+func (s *sarifLog) Flush() {
+	// Not required.
+}
+
+func (s *sarifLog) Message(level MsgLevel, message string) {}
+
+func (s *sarifLog) Write(p []byte) (int, error) {
+	return 0, errors.New("not supported")
+}
@@ -0,0 +1,106 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package status
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseDiagnostics(t *testing.T) {
+	tests := []struct {
+		name     string
+		output   string
+		expected []Diagnostic
+	}{
+		{
+			name: "clang",
+			output: "\x1b[1mfoo/bar.cpp:10:5: \x1b[0m\x1b[0;1;31merror: \x1b[0m\x1b[1muse of undeclared identifier 'x'\x1b[0m\n" +
+				"  x = 1;\n" +
+				"  ^\n" +
+				"foo/bar.h:3:1: warning: unused variable 'y' [-Wunused-variable]\n" +
+				"foo/baz.cpp:1:10: fatal error: 'missing.h' file not found\n" +
+				"1 warning and 2 errors generated.\n",
+			expected: []Diagnostic{
+				{File: "foo/bar.cpp", Line: 10, Column: 5, Severity: "error", Message: "use of undeclared identifier 'x'"},
+				{File: "foo/bar.h", Line: 3, Column: 1, Severity: "warning", Message: "unused variable 'y'", Rule: "-Wunused-variable"},
+				{File: "foo/baz.cpp", Line: 1, Column: 10, Severity: "error", Message: "'missing.h' file not found"},
+			},
+		},
+		{
+			name: "javac",
+			output: "foo/Foo.java:10: error: cannot find symbol\n" +
+				"    Bar bar;\n" +
+				"    ^\n" +
+				"  symbol:   class Bar\n" +
+				"1 error\n",
+			expected: []Diagnostic{
+				{File: "foo/Foo.java", Line: 10, Severity: "error", Message: "cannot find symbol"},
+			},
+		},
+		{
+			name: "kotlinc",
+			output: "e: foo/Foo.kt: (10, 5): Unresolved reference: x\n" +
+				"w: file:///src/foo/Bar.kt:3:7 Parameter 'y' is never used\n",
+			expected: []Diagnostic{
+				{File: "foo/Foo.kt", Line: 10, Column: 5, Severity: "error", Message: "Unresolved reference: x"},
+				{File: "/src/foo/Bar.kt", Line: 3, Column: 7, Severity: "warning", Message: "Parameter 'y' is never used"},
+			},
+		},
+		{
+			name: "rustc",
+			output: "error[E0425]: cannot find value `x` in this scope\n" +
+				" --> foo/src/main.rs:2:5\n" +
+				"  |\n" +
+				"2 |     x\n" +
+				"  |     ^ not found in this scope\n" +
+				"\n" +
+				"error: aborting due to previous error\n",
+			expected: []Diagnostic{
+				{File: "foo/src/main.rs", Line: 2, Column: 5, Severity: "error", Message: "cannot find value `x` in this scope", Rule: "E0425"},
+				{Severity: "error", Message: "aborting due to previous error"},
+			},
+		},
+		{
+			name: "aapt2",
+			output: "res/values/strings.xml:3: error: unescaped apostrophe in string.\n" +
+				"res/values/strings.xml: error: file failed to compile.\n",
+			expected: []Diagnostic{
+				{File: "res/values/strings.xml", Line: 3, Severity: "error", Message: "unescaped apostrophe in string."},
+				{File: "res/values/strings.xml", Severity: "error", Message: "file failed to compile."},
+			},
+		},
+		{
+			name:   "metalava",
+			output: "foo/Foo.java:12: error: Missing nullability on method `bar` return [MissingNullability]\n",
+			expected: []Diagnostic{
+				{File: "foo/Foo.java", Line: 12, Severity: "error", Message: "Missing nullability on method `bar` return", Rule: "MissingNullability"},
+			},
+		},
+		{
+			name:     "unrecognized",
+			output:   "Segmentation fault\n",
+			expected: nil,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if g, w := ParseDiagnostics(test.output), test.expected; !reflect.DeepEqual(g, w) {
+				t.Errorf("expected:\n%#v\ngot:\n%#v", w, g)
+			}
+		})
+	}
+}
@@ -0,0 +1,233 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package status
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+)
+
+// NinjaLogEntry is a single line of a .ninja_log file, recording a completed action.
+type NinjaLogEntry struct {
+	// The start and end times of the action in milliseconds since the start of the build.
+	StartMs int64
+	EndMs   int64
+
+	// The modification time of the output when the action completed, in ninja's timestamp units.
+	Mtime int64
+
+	Output string
+
+	// The hash of the command line of the action.
+	CommandHash string
+}
+
+const ninjaLogHeader = "# ninja log v"
+
+// ReadNinjaLog parses a .ninja_log file.  Only version 5 of the format is supported.
+func ReadNinjaLog(r io.Reader) ([]NinjaLogEntry, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1024*1024)
+
+	if !scanner.Scan() {
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("empty ninja log")
+	}
+	header := scanner.Text()
+	if !strings.HasPrefix(header, ninjaLogHeader) {
+		return nil, fmt.Errorf("invalid ninja log header %q", header)
+	}
+	if version := strings.TrimPrefix(header, ninjaLogHeader); version != "5" {
+		return nil, fmt.Errorf("unsupported ninja log version %s", version)
+	}
+
+	var entries []NinjaLogEntry
+	line := 1
+	for scanner.Scan() {
+		line++
+		fields := strings.Split(scanner.Text(), "\t")
+		if len(fields) != 5 {
+			return nil, fmt.Errorf("line %d: expected 5 fields, got %d", line, len(fields))
+		}
+		var ints [3]int64
+		for i := range ints {
+			v, err := strconv.ParseInt(fields[i], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %s", line, err)
+			}
+			ints[i] = v
+		}
+		entries = append(entries, NinjaLogEntry{
+			StartMs:     ints[0],
+			EndMs:       ints[1],
+			Mtime:       ints[2],
+			Output:      fields[3],
+			CommandHash: fields[4],
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// NinjaLogSnapshotSuffix is appended to the path of the .ninja_log to get the path of the copy of
+// the log that soong_ui takes before running ninja.
+const NinjaLogSnapshotSuffix = ".before_build"
+
+// NinjaLogEntriesSince returns the entries of a .ninja_log that were written after the snapshot
+// of the log with the entries in previous was taken.  The log has no markers between builds, but an
+// entry that was not rewritten by the build is identical to the latest entry for the same output in
+// the snapshot, even when ninja recompacted the log at the start of the build.  An action that was
+// rerun is only missed if it recorded the same start time, end time and mtime as its previous run.
+func NinjaLogEntriesSince(previous, current []NinjaLogEntry) []NinjaLogEntry {
+	latest := make(map[string]NinjaLogEntry, len(previous))
+	for _, entry := range previous {
+		latest[entry.Output] = entry
+	}
+
+	var ret []NinjaLogEntry
+	for _, entry := range current {
+		if prev, ok := latest[entry.Output]; ok && prev == entry {
+			continue
+		}
+		ret = append(ret, entry)
+	}
+	return ret
+}
+
+// SplitNinjaLogBuilds guesses how the entries of a .ninja_log split into the entries written by
+// each build, for when no snapshot of the log from before the last build is available.  Ninja
+// appends the actions to the log as they complete, with end times relative to the start of the
+// build, so a new build is assumed to start whenever the end times decrease.  This is only a
+// heuristic:
+//   - A build is merged into the previous one when its first action ends later than the last
+//     action of the previous build, e.g. after a build that only ran a few short actions.
+//   - When ninja recompacts the log it rewrites the latest entry of each output in no particular
+//     order, which loses the boundaries of all the builds before the recompaction.
+//
+// Use NinjaLogEntriesSince with a snapshot of the log to find the entries of a build exactly.
+func SplitNinjaLogBuilds(entries []NinjaLogEntry) [][]NinjaLogEntry {
+	var builds [][]NinjaLogEntry
+	start := 0
+	for i := 1; i < len(entries); i++ {
+		if entries[i].EndMs < entries[i-1].EndMs {
+			builds = append(builds, entries[start:i])
+			start = i
+		}
+	}
+	if start < len(entries) {
+		builds = append(builds, entries[start:])
+	}
+	return builds
+}
+
+// NinjaDeps is the contents of a .ninja_deps file, the dependencies discovered through depfiles.
+type NinjaDeps struct {
+	// The modification time of each output when its dependencies were recorded.
+	Mtimes map[string]int64
+
+	// The dependencies of each output.
+	Deps map[string][]string
+}
+
+const ninjaDepsSignature = "# ninjadeps\n"
+
+// ReadNinjaDeps parses a .ninja_deps file.  Only version 4 of the format is supported.
+func ReadNinjaDeps(r io.Reader) (*NinjaDeps, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if !bytes.HasPrefix(data, []byte(ninjaDepsSignature)) {
+		return nil, fmt.Errorf("invalid ninja deps signature")
+	}
+	data = data[len(ninjaDepsSignature):]
+	if len(data) < 4 {
+		return nil, fmt.Errorf("truncated ninja deps header")
+	}
+	if version := binary.LittleEndian.Uint32(data); version != 4 {
+		return nil, fmt.Errorf("unsupported ninja deps version %d", version)
+	}
+	data = data[4:]
+
+	deps := &NinjaDeps{
+		Mtimes: make(map[string]int64),
+		Deps:   make(map[string][]string),
+	}
+
+	var paths []string
+	for len(data) > 0 {
+		if len(data) < 4 {
+			return nil, fmt.Errorf("truncated ninja deps record header")
+		}
+		header := binary.LittleEndian.Uint32(data)
+		isDeps := header&0x80000000 != 0
+		size := int(header & 0x7fffffff)
+		data = data[4:]
+		if size%4 != 0 || size > len(data) {
+			return nil, fmt.Errorf("invalid ninja deps record size %d", size)
+		}
+		record := data[:size]
+		data = data[size:]
+
+		if isDeps {
+			// An output id, the mtime of the output as two 32-bit halves, and the input ids.
+			if size < 12 {
+				return nil, fmt.Errorf("invalid ninja deps record size %d", size)
+			}
+			out := int(binary.LittleEndian.Uint32(record))
+			if out >= len(paths) {
+				return nil, fmt.Errorf("invalid ninja deps path id %d", out)
+			}
+			mtime := int64(binary.LittleEndian.Uint32(record[4:])) |
+				int64(binary.LittleEndian.Uint32(record[8:]))<<32
+			var inputs []string
+			for i := 12; i < size; i += 4 {
+				id := int(binary.LittleEndian.Uint32(record[i:]))
+				if id >= len(paths) {
+					return nil, fmt.Errorf("invalid ninja deps path id %d", id)
+				}
+				inputs = append(inputs, paths[id])
+			}
+			// Later records for the same output replace earlier ones.
+			deps.Mtimes[paths[out]] = mtime
+			deps.Deps[paths[out]] = inputs
+		} else {
+			// A path padded with NULs to a multiple of 4 bytes, followed by the one's complement of
+			// its id.
+			if size < 4 {
+				return nil, fmt.Errorf("invalid ninja deps record size %d", size)
+			}
+			checksum := binary.LittleEndian.Uint32(record[size-4:])
+			if int(^checksum) != len(paths) {
+				return nil, fmt.Errorf("invalid ninja deps path checksum for id %d", len(paths))
+			}
+			paths = append(paths, string(bytes.TrimRight(record[:size-4], "\x00")))
+		}
+	}
+
+	return deps, nil
+}
@@ -0,0 +1,158 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package status
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadNinjaLog(t *testing.T) {
+	log := "# ninja log v5\n" +
+		"0\t10\t100\tout/a\tabc\n" +
+		"5\t20\t110\tout/b\tdef\n" +
+		"0\t3\t200\tout/a\tabd\n"
+
+	entries, err := ReadNinjaLog(strings.NewReader(log))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []NinjaLogEntry{
+		{StartMs: 0, EndMs: 10, Mtime: 100, Output: "out/a", CommandHash: "abc"},
+		{StartMs: 5, EndMs: 20, Mtime: 110, Output: "out/b", CommandHash: "def"},
+		{StartMs: 0, EndMs: 3, Mtime: 200, Output: "out/a", CommandHash: "abd"},
+	}
+	if !reflect.DeepEqual(entries, expected) {
+		t.Errorf("expected %v, got %v", expected, entries)
+	}
+
+	builds := SplitNinjaLogBuilds(entries)
+	if len(builds) != 2 || len(builds[0]) != 2 || len(builds[1]) != 1 {
+		t.Errorf("expected builds of 2 and 1 entries, got %v", builds)
+	}
+}
+
+func TestSplitNinjaLogBuildsLimits(t *testing.T) {
+	// A build whose first action ends after the last action of the previous build is merged into it.
+	merged := []NinjaLogEntry{
+		{StartMs: 0, EndMs: 3, Output: "out/a"},
+		{StartMs: 0, EndMs: 50, Output: "out/b"},
+		{StartMs: 50, EndMs: 60, Output: "out/c"},
+	}
+	if builds := SplitNinjaLogBuilds(merged); len(builds) != 1 {
+		t.Errorf("expected the heuristic to merge the builds into 1, got %v", builds)
+	}
+
+	// The snapshot of the log taken before the second build separates them.
+	since := NinjaLogEntriesSince(merged[:1], merged)
+	if !reflect.DeepEqual(since, merged[1:]) {
+		t.Errorf("expected %v, got %v", merged[1:], since)
+	}
+}
+
+func TestNinjaLogEntriesSince(t *testing.T) {
+	previous := []NinjaLogEntry{
+		{StartMs: 0, EndMs: 10, Mtime: 100, Output: "out/a", CommandHash: "a"},
+		{StartMs: 5, EndMs: 20, Mtime: 110, Output: "out/b", CommandHash: "b"},
+		{StartMs: 0, EndMs: 5, Mtime: 120, Output: "out/a", CommandHash: "a"},
+	}
+
+	// The log was recompacted at the start of the build, rewriting the latest entry of each output
+	// in a different order, and then out/a and out/c were built.
+	current := []NinjaLogEntry{
+		{StartMs: 5, EndMs: 20, Mtime: 110, Output: "out/b", CommandHash: "b"},
+		{StartMs: 0, EndMs: 5, Mtime: 120, Output: "out/a", CommandHash: "a"},
+		{StartMs: 0, EndMs: 30, Mtime: 200, Output: "out/a", CommandHash: "a"},
+		{StartMs: 0, EndMs: 31, Mtime: 201, Output: "out/c", CommandHash: "c"},
+	}
+
+	expected := current[2:]
+	if since := NinjaLogEntriesSince(previous, current); !reflect.DeepEqual(since, expected) {
+		t.Errorf("expected %v, got %v", expected, since)
+	}
+}
+
+func TestReadNinjaLogErrors(t *testing.T) {
+	tests := []struct {
+		log string
+		err string
+	}{
+		{"", "empty ninja log"},
+		{"foo\n", `invalid ninja log header "foo"`},
+		{"# ninja log v4\n", "unsupported ninja log version 4"},
+		{"# ninja log v5\n0\t1\tout\n", "line 2: expected 5 fields, got 3"},
+		{"# ninja log v5\n0\tx\t1\tout\thash\n", `line 2: strconv.ParseInt: parsing "x"`},
+	}
+	for _, test := range tests {
+		_, err := ReadNinjaLog(strings.NewReader(test.log))
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%q: expected error %q, got %v", test.log, test.err, err)
+		}
+	}
+}
+
+type ninjaDepsWriter struct {
+	bytes.Buffer
+	ids int
+}
+
+func (w *ninjaDepsWriter) uint32(v uint32) {
+	binary.Write(&w.Buffer, binary.LittleEndian, v)
+}
+
+func (w *ninjaDepsWriter) path(p string) {
+	padded := p + strings.Repeat("\x00", (4-len(p)%4)%4)
+	w.uint32(uint32(len(padded) + 4))
+	w.WriteString(padded)
+	w.uint32(^uint32(w.ids))
+	w.ids++
+}
+
+func (w *ninjaDepsWriter) deps(out uint32, mtime int64, inputs ...uint32) {
+	w.uint32(uint32(12+4*len(inputs)) | 0x80000000)
+	w.uint32(out)
+	w.uint32(uint32(mtime))
+	w.uint32(uint32(mtime >> 32))
+	for _, input := range inputs {
+		w.uint32(input)
+	}
+}
+
+func TestReadNinjaDeps(t *testing.T) {
+	w := &ninjaDepsWriter{}
+	w.WriteString(ninjaDepsSignature)
+	w.uint32(4)
+	w.path("out/a.o")
+	w.path("a.c")
+	w.path("a.h")
+	w.deps(0, 1<<33+5, 1)
+	w.deps(0, 1<<33+6, 1, 2)
+
+	deps, err := ReadNinjaDeps(&w.Buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if g, w := deps.Deps["out/a.o"], []string{"a.c", "a.h"}; !reflect.DeepEqual(g, w) {
+		t.Errorf("expected deps %q, got %q", w, g)
+	}
+	if g, w := deps.Mtimes["out/a.o"], int64(1<<33+6); g != w {
+		t.Errorf("expected mtime %d, got %d", w, g)
+	}
+}